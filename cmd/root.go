@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -15,6 +16,16 @@ terraform plan and reporting any resource changes detected.
 Minimum Terraform version required: 1.0.0`,
 }
 
+// version is the driftwatch version reported by --version and in
+// machine-readable reports.
+var version = "dev"
+
+// SetVersion records build version information, normally injected via ldflags.
+func SetVersion(v, commit, date string) {
+	version = v
+	rootCmd.Version = fmt.Sprintf("%s (commit %s, built %s)", v, commit, date)
+}
+
 // Execute runs the root command.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
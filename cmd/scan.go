@@ -1,19 +1,29 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/daemonship/driftwatch/internal/config"
 	"github.com/daemonship/driftwatch/internal/notify"
 	"github.com/daemonship/driftwatch/internal/report"
 	"github.com/daemonship/driftwatch/internal/runner"
+	"github.com/daemonship/driftwatch/internal/state"
 	"github.com/spf13/cobra"
 )
 
 var (
 	configFile string
 	binary     string
+	format     string
+	outputs    []string
+	noColor    bool
+	groupBy    string
+	filterExpr []string
+	sortBy     string
 )
 
 var scanCmd = &cobra.Command{
@@ -25,56 +35,252 @@ configured workspace, runs terraform plan, and reports any detected drift.
 Exit codes:
   0 — no drift detected
   1 — drift detected in one or more workspaces
-  2 — scan error occurred (plan could not be run)`,
+  2 — scan error occurred (plan could not be run or a report not written)
+
+With exit_code_mode: bitmask in the config, drift and errors are combined:
+3 means drift and a scan error. fail_on and errors_as in the config control
+which drift and errors count; notification_errors_as makes a notification
+that could not be delivered count as an error or drift.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Load configuration
-		cfg, err := config.Load(configFile)
+		code, err := runScan(cmd)
 		if err != nil {
-			return fmt.Errorf("loading config: %w", err)
+			return err
 		}
+		os.Exit(code)
+		return nil
+	},
+}
 
-		// Determine binary to use: CLI flag > config > default
-		tfBinary := binary
-		if tfBinary == "" {
-			tfBinary = cfg.Binary
+// runScan runs the scan command and returns its exit code. An error means
+// the scan could not start or its results could not be processed; a report
+// that could not be written is printed and counts as a scan error in the
+// exit code, after notifications are sent and the state is saved.
+func runScan(cmd *cobra.Command) (int, error) {
+	if _, ok := report.Lookup(format); !ok {
+		return 0, fmt.Errorf("unknown format %q (want %s)", format, strings.Join(report.Formats(), ", "))
+	}
+	flagOutputs, err := parseOutputFlags(format, cmd.Flags().Changed("format"), outputs)
+	if err != nil {
+		return 0, err
+	}
+	filters := make([]report.Filter, 0, len(filterExpr))
+	for _, expr := range filterExpr {
+		f, err := report.ParseFilter(expr)
+		if err != nil {
+			return 0, err
 		}
-		if tfBinary == "" {
-			tfBinary = "terraform"
+		filters = append(filters, f)
+	}
+	// Reject bad --sort and --group-by keys before running any plans
+	if err := report.SortResults(nil, sortBy); err != nil {
+		return 0, err
+	}
+	if _, err := report.GroupResults(nil, groupBy); err != nil {
+		return 0, err
+	}
+
+	// Load configuration
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return 0, fmt.Errorf("loading config: %w", err)
+	}
+	// Like --format, reject unknown config output formats before any plans run
+	for _, o := range cfg.Outputs {
+		if _, ok := report.Lookup(o.Format); !ok {
+			return 0, fmt.Errorf("config outputs: unknown format %q (want %s)", o.Format, strings.Join(report.Formats(), ", "))
 		}
+	}
 
-		opts := runner.Options{Binary: tfBinary}
-		runnerResults := runner.RunAll(cfg.Workspaces, opts)
+	targets, err := notify.TargetsFromConfig(cfg)
+	if err != nil {
+		return 0, fmt.Errorf("loading notifications: %w", err)
+	}
+	dispatcher := &notify.Dispatcher{Targets: targets}
 
-		// Convert runner results to report results (parsing JSON)
-		results, err := report.WorkspaceResultsFromRunnerResults(runnerResults)
-		if err != nil {
-			return fmt.Errorf("processing results: %w", err)
+	// Load the previous scan's state before running plans, so a corrupt
+	// state file fails fast
+	statePath := cfg.StateFile
+	if statePath == "" {
+		statePath = state.DefaultPath
+	}
+	var snapshot *state.Snapshot
+	if cfg.StateFile != "" || dispatcher.NeedsState() {
+		if snapshot, err = state.Load(statePath); err != nil {
+			return 0, err
+		}
+		dispatcher.State = snapshot
+	}
+
+	// Determine binary to use: CLI flag > config > default
+	tfBinary := binary
+	if tfBinary == "" {
+		tfBinary = cfg.Binary
+	}
+	if tfBinary == "" {
+		tfBinary = "terraform"
+	}
+
+	opts := runner.Options{Binary: tfBinary, StreamPlan: true}
+	runnerResults := runner.RunAll(cfg.Workspaces, opts)
+
+	// Convert runner results to report results (parsing JSON)
+	results, err := report.WorkspaceResultsFromRunnerResults(runnerResults, report.ConvertOptions{
+		IncludeInformational: cfg.InformationalFindings,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("processing results: %w", err)
+	}
+
+	// Filtering, sorting and grouping only shape the reports;
+	// notifications, the state and the exit code see the whole scan, in
+	// scan order. FilterResults copies, so sorting cannot reorder results
+	reportResults := report.FilterResults(results, filters)
+	if err := report.SortResults(reportResults, sortBy); err != nil {
+		return 0, err
+	}
+	grouped, err := report.GroupResults(reportResults, groupBy)
+	if err != nil {
+		return 0, err
+	}
+
+	// Write every requested report: --format/--output first, then config outputs
+	meta := report.Metadata{DriftwatchVersion: version, GeneratedAt: time.Now()}
+	outputErr := writeOutputs(append(flagOutputs, cfg.Outputs...), reportResults, grouped, meta, useColor())
+	if outputErr != nil {
+		fmt.Fprintln(os.Stderr, outputErr)
+	}
+
+	// Notify every configured target and report each delivery; failures
+	// only affect the exit code if notification_errors_as says so
+	notifyFailed := false
+	for _, d := range dispatcher.Dispatch(results) {
+		switch {
+		case d.Err != nil:
+			notifyFailed = true
+			fmt.Fprintf(os.Stderr, "notification %q failed: %v\n", d.Target, d.Err)
+		case d.Skipped:
+			fmt.Fprintf(os.Stderr, "notification %q: skipped, no trigger fired\n", d.Target)
+		default:
+			fmt.Fprintf(os.Stderr, "notification %q: ok (%s)\n", d.Target, d.Duration.Round(time.Millisecond))
+		}
+	}
+
+	// Remember this scan for the next one
+	if snapshot != nil {
+		snapshot.Record(results, time.Now())
+		if err := snapshot.Save(statePath); err != nil {
+			fmt.Fprintf(os.Stderr, "saving state to %s: %v\n", statePath, err)
+		}
+	}
+
+	// Set exit code based on results
+	policy := report.Policy{
+		FailOnCheckFailures:  cfg.FailOnCheckFailures,
+		FailOn:               cfg.FailOn,
+		ErrorsAs:             cfg.ErrorsAs,
+		Mode:                 cfg.ExitCodeMode,
+		NotificationErrorsAs: cfg.NotificationErrorsAs,
+	}
+	code := policy.ExitCodeAfterNotify(results, notifyFailed)
+	if outputErr != nil {
+		code = policy.WithError(code)
+	}
+	return code, nil
+}
+
+// parseOutputFlags turns --output values into report outputs. A value of the
+// form format=path writes that format to path; a plain path redirects the
+// --format report from stdout. The --format report comes first, and is dropped
+// only when format=path outputs are given and --format was not set.
+func parseOutputFlags(format string, formatSet bool, values []string) ([]config.Output, error) {
+	primary := config.Output{Format: format}
+	var extra []config.Output
+	for _, v := range values {
+		if name, path, ok := strings.Cut(v, "="); ok {
+			if _, known := report.Lookup(name); known {
+				extra = append(extra, config.Output{Format: name, Path: path})
+				continue
+			}
 		}
+		if primary.Path != "" {
+			return nil, fmt.Errorf("--output %q: report for --format %s already goes to %s", v, format, primary.Path)
+		}
+		primary.Path = v
+	}
+	// A format=path output alone should not also print the default text report.
+	if primary.Path == "" && len(extra) > 0 && !formatSet {
+		return extra, nil
+	}
+	return append([]config.Output{primary}, extra...), nil
+}
 
-		// Print the human-readable report
-		report.Print(os.Stdout, results)
+// groupedFormats are the report formats that show --group-by groups. The
+// others keep one entry per workspace, which is what CI tools reading JSON,
+// JUnit and SARIF expect.
+var groupedFormats = map[string]bool{"text": true, "markdown": true, "html": true}
 
-		// Send Slack notification if configured
-		slackWebhook := cfg.SlackWebhook
-		if slackWebhook == "" {
-			slackWebhook = notify.WebhookFromEnv()
+// writeOutputs writes results to every output, or grouped for formats that
+// show groups, continuing past failures so one bad path does not lose the
+// other reports. color enables ANSI colors for a text report written to
+// stdout.
+func writeOutputs(outs []config.Output, results, grouped []report.ScanResult, meta report.Metadata, color bool) error {
+	var errs []error
+	for _, o := range outs {
+		f, _ := report.Lookup(o.Format)
+		in := results
+		if groupedFormats[o.Format] {
+			in = grouped
 		}
-		if slackWebhook != "" {
-			notifier := &notify.SlackNotifier{
-				WebhookURL: slackWebhook,
-				ErrOut:     os.Stderr,
+		if o.Path == "" || o.Path == "-" {
+			if o.Format == "text" {
+				f = report.TextFormatter{Color: color}
 			}
-			notifier.Notify(results)
+			if err := f.Format(os.Stdout, in, meta); err != nil {
+				errs = append(errs, fmt.Errorf("writing %s report: %w", o.Format, err))
+			}
+			continue
+		}
+		if err := writeReportFile(o.Path, f, in, meta); err != nil {
+			errs = append(errs, fmt.Errorf("writing %s report to %s: %w", o.Format, o.Path, err))
 		}
+	}
+	return errors.Join(errs...)
+}
 
-		// Set exit code based on results
-		os.Exit(report.ExitCode(results))
-		return nil
-	},
+// writeReportFile writes results to the file at path, replacing it.
+func writeReportFile(path string, f report.Formatter, results []report.ScanResult, meta report.Metadata) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := f.Format(file, results, meta); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// useColor reports whether the text report on stdout should be colored:
+// stdout must be a terminal and neither --no-color nor NO_COLOR may be set.
+func useColor() bool {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
 }
 
 func init() {
 	scanCmd.Flags().StringVarP(&configFile, "config", "c", "driftwatch.yml", "config file path")
 	scanCmd.Flags().StringVar(&binary, "binary", "", "terraform binary to use (overrides config)")
+	scanCmd.Flags().StringVar(&format, "format", "text", "report format: "+strings.Join(report.Formats(), ", "))
+	scanCmd.Flags().StringArrayVarP(&outputs, "output", "o", nil, "write the report to this file instead of stdout, or format=path for an extra report (repeatable)")
+	scanCmd.Flags().StringVar(&groupBy, "group-by", "workspace", "group report entries by workspace, module, provider, resource-type or action")
+	scanCmd.Flags().StringArrayVar(&filterExpr, "filter", nil, "only report resources matching key=glob or key!=glob; keys: workspace, action, type, address, module, provider (repeatable)")
+	scanCmd.Flags().StringVar(&sortBy, "sort", "", "sort workspaces by workspace or drift, or resources by address, action, type, module or provider")
+	scanCmd.Flags().BoolVar(&noColor, "no-color", false, "disable colored text output (also set by NO_COLOR)")
 	rootCmd.AddCommand(scanCmd)
 }
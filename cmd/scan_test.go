@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// buildFakeTerraform compiles goSrc into a binary that stands in for
+// terraform, skipping the test when the go tool is unavailable.
+func buildFakeTerraform(t *testing.T, goSrc string) string {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not in PATH, skipping fake binary test")
+	}
+	dir := t.TempDir()
+	srcFile := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(srcFile, []byte(goSrc), 0644); err != nil {
+		t.Fatalf("buildFakeTerraform write: %v", err)
+	}
+	binPath := filepath.Join(dir, "fake-terraform")
+	cmd := exec.Command("go", "build", "-o", binPath, srcFile)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("buildFakeTerraform compile: %v\n%s", err, out)
+	}
+	return binPath
+}
+
+// setScanFlags sets the scan command's flags from args, restoring the
+// defaults when the test ends.
+func setScanFlags(t *testing.T, args ...string) {
+	t.Helper()
+	t.Cleanup(func() {
+		configFile, binary, format = "driftwatch.yml", "", "text"
+		outputs, filterExpr = nil, nil
+		groupBy, sortBy, noColor = "workspace", "", false
+	})
+	if err := scanCmd.ParseFlags(args); err != nil {
+		t.Fatalf("ParseFlags(%q): %v", args, err)
+	}
+}
+
+func TestRunScan_OutputErrorStillNotifies(t *testing.T) {
+	fakeTerraform := buildFakeTerraform(t, `
+package main
+import (
+	"fmt"
+	"os"
+)
+func main() {
+	fmt.Print(`+"`"+`{"format_version":"1.2","resource_changes":[{"address":"aws_instance.web","change":{"actions":["update"],"before":{"ami":"ami-old"},"after":{"ami":"ami-new"}}}]}`+"`"+`)
+	os.Exit(2)
+}
+`)
+	var posts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts.Add(1)
+	}))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		mode string
+		want int
+	}{
+		{"priority", 2},
+		{"bitmask", 3},
+	} {
+		t.Run(tt.mode, func(t *testing.T) {
+			dir := t.TempDir()
+			statePath := filepath.Join(dir, "state.json")
+			cfgPath := filepath.Join(dir, "driftwatch.yml")
+			cfg := fmt.Sprintf(`workspaces:
+  - %s
+exit_code_mode: %s
+state_file: %s
+notifications:
+  - type: webhook
+    url: %s
+`, t.TempDir(), tt.mode, statePath, srv.URL)
+			if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+				t.Fatal(err)
+			}
+			setScanFlags(t, "--config", cfgPath, "--binary", fakeTerraform,
+				"--output", filepath.Join(dir, "missing", "report.txt"))
+
+			before := posts.Load()
+			code, err := runScan(scanCmd)
+			if err != nil {
+				t.Fatalf("runScan() error = %v, want the output error reported in the exit code", err)
+			}
+			if code != tt.want {
+				t.Errorf("runScan() = %d, want %d", code, tt.want)
+			}
+			if posts.Load() == before {
+				t.Error("webhook not notified after the report failed to write")
+			}
+			if _, err := os.Stat(statePath); err != nil {
+				t.Errorf("state not saved after the report failed to write: %v", err)
+			}
+		})
+	}
+}
@@ -10,9 +10,100 @@ import (
 
 // Config represents the top-level driftwatch.yml configuration.
 type Config struct {
-	Workspaces   []string `yaml:"workspaces"`
-	SlackWebhook string   `yaml:"slack_webhook,omitempty"`
-	Binary       string   `yaml:"binary,omitempty"`
+	// Workspaces lists the workspace paths to scan, in order. In the file each
+	// entry is either a path or a mapping with path, owners and tags.
+	Workspaces []string `yaml:"-"`
+	// WorkspaceSettings holds the owners and tags of every workspace, keyed by path.
+	WorkspaceSettings map[string]Workspace `yaml:"-"`
+	SlackWebhook      string               `yaml:"slack_webhook,omitempty"`
+	Binary            string               `yaml:"binary,omitempty"`
+	// InformationalFindings opts in to reporting data source reads and
+	// deferred changes as informational findings (never counted as drift).
+	InformationalFindings bool `yaml:"informational_findings,omitempty"`
+	// FailOnCheckFailures makes failing check blocks and conditions count as
+	// drift when computing the exit code.
+	FailOnCheckFailures bool `yaml:"fail_on_check_failures,omitempty"`
+	// FailOn limits which resource actions count as drift for the exit code
+	// (e.g. [delete, replace]); empty means every action.
+	FailOn []string `yaml:"fail_on,omitempty"`
+	// ErrorsAs is how scan errors affect the exit code: "error" (default),
+	// "drift" or "ignore".
+	ErrorsAs string `yaml:"errors_as,omitempty"`
+	// ExitCodeMode is "priority" (default: 2 for errors, else 1 for drift) or
+	// "bitmask" (1 for drift plus 2 for errors, so both can be signalled).
+	ExitCodeMode string `yaml:"exit_code_mode,omitempty"`
+	// NotificationErrorsAs is how a notification that could not be delivered
+	// affects the exit code: "ignore" (default), "error" or "drift".
+	NotificationErrorsAs string `yaml:"notification_errors_as,omitempty"`
+	// Outputs lists additional report artifacts written on every scan.
+	Outputs []Output `yaml:"outputs,omitempty"`
+	// Notifications lists the notification targets results are sent to.
+	Notifications []Notification `yaml:"notifications,omitempty"`
+	// StateFile is where the outcome of each scan is kept for the next one
+	// (default .driftwatch/state.json). It is written when set, or when a
+	// notifier needs the previous scan.
+	StateFile string `yaml:"state_file,omitempty"`
+}
+
+// Notification is a named notification target. Settings other than name,
+// type and filters are specific to the notifier type and kept inline.
+type Notification struct {
+	// Name identifies the target in logs; defaults to Type.
+	Name string `yaml:"name,omitempty"`
+	// Type is the registered notifier type (e.g. "slack").
+	Type string `yaml:"type"`
+	// Filters narrow the results sent to this target, using the same
+	// key=glob expressions as --filter.
+	Filters []string `yaml:"filters,omitempty"`
+	// Triggers lists when the target is notified: on_drift, on_error,
+	// on_recovery, on_change and always. Empty means on drift.
+	Triggers []string `yaml:"triggers,omitempty"`
+	// Settings holds the remaining, type-specific keys.
+	Settings map[string]interface{} `yaml:",inline"`
+}
+
+// Workspace is a workspace entry: its path and who and what it belongs to.
+type Workspace struct {
+	Path string `yaml:"path"`
+	// Owners are contacts (e.g. email addresses) responsible for the workspace.
+	Owners []string `yaml:"owners,omitempty"`
+	// Tags label the workspace for notifiers that support them.
+	Tags []string `yaml:"tags,omitempty"`
+}
+
+// UnmarshalYAML accepts either a bare path or a mapping.
+func (w *Workspace) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&w.Path)
+	}
+	type plain Workspace
+	return value.Decode((*plain)(w))
+}
+
+// Output is a report artifact: a registered report format and a file path.
+type Output struct {
+	// Format is the report format name (e.g. "json", "junit").
+	Format string `yaml:"format"`
+	// Path is the destination file; empty or "-" means stdout.
+	Path string `yaml:"path,omitempty"`
+}
+
+// validActions are the resource actions accepted in fail_on. Data source
+// reads are informational findings, never drift, so "read" is not one.
+var validActions = map[string]bool{
+	"create":  true,
+	"update":  true,
+	"delete":  true,
+	"replace": true,
+}
+
+// validTriggers are the trigger names accepted in a notification's triggers.
+var validTriggers = map[string]bool{
+	"on_drift":    true,
+	"on_error":    true,
+	"on_recovery": true,
+	"on_change":   true,
+	"always":      true,
 }
 
 // Load reads and parses the config file at path.
@@ -27,10 +118,69 @@ func Load(path string) (*Config, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("parsing config file: %w", err)
 	}
+	var raw struct {
+		Workspaces []Workspace `yaml:"workspaces"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing config file: %w", err)
+	}
 
 	// Ensure Workspaces is at least an empty slice, not nil
-	if cfg.Workspaces == nil {
-		cfg.Workspaces = []string{}
+	cfg.Workspaces = make([]string, 0, len(raw.Workspaces))
+	cfg.WorkspaceSettings = make(map[string]Workspace, len(raw.Workspaces))
+	for i, ws := range raw.Workspaces {
+		if ws.Path == "" {
+			return nil, fmt.Errorf("workspaces[%d]: path is required", i)
+		}
+		cfg.Workspaces = append(cfg.Workspaces, ws.Path)
+		cfg.WorkspaceSettings[ws.Path] = ws
+	}
+
+	for i, o := range cfg.Outputs {
+		if o.Format == "" {
+			return nil, fmt.Errorf("outputs[%d]: format is required", i)
+		}
+	}
+
+	names := make(map[string]bool)
+	for i := range cfg.Notifications {
+		n := &cfg.Notifications[i]
+		if n.Type == "" {
+			return nil, fmt.Errorf("notifications[%d]: type is required", i)
+		}
+		if n.Name == "" {
+			n.Name = n.Type
+		}
+		if names[n.Name] {
+			return nil, fmt.Errorf("notifications[%d]: duplicate name %q", i, n.Name)
+		}
+		names[n.Name] = true
+		for _, t := range n.Triggers {
+			if !validTriggers[t] {
+				return nil, fmt.Errorf("notifications[%d]: unknown trigger %q (want on_drift, on_error, on_recovery, on_change or always)", i, t)
+			}
+		}
+	}
+
+	for _, action := range cfg.FailOn {
+		if !validActions[action] {
+			return nil, fmt.Errorf("fail_on: unknown action %q (want create, update, delete or replace)", action)
+		}
+	}
+	switch cfg.ErrorsAs {
+	case "", "error", "drift", "ignore":
+	default:
+		return nil, fmt.Errorf("errors_as: unknown value %q (want error, drift or ignore)", cfg.ErrorsAs)
+	}
+	switch cfg.NotificationErrorsAs {
+	case "", "ignore", "error", "drift":
+	default:
+		return nil, fmt.Errorf("notification_errors_as: unknown value %q (want ignore, error or drift)", cfg.NotificationErrorsAs)
+	}
+	switch cfg.ExitCodeMode {
+	case "", "priority", "bitmask":
+	default:
+		return nil, fmt.Errorf("exit_code_mode: unknown value %q (want priority or bitmask)", cfg.ExitCodeMode)
 	}
 
 	return &cfg, nil
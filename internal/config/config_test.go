@@ -125,3 +125,211 @@ func writeTempConfig(t *testing.T, content string) string {
 	}
 	return path
 }
+
+func TestLoad_InformationalFindings(t *testing.T) {
+	content := `
+workspaces:
+  - ./infra
+informational_findings: true
+`
+	path := writeTempConfig(t, content)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v, want nil", err)
+	}
+	if !cfg.InformationalFindings {
+		t.Error("InformationalFindings = false, want true")
+	}
+}
+
+func TestLoad_FailOnCheckFailures(t *testing.T) {
+	content := `
+workspaces:
+  - ./infra
+fail_on_check_failures: true
+`
+	path := writeTempConfig(t, content)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v, want nil", err)
+	}
+	if !cfg.FailOnCheckFailures {
+		t.Error("FailOnCheckFailures = false, want true")
+	}
+}
+
+func TestLoad_Outputs(t *testing.T) {
+	content := `
+workspaces:
+  - ./infra
+outputs:
+  - format: json
+    path: artifacts/drift.json
+  - format: junit
+    path: artifacts/drift.xml
+`
+	path := writeTempConfig(t, content)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v, want nil", err)
+	}
+	if len(cfg.Outputs) != 2 {
+		t.Fatalf("Outputs count = %d, want 2", len(cfg.Outputs))
+	}
+	if cfg.Outputs[0].Format != "json" || cfg.Outputs[0].Path != "artifacts/drift.json" {
+		t.Errorf("Outputs[0] = %+v, want json to artifacts/drift.json", cfg.Outputs[0])
+	}
+}
+
+func TestLoad_OutputMissingFormat(t *testing.T) {
+	content := `
+outputs:
+  - path: drift.json
+`
+	path := writeTempConfig(t, content)
+	if _, err := config.Load(path); err == nil {
+		t.Error("Load() error = nil, want error for output without format")
+	}
+}
+
+func TestLoad_ExitCodePolicy(t *testing.T) {
+	content := `
+workspaces:
+  - ./infra
+fail_on: [delete, replace]
+errors_as: drift
+exit_code_mode: bitmask
+notification_errors_as: error
+`
+	path := writeTempConfig(t, content)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v, want nil", err)
+	}
+	if len(cfg.FailOn) != 2 || cfg.FailOn[0] != "delete" || cfg.FailOn[1] != "replace" {
+		t.Errorf("FailOn = %v, want [delete replace]", cfg.FailOn)
+	}
+	if cfg.ErrorsAs != "drift" {
+		t.Errorf("ErrorsAs = %q, want %q", cfg.ErrorsAs, "drift")
+	}
+	if cfg.ExitCodeMode != "bitmask" {
+		t.Errorf("ExitCodeMode = %q, want %q", cfg.ExitCodeMode, "bitmask")
+	}
+	if cfg.NotificationErrorsAs != "error" {
+		t.Errorf("NotificationErrorsAs = %q, want %q", cfg.NotificationErrorsAs, "error")
+	}
+}
+
+func TestLoad_InvalidExitCodePolicy(t *testing.T) {
+	for _, content := range []string{
+		"fail_on: [destroy]\n",
+		"fail_on: [read]\n",
+		"errors_as: warn\n",
+		"exit_code_mode: sum\n",
+		"notification_errors_as: fail\n",
+	} {
+		path := writeTempConfig(t, content)
+		if _, err := config.Load(path); err == nil {
+			t.Errorf("Load(%q) error = nil, want error", content)
+		}
+	}
+}
+
+func TestLoad_Notifications(t *testing.T) {
+	content := `
+workspaces:
+  - ./infra
+notifications:
+  - name: platform
+    type: slack
+    webhook_url: https://hooks.slack.com/services/x
+    filters: [action=delete]
+  - type: slack
+    webhook_url: https://hooks.slack.com/services/y
+    triggers: [on_error, on_recovery]
+`
+	path := writeTempConfig(t, content)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v, want nil", err)
+	}
+	if len(cfg.Notifications) != 2 {
+		t.Fatalf("Notifications count = %d, want 2", len(cfg.Notifications))
+	}
+	n := cfg.Notifications[0]
+	if n.Name != "platform" || n.Type != "slack" || len(n.Filters) != 1 {
+		t.Errorf("Notifications[0] = %+v, want platform slack target with one filter", n)
+	}
+	if n.Settings["webhook_url"] != "https://hooks.slack.com/services/x" {
+		t.Errorf("Notifications[0].Settings = %v, want inline webhook_url", n.Settings)
+	}
+	if cfg.Notifications[1].Name != "slack" {
+		t.Errorf("Notifications[1].Name = %q, want default %q", cfg.Notifications[1].Name, "slack")
+	}
+	if got := cfg.Notifications[1].Triggers; len(got) != 2 || got[0] != "on_error" || got[1] != "on_recovery" {
+		t.Errorf("Notifications[1].Triggers = %v, want [on_error on_recovery]", got)
+	}
+}
+
+func TestLoad_InvalidNotifications(t *testing.T) {
+	for _, content := range []string{
+		"notifications:\n  - name: x\n",
+		"notifications:\n  - type: slack\n  - type: slack\n",
+		"notifications:\n  - type: slack\n    triggers: [on_fix]\n",
+	} {
+		path := writeTempConfig(t, content)
+		if _, err := config.Load(path); err == nil {
+			t.Errorf("Load(%q) error = nil, want error", content)
+		}
+	}
+}
+
+func TestLoad_WorkspaceObjects(t *testing.T) {
+	content := `
+workspaces:
+  - ./infra/staging
+  - path: ./infra/production
+    owners: [alice@example.com, bob@example.com]
+    tags: [prod, payments]
+`
+	path := writeTempConfig(t, content)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v, want nil", err)
+	}
+	if len(cfg.Workspaces) != 2 || cfg.Workspaces[0] != "./infra/staging" || cfg.Workspaces[1] != "./infra/production" {
+		t.Errorf("Workspaces = %v, want both paths in order", cfg.Workspaces)
+	}
+	prod := cfg.WorkspaceSettings["./infra/production"]
+	if len(prod.Owners) != 2 || prod.Owners[0] != "alice@example.com" {
+		t.Errorf("production owners = %v", prod.Owners)
+	}
+	if len(prod.Tags) != 2 || prod.Tags[1] != "payments" {
+		t.Errorf("production tags = %v", prod.Tags)
+	}
+	if staging, ok := cfg.WorkspaceSettings["./infra/staging"]; !ok || len(staging.Owners) != 0 {
+		t.Errorf("staging settings = %+v, %v; want a bare entry", staging, ok)
+	}
+}
+
+func TestLoad_WorkspaceObjectMissingPath(t *testing.T) {
+	content := `
+workspaces:
+  - owners: [alice@example.com]
+`
+	path := writeTempConfig(t, content)
+	if _, err := config.Load(path); err == nil {
+		t.Error("Load() error = nil, want error for workspace without path")
+	}
+}
+
+func TestLoad_StateFile(t *testing.T) {
+	path := writeTempConfig(t, "state_file: /var/lib/driftwatch/state.json\n")
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v, want nil", err)
+	}
+	if cfg.StateFile != "/var/lib/driftwatch/state.json" {
+		t.Errorf("StateFile = %q, want configured path", cfg.StateFile)
+	}
+}
@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how HTTP notifiers retry failed deliveries. Rate
+// limits (HTTP 429), server errors (5xx) and network errors are retried;
+// other responses are final. Zero fields take the default: 4 attempts,
+// waits from 1s doubling up to 30s, and a budget of 2 minutes.
+type RetryPolicy struct {
+	// Attempts is the total number of tries, including the first.
+	Attempts int
+	// BaseDelay is the wait before the first retry; it doubles after each
+	// retry.
+	BaseDelay time.Duration
+	// MaxDelay caps every wait, including one asked for with Retry-After.
+	MaxDelay time.Duration
+	// Budget caps the time one notification spends delivering, however
+	// many requests it sends; no retry is made past it.
+	Budget time.Duration
+}
+
+// defaultRetry holds the defaults of RetryPolicy's zero fields.
+var defaultRetry = RetryPolicy{Attempts: 4, BaseDelay: time.Second, MaxDelay: 30 * time.Second, Budget: 2 * time.Minute}
+
+// withDefaults returns p with its zero fields set to the defaults.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.Attempts <= 0 {
+		p.Attempts = defaultRetry.Attempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaultRetry.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaultRetry.MaxDelay
+	}
+	if p.Budget <= 0 {
+		p.Budget = defaultRetry.Budget
+	}
+	return p
+}
+
+// requestTimeout caps each HTTP request.
+const requestTimeout = 10 * time.Second
+
+// maxResponseBody is how much of a response body is read.
+const maxResponseBody = 1 << 20
+
+// DeliveryError is returned when a notification could not be delivered,
+// after any retries.
+type DeliveryError struct {
+	// Service names the receiving endpoint, e.g. "Slack webhook".
+	Service string
+	// StatusCode is the last HTTP status received, or 0 if the last attempt
+	// got no response.
+	StatusCode int
+	// Attempts is how many times the request was sent.
+	Attempts int
+	// Err is the network error of the last attempt, if any.
+	Err error
+}
+
+func (e *DeliveryError) Error() string {
+	var msg string
+	if e.Err != nil {
+		msg = fmt.Sprintf("sending to %s: %v", e.Service, e.Err)
+	} else {
+		msg = fmt.Sprintf("%s returned HTTP %d", e.Service, e.StatusCode)
+	}
+	if e.Attempts > 1 {
+		msg += fmt.Sprintf(" (after %d attempts)", e.Attempts)
+	}
+	return msg
+}
+
+func (e *DeliveryError) Unwrap() error { return e.Err }
+
+// sender delivers the HTTP requests of one notification under a retry
+// policy. The requests share the policy's Budget. Once a request to a host
+// has failed every attempt, or run out of budget, the host is taken to be
+// down and later requests to it fail without being sent, so a notification
+// of many events does not retry each of them against a dead endpoint.
+type sender struct {
+	policy   RetryPolicy
+	deadline time.Time
+	// down holds the failure that took each host down.
+	down map[string]*DeliveryError
+}
+
+// newSender returns a sender whose budget starts now.
+func newSender(policy RetryPolicy) *sender {
+	policy = policy.withDefaults()
+	return &sender{policy: policy, deadline: time.Now().Add(policy.Budget), down: make(map[string]*DeliveryError)}
+}
+
+// deliver sends body to endpoint with the given method and headers,
+// retrying under the sender's policy. It returns the body of the first 2xx
+// response; any other outcome is a *DeliveryError.
+func (s *sender) deliver(service, method, endpoint string, header http.Header, body []byte) ([]byte, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("creating %s request: %w", service, err)
+	}
+	if prev, ok := s.down[u.Host]; ok {
+		return nil, &DeliveryError{Service: service, Err: fmt.Errorf("not sent after an earlier request failed: %w", prev)}
+	}
+
+	delay := s.policy.BaseDelay
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("creating %s request: %w", service, err)
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		var wait time.Duration
+		derr := &DeliveryError{Service: service, Attempts: attempt}
+		client := &http.Client{Timeout: max(min(requestTimeout, time.Until(s.deadline)), time.Millisecond)}
+		resp, err := client.Do(req)
+		if err != nil {
+			derr.Err = err
+		} else {
+			data, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return data, nil
+			}
+			derr.StatusCode = resp.StatusCode
+			if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+				return nil, derr
+			}
+			wait = retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		}
+
+		if wait == 0 {
+			wait = delay
+		}
+		wait = min(wait, s.policy.MaxDelay)
+		if attempt >= s.policy.Attempts || time.Now().Add(wait).After(s.deadline) {
+			s.down[u.Host] = derr
+			return nil, derr
+		}
+		time.Sleep(wait)
+		delay *= 2
+	}
+}
+
+// retryAfter parses a Retry-After header, given in seconds or as an HTTP
+// date. It returns 0 if the header is missing or invalid.
+func retryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,136 @@
+package notify_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/daemonship/driftwatch/internal/notify"
+)
+
+// fastRetry keeps retries in tests fast; Retry-After is still honoured up
+// to the default MaxDelay.
+var fastRetry = notify.RetryPolicy{BaseDelay: time.Millisecond}
+
+// flakyServer answers the first fails requests with status, then 200. It
+// counts every request.
+func flakyServer(t *testing.T, fails int, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(calls.Add(1)) <= fails {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestDeliver_RetriesServerErrors(t *testing.T) {
+	srv, calls := flakyServer(t, 2, http.StatusBadGateway, nil)
+
+	n := &notify.SlackNotifier{WebhookURL: srv.URL, Retry: fastRetry}
+	if err := n.Notify(driftResults()); err != nil {
+		t.Fatalf("Notify() error = %v, want success on the third attempt", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+}
+
+func TestDeliver_HonoursRetryAfter(t *testing.T) {
+	srv, calls := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+
+	start := time.Now()
+	n := &notify.SlackNotifier{WebhookURL: srv.URL}
+	if err := n.Notify(driftResults()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want at least the 1s Retry-After", elapsed)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
+
+func TestDeliver_DoesNotRetryClientErrors(t *testing.T) {
+	srv, calls := flakyServer(t, 1, http.StatusForbidden, nil)
+
+	n := &notify.SlackNotifier{WebhookURL: srv.URL}
+	err := n.Notify(driftResults())
+	var derr *notify.DeliveryError
+	if !errors.As(err, &derr) {
+		t.Fatalf("Notify() error = %v, want a *DeliveryError", err)
+	}
+	if derr.StatusCode != http.StatusForbidden || derr.Attempts != 1 {
+		t.Errorf("error = %+v, want HTTP 403 after 1 attempt", derr)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestDeliver_GivesUpAfterAttempts(t *testing.T) {
+	srv, calls := flakyServer(t, 100, http.StatusServiceUnavailable, nil)
+
+	n := &notify.TeamsNotifier{WebhookURL: srv.URL, Retry: fastRetry}
+	err := n.Notify(driftResults())
+	var derr *notify.DeliveryError
+	if !errors.As(err, &derr) {
+		t.Fatalf("Notify() error = %v, want a *DeliveryError", err)
+	}
+	want := 4 // the default
+	if derr.Attempts != want || int(calls.Load()) != want {
+		t.Errorf("attempts = %d, requests = %d, want %d", derr.Attempts, calls.Load(), want)
+	}
+	if got := err.Error(); got != "Teams webhook returned HTTP 503 (after 4 attempts)" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestDeliver_SkipsRequestsToAnEndpointThatIsDown(t *testing.T) {
+	srv, calls := flakyServer(t, 100, http.StatusServiceUnavailable, nil)
+
+	n := &notify.PagerDutyNotifier{RoutingKey: "R0UT1NG", EventsURL: srv.URL, Retry: fastRetry}
+	err := n.Notify(multiWorkspaceDriftResults())
+	if err == nil {
+		t.Fatal("Notify() error = nil, want delivery errors")
+	}
+	// The first event uses up its attempts; the second is not sent
+	if got := calls.Load(); got != 4 {
+		t.Errorf("requests = %d, want 4 for the first event only", got)
+	}
+	if !strings.Contains(err.Error(), "not sent after an earlier request failed") {
+		t.Errorf("Notify() error = %v, want the second event reported as not sent", err)
+	}
+}
+
+func TestDeliver_StopsRetryingWhenBudgetIsSpent(t *testing.T) {
+	srv, calls := flakyServer(t, 100, http.StatusServiceUnavailable, nil)
+
+	n := &notify.TeamsNotifier{
+		WebhookURL: srv.URL,
+		Retry:      notify.RetryPolicy{Attempts: 10, BaseDelay: 50 * time.Millisecond, Budget: 100 * time.Millisecond},
+	}
+	start := time.Now()
+	if err := n.Notify(driftResults()); err == nil {
+		t.Fatal("Notify() error = nil, want a delivery error")
+	}
+	// Waits of 50ms then 100ms: the second would end past the budget
+	if got := calls.Load(); got != 2 {
+		t.Errorf("requests = %d, want 2 within the budget", got)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("gave up after %v, want about the 100ms budget", elapsed)
+	}
+}
@@ -0,0 +1,152 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+// digest is the channel-neutral summary of a scan that chat notifiers
+// render in their own message formats.
+type digest struct {
+	// Title is the one-line headline, e.g. "🚨 Terraform Drift Detected".
+	Title   string
+	Summary report.Summary
+	// Breakdown holds summary lines such as "By action: update 2".
+	Breakdown []string
+	// Workspaces lists the drifted workspaces in scan order.
+	Workspaces []workspaceDigest
+	// CheckFailures lists failing health checks across all workspaces.
+	CheckFailures []checkDigest
+	// Errors lists the workspaces whose scan failed.
+	Errors []errorDigest
+	// Recovered lists workspaces that are clean again, set for an Event.
+	Recovered []string
+	// RunURL links to the CI run that produced the scan, if known.
+	RunURL string
+}
+
+// workspaceDigest is one drifted workspace and its resource changes.
+type workspaceDigest struct {
+	Path      string
+	Resources []report.ResourceChange
+}
+
+// checkDigest is one failing health check.
+type checkDigest struct {
+	Workspace string
+	Address   string
+	Status    string
+}
+
+// errorDigest is one workspace whose scan failed.
+type errorDigest struct {
+	Workspace string
+	// Message is the first line of the error, shortened to
+	// errorMessageMax.
+	Message string
+}
+
+// errorMessageMax is the longest scan error shown per workspace.
+const errorMessageMax = 200
+
+// buildDigest summarizes results for chat notifiers.
+func buildDigest(results []report.ScanResult) digest {
+	summary := report.Summarize(results)
+	d := digest{Title: "🚨 Terraform Drift Detected", Summary: summary, RunURL: ciRunURL()}
+	if summary.WorkspacesWithDrift == 0 {
+		d.Title = "⚠️ Terraform Health Check Failures"
+	}
+
+	for _, b := range []struct {
+		label  string
+		counts map[string]int
+	}{
+		{"By action", summary.ByAction},
+		{"By provider", summary.ByProvider},
+		{"By resource type", summary.ByResourceType},
+	} {
+		if len(b.counts) > 0 {
+			d.Breakdown = append(d.Breakdown, fmt.Sprintf("%s: %s", b.label, report.FormatCounts(report.SortedCounts(b.counts))))
+		}
+	}
+	if summary.TotalDuration > 0 {
+		line := fmt.Sprintf("Scan duration: %s", summary.TotalDuration.Round(100*time.Millisecond))
+		if len(summary.Slowest) > 0 {
+			slowest := summary.Slowest[0]
+			line += fmt.Sprintf(" (slowest: %s, %s)", slowest.WorkspacePath, slowest.Duration.Round(100*time.Millisecond))
+		}
+		d.Breakdown = append(d.Breakdown, line)
+	}
+
+	for _, r := range results {
+		if len(r.ResourceChanges) > 0 {
+			d.Workspaces = append(d.Workspaces, workspaceDigest{Path: r.WorkspacePath, Resources: r.ResourceChanges})
+		}
+		for _, c := range r.CheckFailures {
+			d.CheckFailures = append(d.CheckFailures, checkDigest{Workspace: r.WorkspacePath, Address: c.Address, Status: c.Status})
+		}
+		if r.Err != nil {
+			msg, _, _ := strings.Cut(r.Err.Error(), "\n")
+			d.Errors = append(d.Errors, errorDigest{Workspace: r.WorkspacePath, Message: truncateText(msg, errorMessageMax)})
+		}
+	}
+	return d
+}
+
+// buildEventDigest summarizes results for a notification sent because ev
+// fired, which may have no drift to report. The title says what happened
+// when there is no drift or failing check to lead with.
+func buildEventDigest(results []report.ScanResult, ev Event) digest {
+	d := buildDigest(results)
+	d.Recovered = ev.Recovered
+	if !d.notable() {
+		switch {
+		case len(d.Errors) > 0:
+			d.Title = "❌ Terraform Scan Errors"
+		case len(d.Recovered) > 0:
+			d.Title = "✅ Terraform Drift Resolved"
+		default:
+			d.Title = "✅ No Terraform Drift"
+		}
+	}
+	return d
+}
+
+// notable reports whether the digest is worth sending: there is drift or a
+// failing health check.
+func (d digest) notable() bool {
+	return d.Summary.WorkspacesWithDrift > 0 || d.Summary.FailedChecks > 0
+}
+
+// ciRunURL returns a link to the CI run driftwatch is running in, read from
+// the environment of GitHub Actions, GitLab CI, Buildkite, CircleCI or
+// Jenkins, or "" outside CI.
+func ciRunURL() string {
+	server, repo, runID := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID")
+	if server != "" && repo != "" && runID != "" {
+		return server + "/" + repo + "/actions/runs/" + runID
+	}
+	for _, key := range []string{"CI_PIPELINE_URL", "BUILDKITE_BUILD_URL", "CIRCLE_BUILD_URL", "BUILD_URL"} {
+		if url := os.Getenv(key); url != "" {
+			return url
+		}
+	}
+	return ""
+}
+
+// truncateText shortens s to at most max bytes, marking the cut with "…".
+func truncateText(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max - len("…")
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
@@ -0,0 +1,190 @@
+package notify
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/daemonship/driftwatch/internal/config"
+	"github.com/daemonship/driftwatch/internal/report"
+	"github.com/daemonship/driftwatch/internal/state"
+)
+
+// StatefulNotifier is a Notifier that remembers what it delivered between
+// runs, e.g. to resolve the incidents it opened once their drift is gone.
+type StatefulNotifier interface {
+	Notifier
+	// NotifyWithState is Notify given what the target kept in st on earlier
+	// runs. It records in st what it delivered, and only that, so a failed
+	// delivery is attempted again on the next run.
+	NotifyWithState(results []report.ScanResult, st TargetState) error
+}
+
+// TargetState is the part of a state snapshot that belongs to one target,
+// so targets never read or overwrite each other's data. The zero value has
+// no snapshot: it loads nothing and saves nothing.
+type TargetState struct {
+	Snapshot *state.Snapshot
+	Target   string
+}
+
+// Load decodes the data the target stored under key into v and reports
+// whether there was any.
+func (s TargetState) Load(key string, v interface{}) (bool, error) {
+	return s.Snapshot.NotifierData(s.key(key), v)
+}
+
+// Save stores v under key for the target's next run; a nil v removes it.
+func (s TargetState) Save(key string, v interface{}) error {
+	return s.Snapshot.SetNotifierData(s.key(key), v)
+}
+
+// key places key under the target's name in the snapshot.
+func (s TargetState) key(key string) string {
+	return s.Target + "/" + key
+}
+
+// Target is a named notifier together with the filters narrowing the
+// results it receives.
+type Target struct {
+	Name     string
+	Notifier Notifier
+	Filters  []report.Filter
+	// Triggers lists when the target is notified (see TriggerOnDrift and
+	// the others); the Notifier must then be a TriggeredNotifier. Empty
+	// leaves the decision to the notifier.
+	Triggers []string
+}
+
+// Delivery is the outcome of notifying one target.
+type Delivery struct {
+	Target string
+	// Err is nil if the target was notified, or had nothing to send.
+	Err error
+	// Skipped is set when none of the target's triggers fired.
+	Skipped bool
+	// Duration is how long notifying the target took, retries included.
+	Duration time.Duration
+}
+
+// Dispatcher fans scan results out to several notification targets.
+type Dispatcher struct {
+	Targets []Target
+	// State is what targets kept from earlier runs. Stateful notifiers and
+	// targets with triggers read their part of it and record what they
+	// delivered.
+	State *state.Snapshot
+}
+
+// NeedsState reports whether any target is a StatefulNotifier or has
+// triggers, so the previous scan's state must be loaded and this scan's
+// saved.
+func (d *Dispatcher) NeedsState() bool {
+	for _, t := range d.Targets {
+		if _, ok := t.Notifier.(StatefulNotifier); ok || len(t.Triggers) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Dispatch notifies every target concurrently and waits for all of them.
+// It returns one Delivery per target, in target order; a failing target
+// does not affect the others. Targets with triggers are only notified when
+// one fires.
+func (d *Dispatcher) Dispatch(results []report.ScanResult) []Delivery {
+	deliveries := make([]Delivery, len(d.Targets))
+	var wg sync.WaitGroup
+	for i, t := range d.Targets {
+		wg.Add(1)
+		go func(i int, t Target) {
+			defer wg.Done()
+			start := time.Now()
+			filtered := report.FilterResults(results, t.Filters)
+			st := TargetState{Snapshot: d.State, Target: t.Name}
+			var err error
+			skipped := false
+			if tn, ok := t.Notifier.(TriggeredNotifier); ok && len(t.Triggers) > 0 {
+				skipped, err = notifyTriggered(tn, t.Triggers, filtered, st)
+			} else if sn, ok := t.Notifier.(StatefulNotifier); ok {
+				err = sn.NotifyWithState(filtered, st)
+			} else {
+				err = t.Notifier.Notify(filtered)
+			}
+			deliveries[i] = Delivery{Target: t.Name, Err: err, Skipped: skipped, Duration: time.Since(start)}
+		}(i, t)
+	}
+	wg.Wait()
+	return deliveries
+}
+
+// notifyTriggered notifies a target with triggers if one fires, comparing
+// results with what the target saw in the previous scan. It reports whether
+// the target was skipped. The target's record is kept in st; after a failed
+// delivery the old record stays, so the same triggers fire again on the next
+// run.
+func notifyTriggered(n TriggeredNotifier, triggers []string, results []report.ScanResult, st TargetState) (bool, error) {
+	var last triggerRecord
+	if _, err := st.Load("trigger", &last); err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	ev := evaluate(triggers, results, last, now)
+	if len(ev.Triggers) > 0 {
+		var err error
+		if sn, ok := n.(StatefulTriggeredNotifier); ok {
+			err = sn.NotifyEventWithState(results, ev, st)
+		} else {
+			err = n.NotifyEvent(results, ev)
+		}
+		if err != nil {
+			return false, err
+		}
+		last.LastSent = now
+	}
+
+	seen := state.New()
+	maps.Copy(seen.Workspaces, last.Workspaces)
+	seen.Record(results, now)
+	last.Workspaces = seen.Workspaces
+	return len(ev.Triggers) == 0, st.Save("trigger", last)
+}
+
+// TargetsFromConfig builds notification targets from the notifications
+// section of cfg. The legacy slack_webhook setting (or the
+// DRIFTWATCH_SLACK_WEBHOOK environment variable) adds a target named "slack"
+// unless one with that name is already configured.
+func TargetsFromConfig(cfg *config.Config) ([]Target, error) {
+	var targets []Target
+	names := make(map[string]bool)
+	for _, n := range cfg.Notifications {
+		notifier, err := New(n.Type, Settings(n.Settings), cfg)
+		if err != nil {
+			return nil, fmt.Errorf("notification %q: %w", n.Name, err)
+		}
+		t := Target{Name: n.Name, Notifier: notifier, Triggers: n.Triggers}
+		if _, ok := notifier.(TriggeredNotifier); len(t.Triggers) > 0 && !ok {
+			return nil, fmt.Errorf("notification %q: type %s does not support triggers", n.Name, n.Type)
+		}
+		for _, expr := range n.Filters {
+			f, err := report.ParseFilter(expr)
+			if err != nil {
+				return nil, fmt.Errorf("notification %q: %w", n.Name, err)
+			}
+			t.Filters = append(t.Filters, f)
+		}
+		targets = append(targets, t)
+		names[n.Name] = true
+	}
+
+	webhook := cfg.SlackWebhook
+	if webhook == "" {
+		webhook = WebhookFromEnv()
+	}
+	if webhook != "" && !names["slack"] {
+		targets = append(targets, Target{Name: "slack", Notifier: &SlackNotifier{WebhookURL: webhook}})
+	}
+	return targets, nil
+}
@@ -0,0 +1,148 @@
+package notify_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/daemonship/driftwatch/internal/config"
+	"github.com/daemonship/driftwatch/internal/notify"
+	"github.com/daemonship/driftwatch/internal/report"
+	"github.com/daemonship/driftwatch/internal/state"
+)
+
+func TestDispatch_CollectsPerTargetErrors(t *testing.T) {
+	ok := &recordingNotifier{}
+	failing := &recordingNotifier{err: errors.New("boom")}
+	d := &notify.Dispatcher{Targets: []notify.Target{
+		{Name: "ok", Notifier: ok},
+		{Name: "failing", Notifier: failing},
+	}}
+
+	deliveries := d.Dispatch(driftResults())
+	if len(deliveries) != 2 {
+		t.Fatalf("Dispatch() returned %d deliveries, want 2", len(deliveries))
+	}
+	if deliveries[0].Target != "ok" || deliveries[0].Err != nil {
+		t.Errorf("deliveries[0] = %+v, want ok without error", deliveries[0])
+	}
+	if deliveries[1].Target != "failing" || deliveries[1].Err == nil {
+		t.Errorf("deliveries[1] = %+v, want failing with error", deliveries[1])
+	}
+	if len(ok.results) != 1 {
+		t.Errorf("ok target got %d results, want 1 (unaffected by failing target)", len(ok.results))
+	}
+}
+
+func TestDispatch_AppliesTargetFilters(t *testing.T) {
+	f, err := report.ParseFilter("action=delete")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &recordingNotifier{}
+	d := &notify.Dispatcher{Targets: []notify.Target{{Name: "deletes", Notifier: n, Filters: []report.Filter{f}}}}
+	d.Dispatch(driftResults())
+
+	if len(n.results) != 1 || len(n.results[0].ResourceChanges) != 0 {
+		t.Errorf("filtered target got %+v, want update filtered out", n.results)
+	}
+}
+
+// statefulNotifier counts its runs in its target state.
+type statefulNotifier struct {
+	recordingNotifier
+	st notify.TargetState
+}
+
+func (n *statefulNotifier) NotifyWithState(results []report.ScanResult, st notify.TargetState) error {
+	n.st = st
+	var runs int
+	if _, err := st.Load("runs", &runs); err != nil {
+		return err
+	}
+	if err := st.Save("runs", runs+1); err != nil {
+		return err
+	}
+	return n.Notify(results)
+}
+
+func TestDispatch_PassesStateToStatefulNotifiers(t *testing.T) {
+	plain := &recordingNotifier{}
+	stateful := &statefulNotifier{}
+	d := &notify.Dispatcher{Targets: []notify.Target{{Name: "plain", Notifier: plain}}}
+	if d.NeedsState() {
+		t.Error("NeedsState() = true without stateful targets")
+	}
+
+	d.Targets = append(d.Targets, notify.Target{Name: "stateful", Notifier: stateful})
+	d.State = state.New()
+	if !d.NeedsState() {
+		t.Error("NeedsState() = false with a stateful target")
+	}
+	d.Dispatch(driftResults())
+	if stateful.st.Snapshot != d.State || stateful.st.Target != "stateful" || len(stateful.results) != 1 {
+		t.Errorf("stateful target got state %+v and %d results, want the dispatcher's snapshot", stateful.st, len(stateful.results))
+	}
+	if len(plain.results) != 1 {
+		t.Errorf("plain target got %d results, want 1", len(plain.results))
+	}
+}
+
+func TestDispatch_KeepsStatePerTarget(t *testing.T) {
+	first, second := &statefulNotifier{}, &statefulNotifier{}
+	d := &notify.Dispatcher{
+		Targets: []notify.Target{{Name: "first", Notifier: first}},
+		State:   state.New(),
+	}
+	d.Dispatch(driftResults())
+	d.Targets = append(d.Targets, notify.Target{Name: "second", Notifier: second})
+	d.Dispatch(driftResults())
+
+	for name, want := range map[string]int{"first": 2, "second": 1} {
+		var runs int
+		if _, err := (notify.TargetState{Snapshot: d.State, Target: name}).Load("runs", &runs); err != nil || runs != want {
+			t.Errorf("%s: runs = %d (err %v), want %d", name, runs, err, want)
+		}
+	}
+}
+
+func TestTargetsFromConfig(t *testing.T) {
+	t.Setenv("DRIFTWATCH_SLACK_WEBHOOK", "")
+	cfg := &config.Config{
+		SlackWebhook: "https://hooks.slack.com/services/legacy",
+		Notifications: []config.Notification{
+			{
+				Name:     "platform",
+				Type:     "slack",
+				Filters:  []string{"workspace=./infra/prod*"},
+				Settings: map[string]interface{}{"webhook_url": "https://hooks.slack.com/services/platform"},
+			},
+		},
+	}
+	targets, err := notify.TargetsFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("TargetsFromConfig() error = %v", err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("TargetsFromConfig() returned %d targets, want 2", len(targets))
+	}
+	if targets[0].Name != "platform" || len(targets[0].Filters) != 1 {
+		t.Errorf("targets[0] = %+v, want platform with one filter", targets[0])
+	}
+	if targets[1].Name != "slack" {
+		t.Errorf("targets[1].Name = %q, want legacy %q", targets[1].Name, "slack")
+	}
+}
+
+func TestTargetsFromConfig_InvalidFilter(t *testing.T) {
+	cfg := &config.Config{Notifications: []config.Notification{
+		{
+			Name:     "platform",
+			Type:     "slack",
+			Filters:  []string{"colour=red"},
+			Settings: map[string]interface{}{"webhook_url": "https://hooks.slack.com/services/x"},
+		},
+	}}
+	if _, err := notify.TargetsFromConfig(cfg); err == nil {
+		t.Error("TargetsFromConfig() error = nil, want error for invalid filter")
+	}
+}
@@ -0,0 +1,394 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daemonship/driftwatch/internal/config"
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+// TLS modes for EmailNotifier.TLS.
+const (
+	// EmailSTARTTLS upgrades a plain connection with STARTTLS (default, port 587).
+	EmailSTARTTLS = "starttls"
+	// EmailImplicitTLS connects over TLS from the start (port 465).
+	EmailImplicitTLS = "implicit"
+	// EmailNoTLS sends in the clear; only for local relays (port 25).
+	EmailNoTLS = "none"
+)
+
+// emailTimeout bounds a whole SMTP conversation.
+const emailTimeout = 30 * time.Second
+
+// EmailNotifier emails drift reports over SMTP. Every address in To gets the
+// full report; workspace owners get a report of the workspaces they own.
+// Messages are multipart/alternative with the text report and a static HTML
+// report, without the script and filter controls mail clients would strip.
+type EmailNotifier struct {
+	// Host and Port address the SMTP server. Port defaults by TLS mode.
+	Host string
+	Port int
+	// Username and Password enable SMTP PLAIN authentication when set.
+	Username string
+	Password string
+	// From is the sender address.
+	From string
+	// To lists recipients of the full report.
+	To []string
+	// Owners maps workspace paths to the addresses of their owners.
+	Owners map[string][]string
+	// TLS is EmailSTARTTLS (default), EmailImplicitTLS or EmailNoTLS.
+	TLS string
+	// Subject overrides the default subject line.
+	Subject string
+	// TLSConfig customizes TLS, e.g. with extra root CAs; ServerName
+	// defaults to Host.
+	TLSConfig *tls.Config
+}
+
+func init() {
+	Register("email", newEmailNotifier)
+}
+
+// newEmailNotifier builds an EmailNotifier from settings: host, port, tls,
+// username, password, from, to and subject. ${VAR} references are expanded
+// in username and password. Owners come from the workspace entries in cfg.
+func newEmailNotifier(settings Settings, cfg *config.Config) (Notifier, error) {
+	n := &EmailNotifier{}
+	var err error
+	for key, dst := range map[string]*string{
+		"host":     &n.Host,
+		"tls":      &n.TLS,
+		"username": &n.Username,
+		"password": &n.Password,
+		"from":     &n.From,
+		"subject":  &n.Subject,
+	} {
+		if *dst, err = settings.String(key); err != nil {
+			return nil, err
+		}
+	}
+	if n.Port, err = settings.Int("port"); err != nil {
+		return nil, err
+	}
+	if n.To, err = settings.StringSlice("to"); err != nil {
+		return nil, err
+	}
+	n.Username, n.Password = expandEnv(n.Username), expandEnv(n.Password)
+
+	for path, ws := range cfg.WorkspaceSettings {
+		if len(ws.Owners) > 0 {
+			if n.Owners == nil {
+				n.Owners = make(map[string][]string)
+			}
+			n.Owners[path] = ws.Owners
+		}
+	}
+
+	switch {
+	case n.Host == "":
+		return nil, fmt.Errorf("email: host is required")
+	case n.From == "":
+		return nil, fmt.Errorf("email: from is required")
+	case len(n.To) == 0 && len(n.Owners) == 0:
+		return nil, fmt.Errorf("email: to is required when no workspace has owners")
+	}
+	switch n.TLS {
+	case "", EmailSTARTTLS, EmailImplicitTLS, EmailNoTLS:
+	default:
+		return nil, fmt.Errorf("email: unknown tls mode %q (want starttls, implicit or none)", n.TLS)
+	}
+	// net/smtp refuses to send PLAIN credentials in the clear to anything
+	// but localhost, so fail here rather than on every run.
+	if n.TLS == EmailNoTLS && n.Username != "" && !isLocalhost(n.Host) {
+		return nil, fmt.Errorf("email: username and password need tls starttls or implicit unless host is localhost")
+	}
+	return n, nil
+}
+
+// isLocalhost reports whether host is one net/smtp trusts with credentials
+// over an unencrypted connection.
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+// Notify emails each recipient a report of the workspaces they receive, if
+// any of those drifted or have failing health checks. Recipients that would
+// get identical reports share one message. Delivery errors are joined.
+func (n *EmailNotifier) Notify(results []report.ScanResult) error {
+	return n.notify(results, nil)
+}
+
+// NotifyEvent emails the report because a trigger fired: the addresses in
+// To always get it, and owners when their workspaces drifted, have failing
+// health checks, failed to scan or recovered.
+func (n *EmailNotifier) NotifyEvent(results []report.ScanResult, ev Event) error {
+	return n.notify(results, &ev)
+}
+
+// notify sends the messages planned for results and ev, which is nil
+// outside of triggers.
+func (n *EmailNotifier) notify(results []report.ScanResult, ev *Event) error {
+	var errs []error
+	for _, m := range n.plan(results, ev) {
+		msg, err := n.buildMessage(m.To, m.Results, ev)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := n.send(m.To, msg); err != nil {
+			errs = append(errs, fmt.Errorf("emailing %s: %w", strings.Join(m.To, ", "), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// emailMessage is one message to send: its recipients and their results.
+type emailMessage struct {
+	To      []string
+	Results []report.ScanResult
+}
+
+// plan works out which results each recipient gets and merges recipients
+// with the same results into one message.
+func (n *EmailNotifier) plan(results []report.ScanResult, ev *Event) []emailMessage {
+	workspaces := make(map[string][]string) // address → owned workspace paths; nil means all
+	for _, addr := range n.To {
+		workspaces[addr] = nil
+	}
+	for _, r := range results {
+		for _, addr := range n.Owners[r.WorkspacePath] {
+			if paths, ok := workspaces[addr]; ok && paths == nil {
+				continue // already receives the full report
+			}
+			workspaces[addr] = append(workspaces[addr], r.WorkspacePath)
+		}
+	}
+
+	addrs := make([]string, 0, len(workspaces))
+	for addr := range workspaces {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	var messages []emailMessage
+	index := make(map[string]int) // workspace set → message index
+	for _, addr := range addrs {
+		paths := workspaces[addr]
+		key := "*"
+		subset := results
+		if paths != nil {
+			key = strings.Join(paths, "\x00")
+			subset = selectWorkspaces(results, paths)
+		}
+		if i, ok := index[key]; ok {
+			messages[i].To = append(messages[i].To, addr)
+			continue
+		}
+		if !emailWorthSending(subset, paths == nil, ev) {
+			continue
+		}
+		index[key] = len(messages)
+		messages = append(messages, emailMessage{To: []string{addr}, Results: subset})
+	}
+	return messages
+}
+
+// emailWorthSending reports whether a recipient gets a message with
+// results, all of them if full. Without an event only drift and failing
+// health checks are worth it; for an event, recipients of the full report
+// always get it, and owners when their workspaces failed to scan or
+// recovered.
+func emailWorthSending(results []report.ScanResult, full bool, ev *Event) bool {
+	d := buildDigest(results)
+	switch {
+	case d.notable():
+		return true
+	case ev == nil:
+		return false
+	case full || len(d.Errors) > 0:
+		return true
+	}
+	return len(recoveredIn(results, *ev)) > 0
+}
+
+// selectWorkspaces returns the results whose workspace is in paths.
+func selectWorkspaces(results []report.ScanResult, paths []string) []report.ScanResult {
+	want := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		want[p] = true
+	}
+	var out []report.ScanResult
+	for _, r := range results {
+		if want[r.WorkspacePath] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// buildMessage renders an RFC 5322 message with text and HTML alternatives.
+// For an event, the subject says what happened when there is no drift or
+// failing check to lead with.
+func (n *EmailNotifier) buildMessage(to []string, results []report.ScanResult, ev *Event) ([]byte, error) {
+	summary := report.Summarize(results)
+	var recovered []string
+	if ev != nil {
+		recovered = recoveredIn(results, *ev)
+	}
+	subject := n.Subject
+	if subject == "" {
+		switch {
+		case summary.WorkspacesWithDrift > 0:
+			subject = fmt.Sprintf("[driftwatch] Drift detected in %d workspace(s)", summary.WorkspacesWithDrift)
+		case summary.FailedChecks > 0:
+			subject = fmt.Sprintf("[driftwatch] %d health check failure(s)", summary.FailedChecks)
+		case summary.ScanErrors > 0:
+			subject = fmt.Sprintf("[driftwatch] Scan failed in %d workspace(s)", summary.ScanErrors)
+		case len(recovered) > 0:
+			subject = fmt.Sprintf("[driftwatch] Drift resolved in %d workspace(s)", len(recovered))
+		default:
+			subject = "[driftwatch] No drift detected"
+		}
+	}
+
+	var text, html bytes.Buffer
+	if len(recovered) > 0 {
+		fmt.Fprintf(&text, "Clean again since the last scan: %s\n\n", strings.Join(recovered, ", "))
+	}
+	report.Print(&text, results)
+	if err := report.WriteStaticHTML(&html, results, report.Metadata{GeneratedAt: time.Now()}); err != nil {
+		return nil, fmt.Errorf("rendering HTML email: %w", err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// send delivers msg to the recipients in one SMTP conversation.
+func (n *EmailNotifier) send(to []string, msg []byte) error {
+	port := n.Port
+	if port == 0 {
+		switch n.TLS {
+		case EmailImplicitTLS:
+			port = 465
+		case EmailNoTLS:
+			port = 25
+		default:
+			port = 587
+		}
+	}
+	addr := net.JoinHostPort(n.Host, strconv.Itoa(port))
+
+	tlsConfig := &tls.Config{}
+	if n.TLSConfig != nil {
+		tlsConfig = n.TLSConfig.Clone()
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = n.Host
+	}
+
+	dialer := &net.Dialer{Timeout: emailTimeout}
+	var conn net.Conn
+	var err error
+	if n.TLS == EmailImplicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(emailTimeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, n.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if n.TLS == "" || n.TLS == EmailSTARTTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("server %s does not support STARTTLS", addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if n.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.Username, n.Password, n.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(n.From); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,443 @@
+package notify_test
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/daemonship/driftwatch/internal/config"
+	"github.com/daemonship/driftwatch/internal/notify"
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+// receivedMail is one message accepted by the stub SMTP server.
+type receivedMail struct {
+	From string
+	To   []string
+	Data string
+	Auth string // decoded AUTH PLAIN credentials
+	TLS  bool
+}
+
+// smtpServer is a minimal in-process SMTP server for tests. It speaks just
+// enough ESMTP for net/smtp: EHLO, STARTTLS, AUTH PLAIN, MAIL, RCPT, DATA
+// and QUIT.
+type smtpServer struct {
+	Host string
+	Port int
+	// RootCAs trusts the server's certificate.
+	RootCAs *x509.CertPool
+
+	tlsConfig *tls.Config
+	startTLS  bool
+
+	mu   sync.Mutex
+	mail []receivedMail
+}
+
+// newSMTPServer starts a stub server. With implicit set it speaks TLS from
+// the start; otherwise it offers STARTTLS.
+func newSMTPServer(t *testing.T, implicit bool) *smtpServer {
+	t.Helper()
+	// Borrow httptest's self-signed certificate for 127.0.0.1.
+	certSrv := httptest.NewTLSServer(http.NotFoundHandler())
+	s := &smtpServer{
+		RootCAs:   certSrv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs,
+		tlsConfig: &tls.Config{Certificates: certSrv.TLS.Certificates},
+		startTLS:  !implicit,
+	}
+	certSrv.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	if implicit {
+		ln = tls.NewListener(ln, s.tlsConfig)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	s.Host = host
+	s.Port, _ = strconv.Atoi(port)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, implicit)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn, secure bool) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	var m receivedMail
+	reply("220 stub ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			reply("250-stub")
+			if s.startTLS && !secure {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, r, secure = tlsConn, bufio.NewReader(tlsConn), true
+		case "AUTH":
+			_, cred, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(cred)
+			m.Auth = string(decoded)
+			reply("235 ok")
+		case "MAIL":
+			m.From = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			m.TLS = secure
+			reply("250 ok")
+		case "RCPT":
+			m.To = append(m.To, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			m.Data = data.String()
+			s.mu.Lock()
+			s.mail = append(s.mail, m)
+			s.mu.Unlock()
+			m = receivedMail{Auth: m.Auth}
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// Mail returns the messages received so far.
+func (s *smtpServer) Mail() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMail(nil), s.mail...)
+}
+
+func (s *smtpServer) notifier() *notify.EmailNotifier {
+	return &notify.EmailNotifier{
+		Host:      s.Host,
+		Port:      s.Port,
+		From:      "driftwatch@example.com",
+		TLSConfig: &tls.Config{RootCAs: s.RootCAs},
+	}
+}
+
+// mailParts parses a received message and returns its Subject and the
+// decoded body of each MIME part keyed by media type.
+func mailParts(t *testing.T, data string) (string, map[string]string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("parsing message: %v\n%s", err, data)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+	}
+	parts := make(map[string]string)
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart() // decodes quoted-printable
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading part: %v", err)
+		}
+		body, _ := io.ReadAll(p)
+		partType, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		parts[partType] = string(body)
+	}
+	return subject, parts
+}
+
+func TestEmail_STARTTLSWithAuth(t *testing.T) {
+	srv := newSMTPServer(t, false)
+	n := srv.notifier()
+	n.Username, n.Password = "bot", "hunter2"
+	n.To = []string{"ops@example.com"}
+
+	if err := n.Notify(driftResults()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	got := srv.Mail()
+	if len(got) != 1 {
+		t.Fatalf("received %d messages, want 1", len(got))
+	}
+	m := got[0]
+	if !m.TLS {
+		t.Error("message was sent before STARTTLS")
+	}
+	if m.Auth != "\x00bot\x00hunter2" {
+		t.Errorf("AUTH PLAIN credentials = %q", m.Auth)
+	}
+	if m.From != "driftwatch@example.com" || len(m.To) != 1 || m.To[0] != "ops@example.com" {
+		t.Errorf("envelope = %s → %v", m.From, m.To)
+	}
+
+	subject, parts := mailParts(t, m.Data)
+	if subject != "[driftwatch] Drift detected in 1 workspace(s)" {
+		t.Errorf("Subject = %q", subject)
+	}
+	if !strings.Contains(parts["text/plain"], "~ aws_instance.web") {
+		t.Errorf("text part is not the text report:\n%s", parts["text/plain"])
+	}
+	if !strings.Contains(parts["text/html"], "<html") || !strings.Contains(parts["text/html"], "aws_instance.web") {
+		t.Errorf("HTML part is not the HTML report:\n%s", parts["text/html"])
+	}
+	if strings.Contains(parts["text/html"], "<script") || strings.Contains(parts["text/html"], "<input") {
+		t.Error("HTML part has the report's script or filter controls, want a static body")
+	}
+}
+
+func TestEmail_ImplicitTLS(t *testing.T) {
+	srv := newSMTPServer(t, true)
+	n := srv.notifier()
+	n.TLS = notify.EmailImplicitTLS
+	n.To = []string{"ops@example.com"}
+
+	if err := n.Notify(driftResults()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if got := srv.Mail(); len(got) != 1 || !got[0].TLS {
+		t.Errorf("received %+v, want one message over TLS", got)
+	}
+}
+
+func TestEmail_RoutesWorkspacesToOwners(t *testing.T) {
+	srv := newSMTPServer(t, false)
+	n := srv.notifier()
+	n.To = []string{"ops@example.com"}
+	n.Owners = map[string][]string{
+		"./infra/staging":    {"alice@example.com", "bob@example.com"},
+		"./infra/production": {"carol@example.com"},
+		"./infra/clean":      {"dave@example.com"},
+	}
+	results := []report.ScanResult{
+		{WorkspacePath: "./infra/staging", ResourceChanges: []report.ResourceChange{{Address: "aws_instance.web", Action: "update"}}},
+		{WorkspacePath: "./infra/production", ResourceChanges: []report.ResourceChange{{Address: "aws_s3_bucket.logs", Action: "delete"}}},
+		{WorkspacePath: "./infra/clean"},
+	}
+	if err := n.Notify(results); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	byRecipients := make(map[string]string)
+	for _, m := range srv.Mail() {
+		_, parts := mailParts(t, m.Data)
+		byRecipients[strings.Join(m.To, ",")] = parts["text/plain"]
+	}
+	if len(byRecipients) != 3 {
+		t.Fatalf("messages by recipients = %v, want ops, staging owners and production owner", byRecipients)
+	}
+	if text := byRecipients["ops@example.com"]; !strings.Contains(text, "aws_instance.web") || !strings.Contains(text, "aws_s3_bucket.logs") {
+		t.Errorf("full report is missing workspaces:\n%s", text)
+	}
+	if text := byRecipients["alice@example.com,bob@example.com"]; !strings.Contains(text, "aws_instance.web") || strings.Contains(text, "aws_s3_bucket.logs") {
+		t.Errorf("staging owners' report should only cover staging:\n%s", text)
+	}
+	if text := byRecipients["carol@example.com"]; !strings.Contains(text, "aws_s3_bucket.logs") || strings.Contains(text, "aws_instance.web") {
+		t.Errorf("production owner's report should only cover production:\n%s", text)
+	}
+}
+
+func TestEmail_NotifyEvent(t *testing.T) {
+	srv := newSMTPServer(t, false)
+	n := srv.notifier()
+	n.To = []string{"ops@example.com"}
+	n.Owners = map[string][]string{
+		"./infra/staging":    {"alice@example.com"},
+		"./infra/production": {"carol@example.com"},
+	}
+	results := []report.ScanResult{
+		{WorkspacePath: "./infra/staging"},
+		{WorkspacePath: "./infra/production"},
+	}
+
+	// A recovery reaches the full-report recipients and the owners of the
+	// recovered workspace
+	ev := notify.Event{Triggers: []string{notify.TriggerOnRecovery}, Recovered: []string{"./infra/staging"}}
+	if err := n.NotifyEvent(results, ev); err != nil {
+		t.Fatalf("NotifyEvent() error = %v", err)
+	}
+	subjects := make(map[string]string)
+	for _, m := range srv.Mail() {
+		subject, parts := mailParts(t, m.Data)
+		subjects[strings.Join(m.To, ",")] = subject
+		if !strings.Contains(parts["text/plain"], "Clean again since the last scan: ./infra/staging") {
+			t.Errorf("text part to %v does not name the recovered workspace:\n%s", m.To, parts["text/plain"])
+		}
+	}
+	want := "[driftwatch] Drift resolved in 1 workspace(s)"
+	if len(subjects) != 2 || subjects["ops@example.com"] != want || subjects["alice@example.com"] != want {
+		t.Errorf("subjects by recipients = %v, want %q to ops and alice only", subjects, want)
+	}
+
+	// A heartbeat on a clean scan only reaches the full-report recipients
+	if err := n.NotifyEvent(results, notify.Event{Triggers: []string{notify.TriggerAlways}}); err != nil {
+		t.Fatalf("NotifyEvent() error = %v", err)
+	}
+	got := srv.Mail()[2:]
+	if len(got) != 1 || got[0].To[0] != "ops@example.com" {
+		t.Fatalf("heartbeat messages = %+v, want one to ops", got)
+	}
+	if subject, _ := mailParts(t, got[0].Data); subject != "[driftwatch] No drift detected" {
+		t.Errorf("heartbeat Subject = %q", subject)
+	}
+}
+
+func TestEmail_Subjects(t *testing.T) {
+	srv := newSMTPServer(t, false)
+	n := srv.notifier()
+	n.To = []string{"ops@example.com"}
+
+	checks := []report.ScanResult{{WorkspacePath: "./infra/staging", CheckFailures: []report.CheckFailure{{Address: "check.health", Status: "fail"}}}}
+	if err := n.Notify(checks); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	errored := []report.ScanResult{{WorkspacePath: "./infra/staging", Err: errors.New("no credentials")}}
+	if err := n.NotifyEvent(errored, notify.Event{Triggers: []string{notify.TriggerOnError}}); err != nil {
+		t.Fatalf("NotifyEvent() error = %v", err)
+	}
+
+	want := []string{"[driftwatch] 1 health check failure(s)", "[driftwatch] Scan failed in 1 workspace(s)"}
+	got := srv.Mail()
+	if len(got) != len(want) {
+		t.Fatalf("received %d messages, want %d", len(got), len(want))
+	}
+	for i, m := range got {
+		if subject, _ := mailParts(t, m.Data); subject != want[i] {
+			t.Errorf("Subject = %q, want %q", subject, want[i])
+		}
+	}
+}
+
+func TestEmail_SilentOnNoDrift(t *testing.T) {
+	srv := newSMTPServer(t, false)
+	n := srv.notifier()
+	n.To = []string{"ops@example.com"}
+	if err := n.Notify(noDriftResults()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if got := srv.Mail(); len(got) != 0 {
+		t.Errorf("received %d messages on a clean scan, want none", len(got))
+	}
+}
+
+func TestEmail_RequiresSTARTTLSSupport(t *testing.T) {
+	srv := newSMTPServer(t, false)
+	srv.startTLS = false
+	n := srv.notifier()
+	n.To = []string{"ops@example.com"}
+
+	if err := n.Notify(driftResults()); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("Notify() error = %v, want STARTTLS unsupported", err)
+	}
+	if got := srv.Mail(); len(got) != 0 {
+		t.Errorf("received %d messages in the clear, want none", len(got))
+	}
+}
+
+func TestNew_Email(t *testing.T) {
+	t.Setenv("SMTP_PASSWORD", "hunter2")
+	cfg := &config.Config{WorkspaceSettings: map[string]config.Workspace{
+		"./infra/staging": {Path: "./infra/staging", Owners: []string{"alice@example.com"}},
+	}}
+
+	n, err := notify.New("email", notify.Settings{
+		"host":     "smtp.example.com",
+		"port":     2525,
+		"username": "bot",
+		"password": "${SMTP_PASSWORD}",
+		"from":     "driftwatch@example.com",
+		"to":       []interface{}{"ops@example.com"},
+	}, cfg)
+	if err != nil {
+		t.Fatalf("New(email) error = %v", err)
+	}
+	email, ok := n.(*notify.EmailNotifier)
+	if !ok {
+		t.Fatalf("New(email) = %T, want *notify.EmailNotifier", n)
+	}
+	if email.Port != 2525 || email.Password != "hunter2" || len(email.To) != 1 {
+		t.Errorf("EmailNotifier = %+v", email)
+	}
+	if owners := email.Owners["./infra/staging"]; len(owners) != 1 || owners[0] != "alice@example.com" {
+		t.Errorf("Owners = %v, want staging owners from config", email.Owners)
+	}
+
+	// Owners alone are enough recipients.
+	if _, err := notify.New("email", notify.Settings{"host": "smtp.example.com", "from": "a@example.com"}, cfg); err != nil {
+		t.Errorf("New(email) with owners only error = %v", err)
+	}
+
+	// A local relay may take credentials without TLS.
+	if _, err := notify.New("email", notify.Settings{
+		"host": "localhost", "tls": "none", "username": "bot", "password": "hunter2",
+		"from": "a@example.com", "to": "b@example.com",
+	}, nil); err != nil {
+		t.Errorf("New(email) with auth to localhost without TLS error = %v", err)
+	}
+
+	for name, settings := range map[string]notify.Settings{
+		"missing host":  {"from": "a@example.com", "to": "b@example.com"},
+		"missing from":  {"host": "smtp.example.com", "to": "b@example.com"},
+		"no recipients": {"host": "smtp.example.com", "from": "a@example.com"},
+		"bad tls":       {"host": "smtp.example.com", "from": "a@example.com", "to": "b@example.com", "tls": "ssl"},
+		"bad port":      {"host": "smtp.example.com", "from": "a@example.com", "to": "b@example.com", "port": "587"},
+		"auth in clear": {"host": "smtp.example.com", "from": "a@example.com", "to": "b@example.com", "tls": "none", "username": "bot", "password": "hunter2"},
+	} {
+		if _, err := notify.New("email", settings, nil); err == nil {
+			t.Errorf("%s: New(email) error = nil, want error", name)
+		}
+	}
+}
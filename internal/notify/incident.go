@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+// incidentKeyPrefix starts every incident key, so driftwatch's alerts are
+// recognizable in the incident tool.
+const incidentKeyPrefix = "driftwatch:"
+
+// incident is an alert that an incident-style notifier (PagerDuty, Opsgenie)
+// should open or resolve. Key is stable across runs so repeated triggers
+// update one alert instead of opening new ones.
+type incident struct {
+	Key       string
+	Workspace string
+	// Resources are the drifted resources the incident covers; empty for
+	// resolutions.
+	Resources []report.ResourceChange
+	Resolve   bool
+}
+
+// incidentKey returns the dedup key for a workspace, or for one of its
+// resources when address is set.
+func incidentKey(workspace, address string) string {
+	if address == "" {
+		return incidentKeyPrefix + workspace
+	}
+	return incidentKeyPrefix + workspace + "#" + address
+}
+
+// incidents compares a scan with the incidents a target has open, which
+// map each incident key to its workspace. Drifted workspaces get an incident
+// to open, one per workspace or, with perResource, one per drifted resource.
+// Open incidents of a scanned workspace that the scan does not reopen are
+// resolved. Workspaces that failed to scan are left alone, as their drift is
+// unknown; so are workspaces not in results.
+func incidents(results []report.ScanResult, open map[string]string, perResource bool) []incident {
+	var out []incident
+	for _, r := range results {
+		if r.Status() == report.StatusError {
+			continue
+		}
+
+		current := make(map[string]bool)
+		switch {
+		case perResource:
+			for _, rc := range r.ResourceChanges {
+				key := incidentKey(r.WorkspacePath, rc.Address)
+				current[key] = true
+				out = append(out, incident{Key: key, Workspace: r.WorkspacePath, Resources: []report.ResourceChange{rc}})
+			}
+		case len(r.ResourceChanges) > 0:
+			key := incidentKey(r.WorkspacePath, "")
+			current[key] = true
+			out = append(out, incident{Key: key, Workspace: r.WorkspacePath, Resources: r.ResourceChanges})
+		}
+		for _, key := range sortedNames(open) {
+			if open[key] == r.WorkspacePath && !current[key] {
+				out = append(out, incident{Key: key, Workspace: r.WorkspacePath, Resolve: true})
+			}
+		}
+	}
+	return out
+}
+
+// notifyIncidents opens and resolves the incidents for results with send,
+// keeping the incidents the target has open in st. An incident only counts
+// as opened or resolved once send succeeds, so a failed event is sent again
+// on the next run. Every event is attempted; errors are joined.
+func notifyIncidents(results []report.ScanResult, st TargetState, perResource bool, send func(incident) error) error {
+	open := make(map[string]string)
+	if _, err := st.Load("incidents", &open); err != nil {
+		return err
+	}
+	var errs []error
+	for _, inc := range incidents(results, open, perResource) {
+		if err := send(inc); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", inc.Key, err))
+			continue
+		}
+		if inc.Resolve {
+			delete(open, inc.Key)
+		} else {
+			open[inc.Key] = inc.Workspace
+		}
+	}
+	if len(open) == 0 {
+		errs = append(errs, st.Save("incidents", nil))
+	} else {
+		errs = append(errs, st.Save("incidents", open))
+	}
+	return errors.Join(errs...)
+}
+
+// actionRank orders resource actions by how disruptive the drift is, most
+// disruptive first.
+var actionRank = map[string]int{"delete": 0, "replace": 1, "update": 2, "create": 3, "read": 4}
+
+// worstAction returns the most disruptive action among resources. Unknown
+// actions rank below all known ones.
+func worstAction(resources []report.ResourceChange) string {
+	rank := func(action string) int {
+		if r, ok := actionRank[action]; ok {
+			return r
+		}
+		return len(actionRank)
+	}
+	worst := ""
+	for _, rc := range resources {
+		if worst == "" || rank(rc.Action) < rank(worst) {
+			worst = rc.Action
+		}
+	}
+	return worst
+}
+
+// incidentSummary is a one-line description of an incident to open.
+func incidentSummary(inc incident) string {
+	if len(inc.Resources) == 1 {
+		rc := inc.Resources[0]
+		return fmt.Sprintf("Terraform drift in %s: %s (%s)", inc.Workspace, rc.Address, rc.Action)
+	}
+	actions := make(map[string]int)
+	for _, rc := range inc.Resources {
+		actions[rc.Action]++
+	}
+	return fmt.Sprintf("Terraform drift in %s: %d resources (%s)",
+		inc.Workspace, len(inc.Resources), report.FormatCounts(report.SortedCounts(actions)))
+}
+
+// incidentDetails maps the address of each resource in inc to its action,
+// for the structured details of an alert.
+func incidentDetails(inc incident) map[string]string {
+	details := make(map[string]string, len(inc.Resources))
+	for _, rc := range inc.Resources {
+		details[rc.Address] = rc.Action
+	}
+	return details
+}
+
+// sortedNames returns the keys of m in order.
+func sortedNames(m map[string]string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/daemonship/driftwatch/internal/config"
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+// Notifier delivers scan results to an external service.
+type Notifier interface {
+	Notify(results []report.ScanResult) error
+}
+
+// Settings holds the type-specific settings of a notification target, as
+// written inline in its config entry.
+type Settings map[string]interface{}
+
+// String returns the string setting key, or "" if it is not set. It returns
+// an error if the setting is present but not a string.
+func (s Settings) String(key string) (string, error) {
+	v, ok := s[key]
+	if !ok || v == nil {
+		return "", nil
+	}
+	str, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("setting %q: want a string, got %T", key, v)
+	}
+	return str, nil
+}
+
+// StringMap returns the string-to-string map setting key, or nil if it is
+// not set. It returns an error if the setting is not a map of strings.
+func (s Settings) StringMap(key string) (map[string]string, error) {
+	v, ok := s[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("setting %q: want a map, got %T", key, v)
+	}
+	out := make(map[string]string, len(m))
+	for k, val := range m {
+		str, ok := val.(string)
+		if !ok {
+			return nil, fmt.Errorf("setting %q: value for %q: want a string, got %T", key, k, val)
+		}
+		out[k] = str
+	}
+	return out, nil
+}
+
+// Bool returns the boolean setting key, or false if it is not set.
+func (s Settings) Bool(key string) (bool, error) {
+	v, ok := s[key]
+	if !ok || v == nil {
+		return false, nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("setting %q: want true or false, got %T", key, v)
+	}
+	return b, nil
+}
+
+// Int returns the integer setting key, or 0 if it is not set.
+func (s Settings) Int(key string) (int, error) {
+	v, ok := s[key]
+	if !ok || v == nil {
+		return 0, nil
+	}
+	n, ok := v.(int)
+	if !ok {
+		return 0, fmt.Errorf("setting %q: want an integer, got %T", key, v)
+	}
+	return n, nil
+}
+
+// StringSlice returns the list-of-strings setting key, or nil if it is not
+// set. A single string is returned as a one-element list.
+func (s Settings) StringSlice(key string) ([]string, error) {
+	v, ok := s[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	switch val := v.(type) {
+	case string:
+		return []string{val}, nil
+	case []interface{}:
+		out := make([]string, 0, len(val))
+		for _, item := range val {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("setting %q: want a list of strings, got %T item", key, item)
+			}
+			out = append(out, str)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("setting %q: want a list of strings, got %T", key, v)
+	}
+}
+
+// Factory builds a notifier from its settings. cfg is the loaded
+// configuration, for notifiers that need workspace owners or tags.
+type Factory func(settings Settings, cfg *config.Config) (Notifier, error)
+
+// factories holds registered notifier factories by type.
+var factories = make(map[string]Factory)
+
+// Register makes a notifier type available to the notifications config. It
+// panics if typ is already registered or f is nil, since that is a
+// programming error.
+func Register(typ string, f Factory) {
+	if f == nil {
+		panic("notify: Register factory is nil")
+	}
+	if _, dup := factories[typ]; dup {
+		panic(fmt.Sprintf("notify: Register called twice for notifier type %q", typ))
+	}
+	factories[typ] = f
+}
+
+// New builds a notifier of the registered type typ from settings. A nil cfg
+// is treated as an empty configuration.
+func New(typ string, settings Settings, cfg *config.Config) (Notifier, error) {
+	f, ok := factories[typ]
+	if !ok {
+		return nil, fmt.Errorf("unknown notifier type %q (want %s)", typ, strings.Join(Types(), ", "))
+	}
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	return f(settings, cfg)
+}
+
+// Types returns the registered notifier types, sorted.
+func Types() []string {
+	types := make([]string, 0, len(factories))
+	for typ := range factories {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// envRef matches a ${VAR} reference to an environment variable.
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${VAR} references in s with the values of the
+// environment variables. Unlike os.ExpandEnv it leaves any other "$" alone,
+// so literal secrets and headers containing one are sent as written.
+func expandEnv(s string) string {
+	return envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		return os.Getenv(ref[2 : len(ref)-1])
+	})
+}
@@ -0,0 +1,67 @@
+package notify_test
+
+import (
+	"testing"
+
+	"github.com/daemonship/driftwatch/internal/config"
+	"github.com/daemonship/driftwatch/internal/notify"
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+// recordingNotifier records the results it is given and returns err.
+type recordingNotifier struct {
+	results []report.ScanResult
+	err     error
+}
+
+func (n *recordingNotifier) Notify(results []report.ScanResult) error {
+	n.results = results
+	return n.err
+}
+
+func TestTypes_SlackRegistered(t *testing.T) {
+	found := false
+	for _, typ := range notify.Types() {
+		if typ == "slack" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Types() = %v, want slack registered", notify.Types())
+	}
+}
+
+func TestNew_UnknownType(t *testing.T) {
+	if _, err := notify.New("carrier-pigeon", nil, nil); err == nil {
+		t.Error("New() error = nil, want error for unknown type")
+	}
+}
+
+func TestNew_SlackRequiresWebhook(t *testing.T) {
+	t.Setenv("DRIFTWATCH_SLACK_WEBHOOK", "")
+	if _, err := notify.New("slack", notify.Settings{}, nil); err == nil {
+		t.Error("New(slack) error = nil, want error without webhook_url")
+	}
+	n, err := notify.New("slack", notify.Settings{"webhook_url": "https://hooks.slack.com/services/x"}, nil)
+	if err != nil {
+		t.Fatalf("New(slack) error = %v", err)
+	}
+	if s, ok := n.(*notify.SlackNotifier); !ok || s.WebhookURL != "https://hooks.slack.com/services/x" {
+		t.Errorf("New(slack) = %#v, want SlackNotifier with webhook URL", n)
+	}
+}
+
+func TestSettingsString_WrongType(t *testing.T) {
+	if _, err := (notify.Settings{"webhook_url": 42}).String("webhook_url"); err == nil {
+		t.Error("String() error = nil, want error for non-string setting")
+	}
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Register() did not panic on duplicate type")
+		}
+	}()
+	notify.Register("slack", func(notify.Settings, *config.Config) (notify.Notifier, error) { return &recordingNotifier{}, nil })
+}
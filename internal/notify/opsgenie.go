@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/daemonship/driftwatch/internal/config"
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+// OpsgenieAPIURL is the Opsgenie API in the US region; EU accounts use
+// https://api.eu.opsgenie.com.
+const OpsgenieAPIURL = "https://api.opsgenie.com"
+
+// opsgenieMessageMax is the longest alert message Opsgenie accepts.
+const opsgenieMessageMax = 130
+
+// opsgeniePriorities maps the most disruptive action of an alert's drift to
+// its priority. Anything else is P4.
+var opsgeniePriorities = map[string]string{
+	"delete":  "P1",
+	"replace": "P2",
+	"update":  "P3",
+}
+
+// OpsgenieNotifier creates Opsgenie alerts for drift and closes them once a
+// later scan finds the drift gone. Each workspace (or, with PerResource, each
+// drifted resource) has a stable alias, so repeated scans add to one alert
+// instead of creating more. Priority follows the drift's action mix: deletes
+// are P1, replacements P2, updates P3 and the rest P4.
+type OpsgenieNotifier struct {
+	// APIKey is an Opsgenie API integration key.
+	APIKey string
+	// APIURL overrides OpsgenieAPIURL.
+	APIURL string
+	// PerResource creates one alert per drifted resource instead of one per
+	// workspace.
+	PerResource bool
+	// Tags are added to every alert.
+	Tags []string
+	// WorkspaceTags maps workspace paths to tags added to their alerts.
+	WorkspaceTags map[string][]string
+	// Retry controls retries of failed requests; see RetryPolicy.
+	Retry RetryPolicy
+}
+
+func init() {
+	Register("opsgenie", func(settings Settings, cfg *config.Config) (Notifier, error) {
+		n := &OpsgenieNotifier{}
+		var err error
+		if n.APIKey, err = settings.String("api_key"); err != nil {
+			return nil, err
+		}
+		if n.APIURL, err = settings.String("api_url"); err != nil {
+			return nil, err
+		}
+		if n.PerResource, err = settings.Bool("per_resource"); err != nil {
+			return nil, err
+		}
+		if n.Tags, err = settings.StringSlice("tags"); err != nil {
+			return nil, err
+		}
+		n.APIKey = expandEnv(n.APIKey)
+		if n.APIKey == "" {
+			return nil, fmt.Errorf("opsgenie: api_key is required")
+		}
+		for path, ws := range cfg.WorkspaceSettings {
+			if len(ws.Tags) > 0 {
+				if n.WorkspaceTags == nil {
+					n.WorkspaceTags = make(map[string][]string)
+				}
+				n.WorkspaceTags[path] = ws.Tags
+			}
+		}
+		return n, nil
+	})
+}
+
+// opsgenieAlert is the body of an Opsgenie create-alert request.
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Source      string            `json:"source"`
+	Priority    string            `json:"priority"`
+}
+
+// opsgenieClose is the body of an Opsgenie close-alert request.
+type opsgenieClose struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Notify creates alerts for drifted workspaces. Without state it cannot
+// tell what to close; see NotifyWithState.
+func (n *OpsgenieNotifier) Notify(results []report.ScanResult) error {
+	return n.NotifyWithState(results, TargetState{})
+}
+
+// NotifyWithState creates alerts for drifted workspaces and closes the
+// alerts recorded in st whose drift is gone. Every request is attempted;
+// delivery errors are joined.
+func (n *OpsgenieNotifier) NotifyWithState(results []report.ScanResult, st TargetState) error {
+	api := strings.TrimSuffix(valueOr(n.APIURL, OpsgenieAPIURL), "/")
+	s := newSender(n.Retry)
+	return notifyIncidents(results, st, n.PerResource, func(inc incident) error {
+		if inc.Resolve {
+			closeURL := api + "/v2/alerts/" + url.PathEscape(inc.Key) + "/close?identifierType=alias"
+			return n.post(s, closeURL, opsgenieClose{Source: "driftwatch", Note: "Drift resolved: a later scan found it gone."})
+		}
+		return n.post(s, api+"/v2/alerts", n.alert(inc))
+	})
+}
+
+// alert builds the create-alert request for inc.
+func (n *OpsgenieNotifier) alert(inc incident) opsgenieAlert {
+	message := truncateText(incidentSummary(inc), opsgenieMessageMax)
+	var description strings.Builder
+	for _, rc := range inc.Resources {
+		fmt.Fprintf(&description, "%s %s\n", rc.Action, rc.Address)
+	}
+	priority, ok := opsgeniePriorities[worstAction(inc.Resources)]
+	if !ok {
+		priority = "P4"
+	}
+	tags := append([]string{"driftwatch"}, n.Tags...)
+	tags = append(tags, n.WorkspaceTags[inc.Workspace]...)
+
+	return opsgenieAlert{
+		Message:     message,
+		Alias:       inc.Key,
+		Description: description.String(),
+		Tags:        tags,
+		Details:     incidentDetails(inc),
+		Entity:      inc.Workspace,
+		Source:      "driftwatch",
+		Priority:    priority,
+	}
+}
+
+// post sends one JSON request to the Opsgenie API.
+func (n *OpsgenieNotifier) post(s *sender, endpoint string, body interface{}) error {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshaling Opsgenie request: %w", err)
+	}
+
+	header := http.Header{
+		"Content-Type":  {"application/json"},
+		"Authorization": {"GenieKey " + n.APIKey},
+	}
+	_, err = s.deliver("Opsgenie", http.MethodPost, endpoint, header, jsonData)
+	return err
+}
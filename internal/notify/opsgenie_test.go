@@ -0,0 +1,172 @@
+package notify_test
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/daemonship/driftwatch/internal/config"
+	"github.com/daemonship/driftwatch/internal/notify"
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+// opsgenieRequest is a request received by the test Opsgenie server.
+type opsgenieRequest struct {
+	Path  string // escaped path and query
+	Auth  string
+	Alert opsgenieAlert
+}
+
+// opsgenieAlert is the part of a create-alert request the tests check.
+type opsgenieAlert struct {
+	Message  string            `json:"message"`
+	Alias    string            `json:"alias"`
+	Tags     []string          `json:"tags"`
+	Details  map[string]string `json:"details"`
+	Entity   string            `json:"entity"`
+	Priority string            `json:"priority"`
+}
+
+func opsgenieServer(t *testing.T, status int) (*recordingServer, func() []opsgenieRequest) {
+	t.Helper()
+	srv := statusServer(t, status)
+	return srv, func() []opsgenieRequest {
+		received := srv.Requests()
+		alerts := decodeBodies[opsgenieAlert](t, received)
+		requests := make([]opsgenieRequest, len(received))
+		for i, r := range received {
+			requests[i] = opsgenieRequest{Path: r.Path, Auth: r.Header.Get("Authorization"), Alert: alerts[i]}
+		}
+		return requests
+	}
+}
+
+func TestOpsgenie_CreatesAlertWithPriorityAndTags(t *testing.T) {
+	srv, requests := opsgenieServer(t, http.StatusAccepted)
+	n := &notify.OpsgenieNotifier{
+		APIKey:        "k3y",
+		APIURL:        srv.URL,
+		Tags:          []string{"terraform"},
+		WorkspaceTags: map[string][]string{"./infra/prod": {"prod", "payments"}},
+	}
+
+	results := []report.ScanResult{
+		{WorkspacePath: "./infra/prod", ResourceChanges: []report.ResourceChange{
+			{Address: "aws_instance.web", Action: "update"},
+			{Address: "aws_iam_role.old", Action: "delete"},
+		}},
+		{WorkspacePath: "./infra/staging", ResourceChanges: []report.ResourceChange{
+			{Address: "aws_instance.web", Action: "update"},
+		}},
+	}
+	if err := n.Notify(results); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("sent %d requests, want one alert per workspace", len(got))
+	}
+	prod := got[0]
+	if prod.Path != "/v2/alerts" || prod.Auth != "GenieKey k3y" {
+		t.Errorf("request = %s with %q, want create-alert with GenieKey auth", prod.Path, prod.Auth)
+	}
+	if prod.Alert.Alias != "driftwatch:./infra/prod" || prod.Alert.Entity != "./infra/prod" {
+		t.Errorf("alert = %+v, want alias and entity for ./infra/prod", prod.Alert)
+	}
+	if prod.Alert.Priority != "P1" {
+		t.Errorf("prod priority = %q, want P1 for a delete", prod.Alert.Priority)
+	}
+	if want := []string{"driftwatch", "terraform", "prod", "payments"}; !slices.Equal(prod.Alert.Tags, want) {
+		t.Errorf("prod tags = %v, want %v", prod.Alert.Tags, want)
+	}
+	if prod.Alert.Details["aws_iam_role.old"] != "delete" {
+		t.Errorf("prod details = %v, want resource actions", prod.Alert.Details)
+	}
+	if staging := got[1].Alert; staging.Priority != "P3" || !slices.Equal(staging.Tags, []string{"driftwatch", "terraform"}) {
+		t.Errorf("staging alert = %+v, want P3 without workspace tags", staging)
+	}
+}
+
+func TestOpsgenie_ClosesAlertWhenDriftIsGone(t *testing.T) {
+	srv, requests := opsgenieServer(t, http.StatusAccepted)
+	n := &notify.OpsgenieNotifier{APIKey: "k3y", APIURL: srv.URL}
+
+	st := newTargetState()
+	if err := n.NotifyWithState(driftResults(), st); err != nil {
+		t.Fatalf("first NotifyWithState() error = %v", err)
+	}
+	opened := len(requests())
+	if err := n.NotifyWithState(noDriftResults(), st); err != nil {
+		t.Fatalf("NotifyWithState() error = %v", err)
+	}
+
+	got := requests()[opened:]
+	if len(got) != 1 {
+		t.Fatalf("sent %d requests, want one close: %+v", len(got), got)
+	}
+	if want := "/v2/alerts/driftwatch:.%2Finfra%2Fstaging/close?identifierType=alias"; got[0].Path != want {
+		t.Errorf("path = %q, want %q", got[0].Path, want)
+	}
+}
+
+func TestOpsgenie_TruncatesMessageOnRuneBoundary(t *testing.T) {
+	srv, requests := opsgenieServer(t, http.StatusAccepted)
+	n := &notify.OpsgenieNotifier{APIKey: "k3y", APIURL: srv.URL}
+
+	// The cut at 130 bytes falls inside a two-byte rune
+	workspace := "./infra/x" + strings.Repeat("é", 100)
+	results := []report.ScanResult{{WorkspacePath: workspace, ResourceChanges: []report.ResourceChange{
+		{Address: "aws_instance.web", Action: "update"},
+	}}}
+	if err := n.Notify(results); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("sent %d requests, want one alert", len(got))
+	}
+	message := got[0].Alert.Message
+	if len(message) > 130 || !utf8.ValidString(message) || !strings.HasSuffix(message, "…") {
+		t.Errorf("message %q is %d bytes: want at most 130 bytes of valid UTF-8 ending in …", message, len(message))
+	}
+}
+
+func TestOpsgenie_HTTPErrorReturnsError(t *testing.T) {
+	srv, _ := opsgenieServer(t, http.StatusUnauthorized)
+	n := &notify.OpsgenieNotifier{APIKey: "k3y", APIURL: srv.URL}
+	if err := n.Notify(driftResults()); err == nil {
+		t.Error("Notify() error = nil, want error on HTTP 401")
+	}
+}
+
+func TestNew_Opsgenie(t *testing.T) {
+	t.Setenv("OPSGENIE_API_KEY", "k3y")
+	cfg := &config.Config{WorkspaceSettings: map[string]config.Workspace{
+		"./infra/prod": {Path: "./infra/prod", Tags: []string{"prod"}},
+	}}
+	n, err := notify.New("opsgenie", notify.Settings{"api_key": "${OPSGENIE_API_KEY}", "tags": []interface{}{"terraform"}}, cfg)
+	if err != nil {
+		t.Fatalf("New(opsgenie) error = %v", err)
+	}
+	og, ok := n.(*notify.OpsgenieNotifier)
+	if !ok || og.APIKey != "k3y" || !slices.Equal(og.Tags, []string{"terraform"}) {
+		t.Fatalf("New(opsgenie) = %+v, want key from env and tags", n)
+	}
+	if !slices.Equal(og.WorkspaceTags["./infra/prod"], []string{"prod"}) {
+		t.Errorf("WorkspaceTags = %v, want tags from workspace config", og.WorkspaceTags)
+	}
+	n, err = notify.New("opsgenie", notify.Settings{"api_key": "k$y"}, nil)
+	if err != nil {
+		t.Fatalf("New(opsgenie) error = %v", err)
+	}
+	if og := n.(*notify.OpsgenieNotifier); og.APIKey != "k$y" {
+		t.Errorf("APIKey = %q, want a literal $ kept", og.APIKey)
+	}
+	if _, err := notify.New("opsgenie", notify.Settings{}, nil); err == nil {
+		t.Error("New(opsgenie) error = nil, want error without api_key")
+	}
+}
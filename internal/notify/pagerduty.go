@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/daemonship/driftwatch/internal/config"
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+// PagerDutyEventsURL is the PagerDuty Events API v2 endpoint.
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// pagerDutySummaryMax is the longest summary PagerDuty accepts.
+const pagerDutySummaryMax = 1024
+
+// pagerDutySeverities are the severities the Events API accepts.
+var pagerDutySeverities = map[string]bool{"critical": true, "error": true, "warning": true, "info": true}
+
+// PagerDutyNotifier opens PagerDuty incidents for drift through the Events
+// API v2 and resolves them once a later scan finds the drift gone. Each
+// workspace (or, with PerResource, each drifted resource) has a stable
+// dedup key, so repeated scans update one incident rather than opening more.
+type PagerDutyNotifier struct {
+	// RoutingKey is the integration key of the PagerDuty service.
+	RoutingKey string
+	// PerResource opens one incident per drifted resource instead of one
+	// per workspace.
+	PerResource bool
+	// Severity is critical, error, warning (default) or info.
+	Severity string
+	// Source names the affected system (default "driftwatch").
+	Source string
+	// EventsURL overrides PagerDutyEventsURL.
+	EventsURL string
+	// Retry controls retries of failed requests; see RetryPolicy.
+	Retry RetryPolicy
+}
+
+func init() {
+	Register("pagerduty", func(settings Settings, _ *config.Config) (Notifier, error) {
+		n := &PagerDutyNotifier{}
+		var err error
+		for key, dst := range map[string]*string{
+			"routing_key": &n.RoutingKey,
+			"severity":    &n.Severity,
+			"source":      &n.Source,
+			"events_url":  &n.EventsURL,
+		} {
+			if *dst, err = settings.String(key); err != nil {
+				return nil, err
+			}
+		}
+		if n.PerResource, err = settings.Bool("per_resource"); err != nil {
+			return nil, err
+		}
+		n.RoutingKey = expandEnv(n.RoutingKey)
+		if n.RoutingKey == "" {
+			return nil, fmt.Errorf("pagerduty: routing_key is required")
+		}
+		if n.Severity != "" && !pagerDutySeverities[n.Severity] {
+			return nil, fmt.Errorf("pagerduty: unknown severity %q (want critical, error, warning or info)", n.Severity)
+		}
+		return n, nil
+	})
+}
+
+// pagerDutyEvent is an Events API v2 event.
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Component     string            `json:"component,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// Notify triggers incidents for drifted workspaces. Without state it cannot
+// tell what to resolve; see NotifyWithState.
+func (n *PagerDutyNotifier) Notify(results []report.ScanResult) error {
+	return n.NotifyWithState(results, TargetState{})
+}
+
+// NotifyWithState triggers incidents for drifted workspaces and resolves
+// the incidents recorded in st whose drift is gone. Every event is
+// attempted; delivery errors are joined.
+func (n *PagerDutyNotifier) NotifyWithState(results []report.ScanResult, st TargetState) error {
+	s := newSender(n.Retry)
+	return notifyIncidents(results, st, n.PerResource, func(inc incident) error {
+		return n.send(s, n.event(inc))
+	})
+}
+
+// event builds the trigger or resolve event for inc.
+func (n *PagerDutyNotifier) event(inc incident) pagerDutyEvent {
+	e := pagerDutyEvent{RoutingKey: n.RoutingKey, EventAction: "resolve", DedupKey: inc.Key}
+	if inc.Resolve {
+		return e
+	}
+	summary := truncateText(incidentSummary(inc), pagerDutySummaryMax)
+	e.EventAction = "trigger"
+	e.Payload = &pagerDutyPayload{
+		Summary:       summary,
+		Source:        valueOr(n.Source, "driftwatch"),
+		Severity:      valueOr(n.Severity, "warning"),
+		Component:     inc.Workspace,
+		Class:         "terraform-drift",
+		CustomDetails: incidentDetails(inc),
+	}
+	return e
+}
+
+// send posts one event to the Events API.
+func (n *PagerDutyNotifier) send(s *sender, e pagerDutyEvent) error {
+	jsonData, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshaling PagerDuty event: %w", err)
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	_, err = s.deliver("PagerDuty", http.MethodPost, valueOr(n.EventsURL, PagerDutyEventsURL), header, jsonData)
+	return err
+}
+
+// valueOr returns s, or def if s is empty.
+func valueOr(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,267 @@
+package notify_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/daemonship/driftwatch/internal/notify"
+	"github.com/daemonship/driftwatch/internal/report"
+	"github.com/daemonship/driftwatch/internal/state"
+)
+
+// pagerDutyEvent is the part of an Events API v2 event the tests check.
+type pagerDutyEvent struct {
+	RoutingKey  string `json:"routing_key"`
+	EventAction string `json:"event_action"`
+	DedupKey    string `json:"dedup_key"`
+	Payload     *struct {
+		Summary       string            `json:"summary"`
+		Source        string            `json:"source"`
+		Severity      string            `json:"severity"`
+		Component     string            `json:"component"`
+		CustomDetails map[string]string `json:"custom_details"`
+	} `json:"payload"`
+}
+
+func pagerDutyServer(t *testing.T, status int) (*recordingServer, func() []pagerDutyEvent) {
+	t.Helper()
+	srv := statusServer(t, status)
+	return srv, func() []pagerDutyEvent {
+		return decodeBodies[pagerDutyEvent](t, srv.Requests())
+	}
+}
+
+// newTargetState returns empty state for a target named "oncall".
+func newTargetState() notify.TargetState {
+	return notify.TargetState{Snapshot: state.New(), Target: "oncall"}
+}
+
+func TestPagerDuty_TriggersPerWorkspace(t *testing.T) {
+	srv, events := pagerDutyServer(t, http.StatusAccepted)
+	n := &notify.PagerDutyNotifier{RoutingKey: "R0UT1NG", EventsURL: srv.URL}
+
+	results := []report.ScanResult{
+		{WorkspacePath: "./infra/prod", ResourceChanges: []report.ResourceChange{
+			{Address: "aws_instance.web", Action: "update"},
+			{Address: "aws_iam_role.old", Action: "delete"},
+		}},
+		{WorkspacePath: "./infra/clean"},
+	}
+	if err := n.NotifyWithState(results, newTargetState()); err != nil {
+		t.Fatalf("NotifyWithState() error = %v", err)
+	}
+
+	got := events()
+	if len(got) != 1 {
+		t.Fatalf("sent %d events, want 1 trigger: %+v", len(got), got)
+	}
+	e := got[0]
+	if e.RoutingKey != "R0UT1NG" || e.EventAction != "trigger" || e.DedupKey != "driftwatch:./infra/prod" {
+		t.Errorf("event = %+v, want trigger for ./infra/prod", e)
+	}
+	if e.Payload == nil || e.Payload.Severity != "warning" || e.Payload.Source != "driftwatch" || e.Payload.Component != "./infra/prod" {
+		t.Fatalf("payload = %+v, want defaults and workspace component", e.Payload)
+	}
+	if !strings.Contains(e.Payload.Summary, "2 resources") || e.Payload.CustomDetails["aws_iam_role.old"] != "delete" {
+		t.Errorf("payload = %+v, want summary and details of both resources", e.Payload)
+	}
+}
+
+func TestPagerDuty_TriggersPerResource(t *testing.T) {
+	srv, events := pagerDutyServer(t, http.StatusAccepted)
+	n := &notify.PagerDutyNotifier{RoutingKey: "R0UT1NG", EventsURL: srv.URL, PerResource: true, Severity: "critical"}
+
+	results := []report.ScanResult{{WorkspacePath: "./infra/prod", ResourceChanges: []report.ResourceChange{
+		{Address: "aws_instance.web", Action: "update"},
+		{Address: "aws_iam_role.old", Action: "delete"},
+	}}}
+	if err := n.Notify(results); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	keys := make(map[string]string)
+	for _, e := range events() {
+		keys[e.DedupKey] = e.EventAction
+	}
+	if len(keys) != 2 || keys["driftwatch:./infra/prod#aws_instance.web"] != "trigger" || keys["driftwatch:./infra/prod#aws_iam_role.old"] != "trigger" {
+		t.Errorf("events by dedup key = %v, want one trigger per resource", keys)
+	}
+}
+
+func TestPagerDuty_ResolvesWhenDriftIsGone(t *testing.T) {
+	srv, events := pagerDutyServer(t, http.StatusAccepted)
+	n := &notify.PagerDutyNotifier{RoutingKey: "R0UT1NG", EventsURL: srv.URL}
+
+	st := newTargetState()
+	if err := n.NotifyWithState([]report.ScanResult{
+		{WorkspacePath: "./infra/prod", ResourceChanges: []report.ResourceChange{{Address: "aws_instance.web", Action: "update"}}},
+		{WorkspacePath: "./infra/broken", ResourceChanges: []report.ResourceChange{{Address: "aws_instance.db", Action: "update"}}},
+	}, st); err != nil {
+		t.Fatalf("first NotifyWithState() error = %v", err)
+	}
+	opened := len(events())
+
+	results := []report.ScanResult{
+		{WorkspacePath: "./infra/prod"},
+		{WorkspacePath: "./infra/never-drifted"},
+		{WorkspacePath: "./infra/broken", Err: errors.New("plan failed")},
+	}
+	if err := n.NotifyWithState(results, st); err != nil {
+		t.Fatalf("NotifyWithState() error = %v", err)
+	}
+
+	got := events()[opened:]
+	if len(got) != 1 {
+		t.Fatalf("sent %d events, want only a resolve for ./infra/prod: %+v", len(got), got)
+	}
+	if got[0].EventAction != "resolve" || got[0].DedupKey != "driftwatch:./infra/prod" || got[0].Payload != nil {
+		t.Errorf("event = %+v, want resolve for ./infra/prod", got[0])
+	}
+}
+
+func TestPagerDuty_ResolvesFixedResources(t *testing.T) {
+	srv, events := pagerDutyServer(t, http.StatusAccepted)
+	n := &notify.PagerDutyNotifier{RoutingKey: "R0UT1NG", EventsURL: srv.URL, PerResource: true}
+
+	st := newTargetState()
+	if err := n.NotifyWithState([]report.ScanResult{{WorkspacePath: "./infra/prod", ResourceChanges: []report.ResourceChange{
+		{Address: "aws_instance.web", Action: "update"},
+		{Address: "aws_iam_role.old", Action: "delete"},
+	}}}, st); err != nil {
+		t.Fatalf("first NotifyWithState() error = %v", err)
+	}
+	opened := len(events())
+
+	results := []report.ScanResult{{WorkspacePath: "./infra/prod", ResourceChanges: []report.ResourceChange{
+		{Address: "aws_instance.web", Action: "update"},
+	}}}
+	if err := n.NotifyWithState(results, st); err != nil {
+		t.Fatalf("NotifyWithState() error = %v", err)
+	}
+
+	keys := make(map[string]string)
+	for _, e := range events()[opened:] {
+		keys[e.DedupKey] = e.EventAction
+	}
+	if keys["driftwatch:./infra/prod#aws_instance.web"] != "trigger" || keys["driftwatch:./infra/prod#aws_iam_role.old"] != "resolve" {
+		t.Errorf("events by dedup key = %v, want web re-triggered and old resolved", keys)
+	}
+}
+
+func TestPagerDuty_RetriesFailedResolveNextRun(t *testing.T) {
+	srv, events := pagerDutyServer(t, http.StatusAccepted)
+	down, _ := pagerDutyServer(t, http.StatusServiceUnavailable)
+	n := &notify.PagerDutyNotifier{RoutingKey: "R0UT1NG", EventsURL: srv.URL, Retry: fastRetry}
+	st := newTargetState()
+
+	if err := n.NotifyWithState(driftResults(), st); err != nil {
+		t.Fatalf("first NotifyWithState() error = %v", err)
+	}
+	n.EventsURL = down.URL
+	if err := n.NotifyWithState(noDriftResults(), st); err == nil {
+		t.Fatal("resolve while PagerDuty is down: error = nil, want error")
+	}
+	n.EventsURL = srv.URL
+	if err := n.NotifyWithState(noDriftResults(), st); err != nil {
+		t.Fatalf("third NotifyWithState() error = %v", err)
+	}
+	if err := n.NotifyWithState(noDriftResults(), st); err != nil {
+		t.Fatalf("fourth NotifyWithState() error = %v", err)
+	}
+
+	got := events()
+	if len(got) != 2 || got[1].EventAction != "resolve" || got[1].DedupKey != "driftwatch:./infra/staging" {
+		t.Errorf("events = %+v, want the trigger and one resolve once PagerDuty is back", got)
+	}
+}
+
+func TestPagerDuty_FilteredTargetResolvesOnlyWhatItOpened(t *testing.T) {
+	srv, events := pagerDutyServer(t, http.StatusAccepted)
+	deletes, err := report.ParseFilter("action=delete")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &notify.Dispatcher{
+		Targets: []notify.Target{
+			{Name: "deletes", Notifier: &notify.PagerDutyNotifier{RoutingKey: "DEL", EventsURL: srv.URL}, Filters: []report.Filter{deletes}},
+			{Name: "all", Notifier: &notify.PagerDutyNotifier{RoutingKey: "ALL", EventsURL: srv.URL}},
+		},
+		State: state.New(),
+	}
+
+	// Only updates drift: the deletes target has nothing to open or resolve
+	for run := 1; run <= 2; run++ {
+		for _, dl := range d.Dispatch(driftResults()) {
+			if dl.Err != nil {
+				t.Fatalf("run %d: %s: %v", run, dl.Target, dl.Err)
+			}
+		}
+	}
+	for _, e := range events() {
+		if e.RoutingKey == "DEL" || e.EventAction != "trigger" {
+			t.Errorf("event %+v, want only triggers from the unfiltered target", e)
+		}
+	}
+}
+
+func TestPagerDuty_TruncatesSummaryOnRuneBoundary(t *testing.T) {
+	srv, events := pagerDutyServer(t, http.StatusAccepted)
+	n := &notify.PagerDutyNotifier{RoutingKey: "R0UT1NG", EventsURL: srv.URL}
+
+	workspace := "./infra/" + strings.Repeat("é", 600)
+	results := []report.ScanResult{{WorkspacePath: workspace, ResourceChanges: []report.ResourceChange{
+		{Address: "aws_instance.web", Action: "update"},
+	}}}
+	if err := n.Notify(results); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	got := events()
+	if len(got) != 1 || got[0].Payload == nil {
+		t.Fatalf("events = %+v, want one trigger", got)
+	}
+	summary := got[0].Payload.Summary
+	if len(summary) > 1024 || !utf8.ValidString(summary) || !strings.HasSuffix(summary, "…") {
+		t.Errorf("summary is %d bytes, valid UTF-8 %v: want at most 1024 bytes of valid UTF-8 ending in …", len(summary), utf8.ValidString(summary))
+	}
+}
+
+func TestPagerDuty_HTTPErrorReturnsError(t *testing.T) {
+	srv, _ := pagerDutyServer(t, http.StatusBadRequest)
+	n := &notify.PagerDutyNotifier{RoutingKey: "R0UT1NG", EventsURL: srv.URL}
+	if err := n.Notify(driftResults()); err == nil {
+		t.Error("Notify() error = nil, want error on HTTP 400")
+	}
+}
+
+func TestNew_PagerDuty(t *testing.T) {
+	t.Setenv("PD_ROUTING_KEY", "R0UT1NG")
+	n, err := notify.New("pagerduty", notify.Settings{"routing_key": "${PD_ROUTING_KEY}", "per_resource": true}, nil)
+	if err != nil {
+		t.Fatalf("New(pagerduty) error = %v", err)
+	}
+	pd, ok := n.(*notify.PagerDutyNotifier)
+	if !ok || pd.RoutingKey != "R0UT1NG" || !pd.PerResource {
+		t.Errorf("New(pagerduty) = %+v, want routing key from env and per_resource", n)
+	}
+	n, err = notify.New("pagerduty", notify.Settings{"routing_key": "R0UT$NG"}, nil)
+	if err != nil {
+		t.Fatalf("New(pagerduty) error = %v", err)
+	}
+	if pd := n.(*notify.PagerDutyNotifier); pd.RoutingKey != "R0UT$NG" {
+		t.Errorf("RoutingKey = %q, want a literal $ kept", pd.RoutingKey)
+	}
+
+	for name, settings := range map[string]notify.Settings{
+		"missing routing_key": {},
+		"bad severity":        {"routing_key": "x", "severity": "urgent"},
+		"bad per_resource":    {"routing_key": "x", "per_resource": "yes"},
+	} {
+		if _, err := notify.New("pagerduty", settings, nil); err == nil {
+			t.Errorf("%s: New(pagerduty) error = nil, want error", name)
+		}
+	}
+}
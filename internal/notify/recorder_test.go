@@ -0,0 +1,79 @@
+package notify_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// recordedRequest is a request received by a recordingServer.
+type recordedRequest struct {
+	Method string
+	// Path is the escaped path and, if any, the query.
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// recordingServer is a test HTTP server that records every request it
+// receives. Notifiers deliver concurrently, so access is synchronized.
+type recordingServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []recordedRequest
+}
+
+// newRecordingServer starts a recordingServer that answers each request
+// with respond, given the request and how many were received so far,
+// including it.
+func newRecordingServer(t *testing.T, respond func(w http.ResponseWriter, req recordedRequest, n int)) *recordingServer {
+	t.Helper()
+	s := &recordingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := recordedRequest{Method: r.Method, Path: r.URL.EscapedPath(), Header: r.Header.Clone()}
+		if r.URL.RawQuery != "" {
+			req.Path += "?" + r.URL.RawQuery
+		}
+		req.Body, _ = io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		s.requests = append(s.requests, req)
+		n := len(s.requests)
+		s.mu.Unlock()
+		respond(w, req, n)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// statusServer starts a recordingServer that answers every request with
+// status.
+func statusServer(t *testing.T, status int) *recordingServer {
+	t.Helper()
+	return newRecordingServer(t, func(w http.ResponseWriter, _ recordedRequest, _ int) {
+		w.WriteHeader(status)
+	})
+}
+
+// Requests returns the requests received so far.
+func (s *recordingServer) Requests() []recordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]recordedRequest(nil), s.requests...)
+}
+
+// decodeBodies decodes the JSON body of each request into a T.
+func decodeBodies[T any](t *testing.T, requests []recordedRequest) []T {
+	t.Helper()
+	out := make([]T, len(requests))
+	for i, req := range requests {
+		if err := json.Unmarshal(req.Body, &out[i]); err != nil {
+			t.Errorf("request is not valid JSON: %v\n%s", err, req.Body)
+		}
+	}
+	return out
+}
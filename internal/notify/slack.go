@@ -2,14 +2,13 @@
 package notify
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
-	"time"
+	"strings"
 
+	"github.com/daemonship/driftwatch/internal/config"
 	"github.com/daemonship/driftwatch/internal/report"
 )
 
@@ -17,131 +16,343 @@ import (
 type SlackNotifier struct {
 	// WebhookURL is the Slack incoming webhook URL.
 	WebhookURL string
-	// ErrOut is where HTTP errors are written (default: os.Stderr).
-	ErrOut io.Writer
+	// RunURL links the message to a CI run; it defaults to the run
+	// detected from the CI environment.
+	RunURL string
+	// Retry controls retries of failed requests; see RetryPolicy.
+	Retry RetryPolicy
 }
 
-// slackMessage represents the JSON payload sent to a Slack webhook.
+func init() {
+	Register("slack", func(settings Settings, _ *config.Config) (Notifier, error) {
+		if _, ok := settings["token"]; ok {
+			return newSlackBotNotifier(settings)
+		}
+		url, err := settings.String("webhook_url")
+		if err != nil {
+			return nil, err
+		}
+		url = expandEnv(url)
+		if url == "" {
+			url = WebhookFromEnv()
+		}
+		if url == "" {
+			return nil, fmt.Errorf("slack: webhook_url is required (or set DRIFTWATCH_SLACK_WEBHOOK)")
+		}
+		runURL, err := settings.String("run_url")
+		if err != nil {
+			return nil, err
+		}
+		return &SlackNotifier{WebhookURL: url, RunURL: expandEnv(runURL)}, nil
+	})
+}
+
+// Slack Block Kit limits the message builder stays within.
+const (
+	// slackMaxBlocks is the most blocks Slack accepts in one message,
+	// counting those in attachments.
+	slackMaxBlocks = 50
+	// slackMaxSectionText is the longest text of a section block.
+	slackMaxSectionText = 3000
+	// slackMaxHeaderText is the longest text of a header block.
+	slackMaxHeaderText = 150
+	// slackMaxContextElements is the most elements of a context block.
+	slackMaxContextElements = 10
+	// slackMaxMessageText caps the text across all blocks, well below the
+	// size at which Slack rejects a post.
+	slackMaxMessageText = 30000
+	// slackMaxResources is the number of resources listed per workspace
+	// before the rest are summarized.
+	slackMaxResources = 20
+)
+
+// slackActionEmoji marks each resource action in Slack messages.
+var slackActionEmoji = map[string]string{
+	"create":  ":heavy_plus_sign:",
+	"update":  ":pencil2:",
+	"delete":  ":x:",
+	"replace": ":arrows_counterclockwise:",
+	"read":    ":mag:",
+}
+
+// slackEscaper escapes the characters Slack treats as markup in text.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slackMessage represents the JSON payload sent to Slack. Summary blocks go
+// at the top level; workspace details go in an attachment so they keep the
+// severity color bar.
 type slackMessage struct {
+	// Channel, TS and ThreadTS address the message in the Web API; incoming
+	// webhooks leave them empty.
+	Channel  string `json:"channel,omitempty"`
+	TS       string `json:"ts,omitempty"`
+	ThreadTS string `json:"thread_ts,omitempty"`
+	// Text is the fallback shown in notifications.
 	Text        string            `json:"text"`
+	Blocks      []slackBlock      `json:"blocks,omitempty"`
 	Attachments []slackAttachment `json:"attachments,omitempty"`
 }
 
-// slackAttachment represents a Slack message attachment.
+// slackAttachment is a colored attachment holding Block Kit blocks.
 type slackAttachment struct {
-	Color     string   `json:"color"`
-	Title     string   `json:"title"`
-	Text      string   `json:"text"`
-	MrkdwnIn  []string `json:"mrkdwn_in,omitempty"`
-	Timestamp int64    `json:"ts"`
+	Color  string       `json:"color"`
+	Blocks []slackBlock `json:"blocks"`
 }
 
-// Notify posts a drift summary to the Slack webhook if drift was detected.
-// Silent (no POST) when results contain no drift.
-// HTTP errors are written to ErrOut but do not return an error.
-func (n *SlackNotifier) Notify(results []report.ScanResult) error {
-	// Check if there's any drift to report
-	summary := report.Summarize(results)
-	if summary.WorkspacesWithDrift == 0 {
-		// Silent on clean scans
-		return nil
-	}
+// slackBlock is the subset of Block Kit used by the notifier: header,
+// section and context blocks.
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
 
-	// Default ErrOut to os.Stderr if not set
-	errOut := n.ErrOut
-	if errOut == nil {
-		errOut = os.Stderr
-	}
+// slackText is a Block Kit text object.
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
 
-	// Build the Slack message
-	msg := n.buildSlackMessage(results, summary)
+func mrkdwn(format string, args ...interface{}) slackText {
+	return slackText{Type: "mrkdwn", Text: fmt.Sprintf(format, args...)}
+}
 
-	// Marshal to JSON
-	jsonData, err := json.Marshal(msg)
-	if err != nil {
-		fmt.Fprintf(errOut, "Error marshaling Slack message: %v\n", err)
-		return nil // Don't return error, just log
+func slackContext(format string, args ...interface{}) slackBlock {
+	return slackBlock{Type: "context", Elements: []slackText{mrkdwn(format, args...)}}
+}
+
+// textLen is the length of the text in blocks.
+func textLen(blocks []slackBlock) int {
+	n := 0
+	for _, b := range blocks {
+		if b.Text != nil {
+			n += len(b.Text.Text)
+		}
+		for _, t := range b.Fields {
+			n += len(t.Text)
+		}
+		for _, t := range b.Elements {
+			n += len(t.Text)
+		}
 	}
+	return n
+}
 
-	// Create HTTP request
-	req, err := http.NewRequest("POST", n.WebhookURL, bytes.NewReader(jsonData))
-	if err != nil {
-		fmt.Fprintf(errOut, "Error creating Slack request: %v\n", err)
+// Notify posts a drift summary to the Slack webhook if drift or failing
+// health checks were detected. Silent (no POST) otherwise. Rate limits and
+// server errors are retried; a failed delivery is returned as a
+// *DeliveryError.
+func (n *SlackNotifier) Notify(results []report.ScanResult) error {
+	d := buildDigest(results)
+	if !d.notable() {
 		return nil
 	}
-	req.Header.Set("Content-Type", "application/json")
+	return n.send(d)
+}
+
+// NotifyEvent posts a summary because a trigger fired: drift as in Notify,
+// and otherwise the scan errors, the recovered workspaces or an all-clear.
+func (n *SlackNotifier) NotifyEvent(results []report.ScanResult, ev Event) error {
+	return n.send(buildEventDigest(results, ev))
+}
 
-	// Send the request
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+// send posts the message for d to the webhook.
+func (n *SlackNotifier) send(d digest) error {
+	jsonData, err := json.Marshal(buildSlackMessage(d, n.RunURL))
 	if err != nil {
-		fmt.Fprintf(errOut, "Error sending Slack notification: %v\n", err)
-		return nil
+		return fmt.Errorf("marshaling Slack message: %w", err)
 	}
-	defer resp.Body.Close()
+	header := http.Header{"Content-Type": {"application/json"}}
+	_, err = newSender(n.Retry).deliver("Slack webhook", http.MethodPost, n.WebhookURL, header, jsonData)
+	return err
+}
 
-	// Check for HTTP errors (but don't fail the scan)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		fmt.Fprintf(errOut, "Slack webhook returned HTTP %d\n", resp.StatusCode)
+// buildSlackMessage renders a scan digest as Block Kit: a header, summary
+// fields, the breakdown and CI run link, then each drifted workspace with its
+// resources, any failing health checks, scan errors and recovered
+// workspaces. Long resource lists and workspaces beyond Slack's block and
+// text limits are summarized. runURL overrides the CI run link of d.
+func buildSlackMessage(d digest, runURL string) slackMessage {
+	top := slackSummaryBlocks(d, valueOr(runURL, d.RunURL))
+	// Health checks, errors and recoveries come last but are budgeted
+	// first, so drift in many workspaces cannot crowd them out.
+	checks := append(slackCheckBlocks(d), slackErrorBlocks(d)...)
+	checks = append(checks, slackRecoveredBlocks(d)...)
+
+	// Reserve a block for the note about workspaces that do not fit
+	blocksLeft := slackMaxBlocks - len(top) - len(checks) - 1
+	textLeft := slackMaxMessageText - textLen(top) - textLen(checks)
+	var details []slackBlock
+	for i, ws := range d.Workspaces {
+		blocks := slackWorkspaceBlocks(ws)
+		if len(blocks) > blocksLeft || textLen(blocks) > textLeft {
+			details = append(details, slackContext("…and %d more workspace(s) with drift", len(d.Workspaces)-i))
+			break
+		}
+		details = append(details, blocks...)
+		blocksLeft -= len(blocks)
+		textLeft -= textLen(blocks)
 	}
+	details = append(details, checks...)
 
-	return nil
+	return slackMessage{
+		Text:        d.Title,
+		Blocks:      top,
+		Attachments: []slackAttachment{{Color: slackColor(d.Summary), Blocks: details}},
+	}
 }
 
-// buildSlackMessage constructs a Slack message from scan results.
-func (n *SlackNotifier) buildSlackMessage(results []report.ScanResult, summary report.Summary) slackMessage {
-	// Build a concise summary message
-	var buf bytes.Buffer
-
-	buf.WriteString(fmt.Sprintf("*Drift Detected in %d Workspace(s)*\n\n", summary.WorkspacesWithDrift))
-	buf.WriteString(fmt.Sprintf("Total drifted resources: %d\n", summary.TotalDriftedResources))
+// slackSummaryBlocks renders the top of a message: the title header, summary
+// fields, breakdown, a link to runURL if set and, when drift is spread across
+// several workspaces, the most drifted ones.
+func slackSummaryBlocks(d digest, runURL string) []slackBlock {
+	summary := d.Summary
 
-	// List affected workspaces
-	var affectedWorkspaces []string
-	for _, r := range results {
-		if len(r.ResourceChanges) > 0 {
-			affectedWorkspaces = append(affectedWorkspaces, r.WorkspacePath)
+	fields := []slackText{
+		mrkdwn("*Workspaces with drift:*\n%d of %d", summary.WorkspacesWithDrift, summary.WorkspacesScanned),
+		mrkdwn("*Total drifted resources:*\n%d", summary.TotalDriftedResources),
+	}
+	if summary.ScanErrors > 0 {
+		fields = append(fields, mrkdwn("*Scan errors:*\n%d", summary.ScanErrors))
+	}
+	if summary.FailedChecks > 0 {
+		fields = append(fields, mrkdwn("*Health check failures:*\n%d", summary.FailedChecks))
+	}
+	top := []slackBlock{
+		{Type: "header", Text: &slackText{Type: "plain_text", Text: truncateText(d.Title, slackMaxHeaderText)}},
+		{Type: "section", Fields: fields},
+	}
+	if len(d.Breakdown) > 0 {
+		context := slackBlock{Type: "context"}
+		for _, line := range d.Breakdown {
+			if len(context.Elements) == slackMaxContextElements {
+				break
+			}
+			context.Elements = append(context.Elements, mrkdwn("%s", slackEscaper.Replace(line)))
 		}
+		top = append(top, context)
 	}
-
-	if len(affectedWorkspaces) > 0 {
-		buf.WriteString("\n*Affected Workspaces:*\n")
-		for _, ws := range affectedWorkspaces {
-			buf.WriteString(fmt.Sprintf("• %s\n", ws))
+	if runURL != "" {
+		top = append(top, slackContext(":link: <%s|View CI run>", runURL))
+	}
+	// Rank workspaces when drift is spread across several
+	if len(summary.MostDrifted) > 1 {
+		var b strings.Builder
+		b.WriteString("*Most Drifted:*")
+		for _, s := range summary.MostDrifted {
+			fmt.Fprintf(&b, "\n• %s: %d resource(s)", slackEscaper.Replace(s.WorkspacePath), s.DriftedResources)
 		}
+		top = append(top, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: b.String()}})
 	}
+	return top
+}
 
-	// Add a summary of changes by workspace
-	buf.WriteString("\n*Changes Summary:*\n")
-	for _, r := range results {
-		if len(r.ResourceChanges) == 0 {
-			continue
-		}
+// slackCheckBlocks renders failing health checks as one section, or nothing
+// if all checks pass.
+func slackCheckBlocks(d digest) []slackBlock {
+	if len(d.CheckFailures) == 0 {
+		return nil
+	}
+	lines := make([]string, len(d.CheckFailures))
+	for i, c := range d.CheckFailures {
+		lines[i] = fmt.Sprintf("• %s: `%s` (%s)", slackEscaper.Replace(c.Workspace), slackEscaper.Replace(c.Address), c.Status)
+	}
+	text, more := packLines("*Health Check Failures:*", lines, len(lines))
+	if more > 0 {
+		text += fmt.Sprintf("\n…and %d more", more)
+	}
+	return []slackBlock{{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}}}
+}
 
-		buf.WriteString(fmt.Sprintf("\n%s:\n", r.WorkspacePath))
-		for _, rc := range r.ResourceChanges {
-			buf.WriteString(fmt.Sprintf("  • `%s` (%s)\n", rc.Address, rc.Action))
+// slackErrorBlocks lists the workspaces that could not be scanned with the
+// first line of each error, or nothing if every scan succeeded.
+func slackErrorBlocks(d digest) []slackBlock {
+	if len(d.Errors) == 0 {
+		return nil
+	}
+	lines := make([]string, len(d.Errors))
+	for i, e := range d.Errors {
+		lines[i] = fmt.Sprintf("• %s: `%s`", slackEscaper.Replace(e.Workspace), slackEscaper.Replace(e.Message))
+	}
+	text, more := packLines("*Scan Errors:*", lines, len(lines))
+	if more > 0 {
+		text += fmt.Sprintf("\n…and %d more", more)
+	}
+	return []slackBlock{{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}}}
+}
+
+// slackRecoveredBlocks lists the workspaces that are clean again, or nothing
+// if there are none.
+func slackRecoveredBlocks(d digest) []slackBlock {
+	if len(d.Recovered) == 0 {
+		return nil
+	}
+	lines := make([]string, len(d.Recovered))
+	for i, ws := range d.Recovered {
+		lines[i] = fmt.Sprintf(":white_check_mark: %s", slackEscaper.Replace(ws))
+	}
+	text, more := packLines("*Recovered:*", lines, len(lines))
+	if more > 0 {
+		text += fmt.Sprintf("\n…and %d more", more)
+	}
+	return []slackBlock{{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}}}
+}
+
+// slackColor is the attachment color for a scan's severity.
+func slackColor(summary report.Summary) string {
+	switch {
+	case summary.WorkspacesWithDrift > 1:
+		return "danger" // red for multiple workspaces
+	case summary.WorkspacesWithDrift > 0 || summary.FailedChecks > 0:
+		return "warning" // yellow/orange for drift
+	case summary.ScanErrors > 0:
+		return "danger"
+	default:
+		return "good" // green for recoveries and all-clears
+	}
+}
+
+// slackWorkspaceBlocks renders one drifted workspace: a section listing its
+// resources with action emoji, and a context with its action counts and how
+// many resources were left out.
+func slackWorkspaceBlocks(ws workspaceDigest) []slackBlock {
+	lines := make([]string, len(ws.Resources))
+	actions := make(map[string]int)
+	for i, rc := range ws.Resources {
+		emoji, ok := slackActionEmoji[rc.Action]
+		if !ok {
+			emoji = ":grey_question:"
 		}
+		lines[i] = fmt.Sprintf("%s `%s` %s", emoji, slackEscaper.Replace(rc.Address), rc.Action)
+		actions[rc.Action]++
 	}
+	text, more := packLines("*"+slackEscaper.Replace(ws.Path)+"*", lines, slackMaxResources)
 
-	// Create attachment with color based on severity
-	color := "warning" // yellow/orange for drift
-	if summary.WorkspacesWithDrift > 1 {
-		color = "danger" // red for multiple workspaces
+	context := slackContext("%d resource(s): %s", len(ws.Resources), report.FormatCounts(report.SortedCounts(actions)))
+	if more > 0 {
+		context.Elements = append(context.Elements, mrkdwn("…and %d more resources", more))
 	}
+	return []slackBlock{
+		{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}},
+		context,
+	}
+}
 
-	return slackMessage{
-		Text: "🚨 Terraform Drift Detected",
-		Attachments: []slackAttachment{
-			{
-				Color:     color,
-				Title:     "Drift Summary",
-				Text:      buf.String(),
-				MrkdwnIn:  []string{"text"},
-				Timestamp: time.Now().Unix(),
-			},
-		},
+// packLines joins heading and up to max lines, one per line, stopping early
+// to keep room for a "…and N more" note within a section's text limit. It
+// returns the text and the number of lines left out.
+func packLines(heading string, lines []string, max int) (string, int) {
+	const noteRoom = 40
+	var b strings.Builder
+	b.WriteString(heading)
+	for i, line := range lines {
+		if i == max || b.Len()+1+len(line) > slackMaxSectionText-noteRoom {
+			return b.String(), len(lines) - i
+		}
+		b.WriteString("\n" + line)
 	}
+	return b.String(), 0
 }
 
 // WebhookFromEnv returns the Slack webhook URL from the DRIFTWATCH_SLACK_WEBHOOK
@@ -0,0 +1,361 @@
+package notify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+// SlackAPIURL is the base URL of the Slack Web API.
+const SlackAPIURL = "https://slack.com/api"
+
+// slackUpdateWindow is how long a summary is updated while its drift is
+// unchanged. After that, unchanged drift is posted again so it does not stay
+// buried in the channel history.
+const slackUpdateWindow = 24 * time.Hour
+
+// SlackBotNotifier posts drift to Slack with a bot token through the Web
+// API. Each channel gets a summary message with the details of every drifted
+// workspace as thread replies. When a channel's drift is unchanged since a
+// post less than a day old, that message is updated instead of posting again.
+type SlackBotNotifier struct {
+	// Token is the bot token (xoxb-…), which needs the chat:write scope.
+	Token string
+	// Channel receives workspaces no route matches.
+	Channel string
+	// Routes send matching workspaces to other channels; the first match wins.
+	Routes []SlackRoute
+	// RunURL links messages to a CI run; it defaults to the run detected
+	// from the CI environment.
+	RunURL string
+	// APIURL overrides SlackAPIURL.
+	APIURL string
+	// Retry controls retries of failed requests; see RetryPolicy.
+	Retry RetryPolicy
+}
+
+// SlackRoute sends the workspaces matching a filter to a channel.
+type SlackRoute struct {
+	// Workspace is a workspace filter, as given to --filter workspace=.
+	Workspace report.Filter
+	Channel   string
+}
+
+// slackThread is what the notifier remembers about the last summary it
+// posted to a channel.
+type slackThread struct {
+	// Channel is the channel ID Slack returned, needed by chat.update.
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+	// Fingerprint identifies the drift the summary reported.
+	Fingerprint string    `json:"fingerprint"`
+	PostedAt    time.Time `json:"posted_at"`
+}
+
+// slackAPIResponse is the common part of Slack Web API responses.
+type slackAPIResponse struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error"`
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+}
+
+// newSlackBotNotifier builds a SlackBotNotifier from settings: token,
+// channel, routes (a list of workspace/channel pairs) and run_url. ${VAR}
+// references are expanded in token and run_url.
+func newSlackBotNotifier(settings Settings) (Notifier, error) {
+	n := &SlackBotNotifier{}
+	var err error
+	for key, dst := range map[string]*string{
+		"token":   &n.Token,
+		"channel": &n.Channel,
+		"run_url": &n.RunURL,
+		"api_url": &n.APIURL,
+	} {
+		if *dst, err = settings.String(key); err != nil {
+			return nil, err
+		}
+	}
+	n.Token, n.RunURL = expandEnv(n.Token), expandEnv(n.RunURL)
+	if n.Token == "" {
+		return nil, fmt.Errorf("slack: token is empty")
+	}
+	if n.Channel == "" {
+		return nil, fmt.Errorf("slack: channel is required with a token")
+	}
+
+	var routes []interface{}
+	switch v := settings["routes"].(type) {
+	case nil:
+	case []interface{}:
+		routes = v
+	default:
+		return nil, fmt.Errorf("setting %q: want a list, got %T", "routes", v)
+	}
+	for i, item := range routes {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("routes[%d]: want a mapping with workspace and channel", i)
+		}
+		route := Settings(m)
+		glob, err := route.String("workspace")
+		if err != nil {
+			return nil, fmt.Errorf("routes[%d]: %w", i, err)
+		}
+		var r SlackRoute
+		if r.Channel, err = route.String("channel"); err != nil {
+			return nil, fmt.Errorf("routes[%d]: %w", i, err)
+		}
+		if glob == "" || r.Channel == "" {
+			return nil, fmt.Errorf("routes[%d]: workspace and channel are required", i)
+		}
+		if r.Workspace, err = report.ParseFilter("workspace=" + glob); err != nil {
+			return nil, fmt.Errorf("routes[%d]: %w", i, err)
+		}
+		n.Routes = append(n.Routes, r)
+	}
+	return n, nil
+}
+
+// Notify posts to each channel without looking at earlier posts.
+func (n *SlackBotNotifier) Notify(results []report.ScanResult) error {
+	return n.NotifyWithState(results, TargetState{})
+}
+
+// NotifyWithState posts each channel's drift, or updates the message
+// recorded in st when the drift is unchanged, and records the messages it
+// posted in st for the next run. Channels without drift or failing health
+// checks get nothing. Errors for each channel are joined.
+func (n *SlackBotNotifier) NotifyWithState(results []report.ScanResult, st TargetState) error {
+	channels, byChannel := n.route(results)
+	s := newSender(n.Retry)
+	var errs []error
+	for _, channel := range channels {
+		if err := n.notifyChannel(s, channel, byChannel[channel], st); err != nil {
+			errs = append(errs, fmt.Errorf("channel %s: %w", channel, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyEvent posts because a trigger fired, without looking at earlier
+// posts.
+func (n *SlackBotNotifier) NotifyEvent(results []report.ScanResult, ev Event) error {
+	return n.NotifyEventWithState(results, ev, TargetState{})
+}
+
+// NotifyEventWithState posts because a trigger fired. Channels with drift or
+// failing health checks get a summary and thread as in NotifyWithState, so
+// unchanged drift updates a recent summary in st; other channels get a single
+// message if their workspaces failed to scan or recovered. When that posts
+// nothing, the default channel gets the event, e.g. an all-clear.
+func (n *SlackBotNotifier) NotifyEventWithState(results []report.ScanResult, ev Event, st TargetState) error {
+	channels, byChannel := n.route(results)
+	s := newSender(n.Retry)
+	var errs []error
+	posted := false
+	for _, channel := range channels {
+		chResults := byChannel[channel]
+		var err error
+		switch d := buildDigest(chResults); {
+		case d.notable():
+			err = n.notifyChannel(s, channel, chResults, st)
+		case len(d.Errors) > 0 || len(recoveredIn(chResults, ev)) > 0:
+			err = errors.Join(st.Save(slackThreadKey(channel), nil), n.postEvent(s, channel, chResults, ev))
+		default:
+			if err := st.Save(slackThreadKey(channel), nil); err != nil {
+				errs = append(errs, fmt.Errorf("channel %s: %w", channel, err))
+			}
+			continue
+		}
+		posted = true
+		if err != nil {
+			errs = append(errs, fmt.Errorf("channel %s: %w", channel, err))
+		}
+	}
+	if !posted {
+		if err := n.postEvent(s, n.Channel, results, ev); err != nil {
+			errs = append(errs, fmt.Errorf("channel %s: %w", n.Channel, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// postEvent posts one message saying what ev means for results: their
+// scan errors, recovered workspaces or an all-clear.
+func (n *SlackBotNotifier) postEvent(s *sender, channel string, results []report.ScanResult, ev Event) error {
+	ev.Recovered = recoveredIn(results, ev)
+	msg := buildSlackMessage(buildEventDigest(results, ev), n.RunURL)
+	msg.Channel = channel
+	_, err := n.call(s, "chat.postMessage", msg)
+	return err
+}
+
+// route splits results by channel. It returns every configured channel, the
+// default first, so channels whose drift is gone are visited too.
+func (n *SlackBotNotifier) route(results []report.ScanResult) ([]string, map[string][]report.ScanResult) {
+	channels := []string{n.Channel}
+	for _, r := range n.Routes {
+		if !slices.Contains(channels, r.Channel) {
+			channels = append(channels, r.Channel)
+		}
+	}
+
+	byChannel := make(map[string][]report.ScanResult)
+	for _, res := range results {
+		channel := n.Channel
+		for _, r := range n.Routes {
+			if len(report.FilterResults([]report.ScanResult{res}, []report.Filter{r.Workspace})) > 0 {
+				channel = r.Channel
+				break
+			}
+		}
+		byChannel[channel] = append(byChannel[channel], res)
+	}
+	return channels, byChannel
+}
+
+// slackThreadKey is the state key of the last summary posted to channel.
+func slackThreadKey(channel string) string {
+	return "slack:" + channel
+}
+
+// notifyChannel posts one channel's summary and thread, or updates the
+// previous summary if the drift has not changed and it was posted within
+// slackUpdateWindow.
+func (n *SlackBotNotifier) notifyChannel(s *sender, channel string, results []report.ScanResult, st TargetState) error {
+	key := slackThreadKey(channel)
+	d := buildDigest(results)
+	if !d.notable() {
+		// Forget the last post so drift that comes back starts a new thread
+		return st.Save(key, nil)
+	}
+
+	now := time.Now()
+	fingerprint := slackFingerprint(results)
+	summary := slackMessage{
+		Text:        d.Title,
+		Blocks:      slackSummaryBlocks(d, valueOr(n.RunURL, d.RunURL)),
+		Attachments: []slackAttachment{{Color: slackColor(d.Summary), Blocks: slackWorkspaceList(d)}},
+	}
+
+	var last slackThread
+	found, err := st.Load(key, &last)
+	if err != nil {
+		return err
+	}
+	if found && last.Fingerprint == fingerprint && now.Sub(last.PostedAt) < slackUpdateWindow {
+		summary.Channel, summary.TS = last.Channel, last.TS
+		summary.Blocks = append(summary.Blocks, slackContext("Unchanged since %s · last checked %s",
+			last.PostedAt.Format("Jan 2 15:04 MST"), now.Format("Jan 2 15:04 MST")))
+		if _, err := n.call(s, "chat.update", summary); err == nil {
+			return nil
+		}
+		// The message may have been deleted; post a new one instead
+		summary.Blocks = summary.Blocks[:len(summary.Blocks)-1]
+		summary.TS = ""
+	}
+
+	summary.Channel = channel
+	posted, err := n.call(s, "chat.postMessage", summary)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	var replies []slackMessage
+	for _, ws := range d.Workspaces {
+		replies = append(replies, slackMessage{Text: ws.Path, Blocks: slackWorkspaceBlocks(ws)})
+	}
+	if checks := slackCheckBlocks(d); len(checks) > 0 {
+		replies = append(replies, slackMessage{Text: "Health Check Failures", Blocks: checks})
+	}
+	for _, reply := range replies {
+		reply.Channel, reply.ThreadTS = posted.Channel, posted.TS
+		if _, err := n.call(s, "chat.postMessage", reply); err != nil {
+			errs = append(errs, fmt.Errorf("thread reply for %s: %w", reply.Text, err))
+		}
+	}
+
+	errs = append(errs, st.Save(key, slackThread{
+		Channel:     posted.Channel,
+		TS:          posted.TS,
+		Fingerprint: fingerprint,
+		PostedAt:    now,
+	}))
+	return errors.Join(errs...)
+}
+
+// slackWorkspaceList lists the drifted workspaces of a summary message,
+// pointing to the thread for details.
+func slackWorkspaceList(d digest) []slackBlock {
+	if len(d.Workspaces) == 0 {
+		return []slackBlock{slackContext("Details in the thread :thread:")}
+	}
+	lines := make([]string, len(d.Workspaces))
+	for i, ws := range d.Workspaces {
+		lines[i] = fmt.Sprintf("• %s: %d resource(s)", slackEscaper.Replace(ws.Path), len(ws.Resources))
+	}
+	text, more := packLines("*Affected Workspaces:*", lines, len(lines))
+	if more > 0 {
+		text += fmt.Sprintf("\n…and %d more", more)
+	}
+	return []slackBlock{
+		{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}},
+		slackContext("Details in the thread :thread:"),
+	}
+}
+
+// slackFingerprint identifies the drift and failing checks in results, so an
+// unchanged scan can be recognized.
+func slackFingerprint(results []report.ScanResult) string {
+	var lines []string
+	for _, r := range results {
+		for _, rc := range r.ResourceChanges {
+			lines = append(lines, "drift\x00"+r.WorkspacePath+"\x00"+rc.Address+"\x00"+rc.Action)
+		}
+		for _, c := range r.CheckFailures {
+			lines = append(lines, "check\x00"+r.WorkspacePath+"\x00"+c.Address+"\x00"+c.Status)
+		}
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// call invokes a Slack Web API method. Slack reports most failures with
+// "ok": false in an HTTP 200 response; both kinds become errors. Rate limits
+// come as HTTP 429 and are retried like server errors.
+func (n *SlackBotNotifier) call(s *sender, method string, msg slackMessage) (slackAPIResponse, error) {
+	var out slackAPIResponse
+	jsonData, err := json.Marshal(msg)
+	if err != nil {
+		return out, fmt.Errorf("marshaling Slack message: %w", err)
+	}
+
+	url := strings.TrimSuffix(valueOr(n.APIURL, SlackAPIURL), "/") + "/" + method
+	header := http.Header{
+		"Content-Type":  {"application/json; charset=utf-8"},
+		"Authorization": {"Bearer " + n.Token},
+	}
+	data, err := s.deliver("Slack "+method, http.MethodPost, url, header, jsonData)
+	if err != nil {
+		return out, err
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return out, fmt.Errorf("decoding Slack %s response: %w", method, err)
+	}
+	if !out.OK {
+		return out, fmt.Errorf("Slack %s failed: %s", method, out.Error)
+	}
+	return out, nil
+}
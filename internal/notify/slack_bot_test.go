@@ -0,0 +1,357 @@
+package notify_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daemonship/driftwatch/internal/notify"
+	"github.com/daemonship/driftwatch/internal/report"
+	"github.com/daemonship/driftwatch/internal/state"
+)
+
+// slackCall is a Web API call received by the fake Slack server.
+type slackCall struct {
+	Method   string
+	Auth     string
+	Channel  string `json:"channel"`
+	TS       string `json:"ts"`
+	ThreadTS string `json:"thread_ts"`
+	Text     string `json:"text"`
+}
+
+// slackAPI is a fake Slack Web API. Posted messages get increasing
+// timestamps; channel names are returned as IDs with a C prefix.
+type slackAPI struct {
+	*recordingServer
+	t    *testing.T
+	fail string // Slack error returned for every call, if set
+	seen int    // requests already returned by Calls
+}
+
+func newSlackAPI(t *testing.T) *slackAPI {
+	t.Helper()
+	api := &slackAPI{t: t}
+	api.recordingServer = newRecordingServer(t, func(w http.ResponseWriter, req recordedRequest, n int) {
+		call := slackCallOf(t, req)
+		if api.fail != "" {
+			fmt.Fprintf(w, `{"ok": false, "error": %q}`, api.fail)
+			return
+		}
+		channel := call.Channel
+		if !strings.HasPrefix(channel, "C") {
+			channel = "C" + strings.TrimPrefix(channel, "#")
+		}
+		ts := call.TS
+		if call.Method == "chat.postMessage" {
+			ts = fmt.Sprintf("1700000000.%06d", n)
+		}
+		fmt.Fprintf(w, `{"ok": true, "channel": %q, "ts": %q}`, channel, ts)
+	})
+	return api
+}
+
+// slackCallOf decodes a request received by the fake Slack server.
+func slackCallOf(t *testing.T, req recordedRequest) slackCall {
+	t.Helper()
+	call := decodeBodies[slackCall](t, []recordedRequest{req})[0]
+	call.Method = strings.TrimPrefix(req.Path, "/")
+	call.Auth = req.Header.Get("Authorization")
+	return call
+}
+
+// Calls returns the calls received since the last call to Calls.
+func (api *slackAPI) Calls() []slackCall {
+	received := api.Requests()
+	var calls []slackCall
+	for _, req := range received[api.seen:] {
+		calls = append(calls, slackCallOf(api.t, req))
+	}
+	api.seen = len(received)
+	return calls
+}
+
+func TestSlackBot_PostsSummaryWithThreadedDetails(t *testing.T) {
+	api := newSlackAPI(t)
+	n := &notify.SlackBotNotifier{Token: "xoxb-test", Channel: "#drift", APIURL: api.URL}
+
+	if err := n.Notify(multiWorkspaceDriftResults()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	calls := api.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("made %d calls, want a summary and two thread replies: %+v", len(calls), calls)
+	}
+	summary := calls[0]
+	if summary.Method != "chat.postMessage" || summary.Channel != "#drift" || summary.ThreadTS != "" {
+		t.Errorf("summary = %+v, want a top-level post to #drift", summary)
+	}
+	if summary.Auth != "Bearer xoxb-test" {
+		t.Errorf("Authorization = %q, want bot token", summary.Auth)
+	}
+	for i, want := range []string{"./infra/staging", "./infra/production"} {
+		reply := calls[i+1]
+		if reply.ThreadTS != "1700000000.000001" || reply.Channel != "Cdrift" || reply.Text != want {
+			t.Errorf("reply %d = %+v, want %s threaded under the summary", i, reply, want)
+		}
+	}
+}
+
+func TestSlackBot_RoutesWorkspacesToChannels(t *testing.T) {
+	api := newSlackAPI(t)
+	prod, err := report.ParseFilter("workspace=./infra/prod*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &notify.SlackBotNotifier{
+		Token:   "xoxb-test",
+		Channel: "#drift",
+		Routes:  []notify.SlackRoute{{Workspace: prod, Channel: "#prod-drift"}},
+		APIURL:  api.URL,
+	}
+
+	if err := n.Notify(multiWorkspaceDriftResults()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	replies := make(map[string]string) // workspace → channel
+	for _, c := range api.Calls() {
+		if c.ThreadTS != "" {
+			replies[c.Text] = c.Channel
+		}
+	}
+	if replies["./infra/staging"] != "Cdrift" || replies["./infra/production"] != "Cprod-drift" {
+		t.Errorf("thread replies by workspace = %v, want staging in #drift and production in #prod-drift", replies)
+	}
+}
+
+func TestSlackBot_UpdatesMessageWhenDriftIsUnchanged(t *testing.T) {
+	api := newSlackAPI(t)
+	n := &notify.SlackBotNotifier{Token: "xoxb-test", Channel: "#drift", APIURL: api.URL}
+	st := notify.TargetState{Snapshot: state.New(), Target: "drift"}
+
+	if err := n.NotifyWithState(driftResults(), st); err != nil {
+		t.Fatalf("first NotifyWithState() error = %v", err)
+	}
+	api.Calls()
+
+	// Same drift on the next run: update the summary, post nothing new
+	if err := n.NotifyWithState(driftResults(), st); err != nil {
+		t.Fatalf("second NotifyWithState() error = %v", err)
+	}
+	second := api.Calls()
+	if len(second) != 1 || second[0].Method != "chat.update" {
+		t.Fatalf("second run calls = %+v, want one chat.update", second)
+	}
+	if second[0].TS != "1700000000.000001" || second[0].Channel != "Cdrift" {
+		t.Errorf("chat.update = %+v, want the first summary in Cdrift", second[0])
+	}
+
+	// Different drift: a new summary and thread
+	changed := driftResults()
+	changed[0].ResourceChanges = append(changed[0].ResourceChanges, report.ResourceChange{Address: "aws_s3_bucket.logs", Action: "delete"})
+	if err := n.NotifyWithState(changed, st); err != nil {
+		t.Fatalf("third NotifyWithState() error = %v", err)
+	}
+	if third := api.Calls(); len(third) != 2 || third[0].Method != "chat.postMessage" || third[0].ThreadTS != "" {
+		t.Errorf("third run calls = %+v, want a new summary and thread reply", third)
+	}
+}
+
+func TestSlackBot_PostsAgainAfterADay(t *testing.T) {
+	api := newSlackAPI(t)
+	n := &notify.SlackBotNotifier{Token: "xoxb-test", Channel: "#drift", APIURL: api.URL}
+	st := notify.TargetState{Snapshot: state.New(), Target: "drift"}
+
+	if err := n.NotifyWithState(driftResults(), st); err != nil {
+		t.Fatalf("first NotifyWithState() error = %v", err)
+	}
+	api.Calls()
+
+	// Age the remembered summary past the update window
+	var thread map[string]interface{}
+	if found, err := st.Load("slack:#drift", &thread); !found || err != nil {
+		t.Fatalf("Load(slack:#drift) = %v, %v, want the posted summary", found, err)
+	}
+	thread["posted_at"] = time.Now().Add(-25 * time.Hour)
+	if err := st.Save("slack:#drift", thread); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := n.NotifyWithState(driftResults(), st); err != nil {
+		t.Fatalf("second NotifyWithState() error = %v", err)
+	}
+	if calls := api.Calls(); len(calls) != 2 || calls[0].Method != "chat.postMessage" || calls[0].ThreadTS != "" {
+		t.Errorf("calls = %+v, want a new summary and thread for drift unchanged for a day", calls)
+	}
+}
+
+func TestSlackBot_TriggeredTargetUpdatesUnchangedDrift(t *testing.T) {
+	api := newSlackAPI(t)
+	d := &notify.Dispatcher{
+		Targets: []notify.Target{{
+			Name:     "drift",
+			Notifier: &notify.SlackBotNotifier{Token: "xoxb-test", Channel: "#drift", APIURL: api.URL},
+			Triggers: []string{notify.TriggerOnDrift},
+		}},
+		State: state.New(),
+	}
+	d.Dispatch(driftResults())
+	api.Calls()
+
+	d.Dispatch(driftResults())
+	if calls := api.Calls(); len(calls) != 1 || calls[0].Method != "chat.update" {
+		t.Errorf("second run calls = %+v, want one chat.update", calls)
+	}
+}
+
+func TestSlackBot_TargetsSharingAChannelKeepTheirOwnMessages(t *testing.T) {
+	api := newSlackAPI(t)
+	d := &notify.Dispatcher{
+		Targets: []notify.Target{
+			{Name: "platform", Notifier: &notify.SlackBotNotifier{Token: "xoxb-platform", Channel: "#drift", APIURL: api.URL}},
+			{Name: "security", Notifier: &notify.SlackBotNotifier{Token: "xoxb-security", Channel: "#drift", APIURL: api.URL}},
+		},
+		State: state.New(),
+	}
+	d.Dispatch(driftResults())
+	posted := make(map[string]bool) // tokens that posted a summary
+	for _, c := range api.Calls() {
+		if c.Method == "chat.postMessage" && c.ThreadTS == "" {
+			posted[c.Auth] = true
+		}
+	}
+	if len(posted) != 2 {
+		t.Fatalf("first run summaries by token = %v, want one per target", posted)
+	}
+
+	// Each target updates the summary it posted, not the other's
+	d.Dispatch(driftResults())
+	calls := api.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("second run calls = %+v, want one chat.update per target", calls)
+	}
+	for _, c := range calls {
+		if c.Method != "chat.update" || c.TS == "" {
+			t.Errorf("call = %+v, want chat.update of an earlier summary", c)
+		}
+	}
+	if calls[0].TS == calls[1].TS {
+		t.Errorf("both targets updated message %s, want each its own", calls[0].TS)
+	}
+}
+
+func TestSlackBot_SilentAndForgetfulWhenClean(t *testing.T) {
+	api := newSlackAPI(t)
+	n := &notify.SlackBotNotifier{Token: "xoxb-test", Channel: "#drift", APIURL: api.URL}
+	st := notify.TargetState{Snapshot: state.New(), Target: "drift"}
+
+	if err := n.NotifyWithState(driftResults(), st); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.NotifyWithState(noDriftResults(), st); err != nil {
+		t.Fatal(err)
+	}
+	api.Calls()
+	if err := n.NotifyWithState(driftResults(), st); err != nil {
+		t.Fatal(err)
+	}
+	// Drift that comes back after a clean scan is news: post, don't update
+	if calls := api.Calls(); len(calls) == 0 || calls[0].Method != "chat.postMessage" {
+		t.Errorf("calls after drift returned = %+v, want a new post", calls)
+	}
+}
+
+func TestSlackBot_NotifyEvent(t *testing.T) {
+	api := newSlackAPI(t)
+	prod, err := report.ParseFilter("workspace=./infra/prod*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &notify.SlackBotNotifier{
+		Token:   "xoxb-test",
+		Channel: "#drift",
+		Routes:  []notify.SlackRoute{{Workspace: prod, Channel: "#prod-drift"}},
+		APIURL:  api.URL,
+	}
+	clean := []report.ScanResult{{WorkspacePath: "./infra/staging"}, {WorkspacePath: "./infra/production"}}
+	errored := []report.ScanResult{{WorkspacePath: "./infra/staging"}, {WorkspacePath: "./infra/production", Err: errors.New("no credentials")}}
+
+	for _, tc := range []struct {
+		name    string
+		results []report.ScanResult
+		ev      notify.Event
+		channel string
+		text    string
+	}{
+		{"recovery", clean, notify.Event{Triggers: []string{notify.TriggerOnRecovery}, Recovered: []string{"./infra/staging"}}, "#drift", "✅ Terraform Drift Resolved"},
+		{"heartbeat", clean, notify.Event{Triggers: []string{notify.TriggerAlways}}, "#drift", "✅ No Terraform Drift"},
+		{"scan error", errored, notify.Event{Triggers: []string{notify.TriggerOnError}}, "#prod-drift", "❌ Terraform Scan Errors"},
+	} {
+		if err := n.NotifyEvent(tc.results, tc.ev); err != nil {
+			t.Fatalf("%s: NotifyEvent() error = %v", tc.name, err)
+		}
+		calls := api.Calls()
+		if len(calls) != 1 || calls[0].Method != "chat.postMessage" || calls[0].Channel != tc.channel || calls[0].Text != tc.text {
+			t.Errorf("%s: calls = %+v, want %q posted to %s", tc.name, calls, tc.text, tc.channel)
+		}
+	}
+
+	// Drift still gets its summary and thread
+	if err := n.NotifyEvent(driftResults(), notify.Event{Triggers: []string{notify.TriggerOnDrift}}); err != nil {
+		t.Fatalf("drift: NotifyEvent() error = %v", err)
+	}
+	if calls := api.Calls(); len(calls) != 2 || calls[1].ThreadTS == "" {
+		t.Errorf("drift: calls = %+v, want a summary and a thread reply", calls)
+	}
+}
+
+func TestSlackBot_APIErrorReturnsError(t *testing.T) {
+	api := newSlackAPI(t)
+	api.fail = "channel_not_found"
+	n := &notify.SlackBotNotifier{Token: "xoxb-test", Channel: "#nope", APIURL: api.URL}
+
+	err := n.Notify(driftResults())
+	if err == nil || !strings.Contains(err.Error(), "channel_not_found") {
+		t.Errorf("Notify() error = %v, want the Slack error", err)
+	}
+}
+
+func TestNew_SlackBot(t *testing.T) {
+	t.Setenv("SLACK_BOT_TOKEN", "xoxb-env")
+	n, err := notify.New("slack", notify.Settings{
+		"token":   "${SLACK_BOT_TOKEN}",
+		"channel": "#drift",
+		"routes": []interface{}{
+			map[string]interface{}{"workspace": "./infra/prod*", "channel": "#prod-drift"},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("New(slack) error = %v", err)
+	}
+	bot, ok := n.(*notify.SlackBotNotifier)
+	if !ok || bot.Token != "xoxb-env" || len(bot.Routes) != 1 || bot.Routes[0].Channel != "#prod-drift" || bot.Routes[0].Workspace.Pattern != "./infra/prod*" {
+		t.Errorf("New(slack) = %+v, want a bot notifier with one route", n)
+	}
+	n, err = notify.New("slack", notify.Settings{"token": "xoxb-$x", "channel": "#drift", "run_url": "https://ci.example.com/${SLACK_BOT_TOKEN}"}, nil)
+	if err != nil {
+		t.Fatalf("New(slack) error = %v", err)
+	}
+	if bot := n.(*notify.SlackBotNotifier); bot.Token != "xoxb-$x" || bot.RunURL != "https://ci.example.com/xoxb-env" {
+		t.Errorf("Token, RunURL = %q, %q, want a literal $ kept and ${VAR} expanded", bot.Token, bot.RunURL)
+	}
+
+	for name, settings := range map[string]notify.Settings{
+		"missing channel": {"token": "xoxb-x"},
+		"bad routes":      {"token": "xoxb-x", "channel": "#d", "routes": "prod"},
+		"incomplete route": {"token": "xoxb-x", "channel": "#d", "routes": []interface{}{
+			map[string]interface{}{"workspace": "./infra/prod*"},
+		}},
+	} {
+		if _, err := notify.New("slack", settings, nil); err == nil {
+			t.Errorf("%s: New(slack) error = nil, want error", name)
+		}
+	}
+}
@@ -1,12 +1,16 @@
 package notify_test
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/daemonship/driftwatch/internal/notify"
 	"github.com/daemonship/driftwatch/internal/report"
@@ -103,35 +107,30 @@ func TestNotify_BodyContainsResourceAddress(t *testing.T) {
 	}
 }
 
-func TestNotify_HTTPErrorDoesNotReturnError(t *testing.T) {
+func TestNotify_HTTPErrorReturnsDeliveryError(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 	}))
 	defer srv.Close()
 
-	var errBuf strings.Builder
-	n := &notify.SlackNotifier{
-		WebhookURL: srv.URL,
-		ErrOut:     &errBuf,
-	}
-	// HTTP 500 should NOT return an error — just log to stderr.
-	if err := n.Notify(driftResults()); err != nil {
-		t.Errorf("Notify() error = %v, want nil even on HTTP error", err)
+	n := &notify.SlackNotifier{WebhookURL: srv.URL, Retry: fastRetry}
+	err := n.Notify(driftResults())
+	var derr *notify.DeliveryError
+	if !errors.As(err, &derr) {
+		t.Fatalf("Notify() error = %v, want a *DeliveryError", err)
 	}
-	if errBuf.Len() == 0 {
-		t.Error("Notify() did not write HTTP error to ErrOut")
+	if derr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("StatusCode = %d, want 500", derr.StatusCode)
 	}
 }
 
-func TestNotify_InvalidURLDoesNotPanic(t *testing.T) {
-	var errBuf strings.Builder
+func TestNotify_InvalidURLReturnsError(t *testing.T) {
 	n := &notify.SlackNotifier{
 		WebhookURL: "http://127.0.0.1:1", // nothing listening
-		ErrOut:     &errBuf,
+		Retry:      fastRetry,
 	}
-	// Connection error should not return error — just log.
-	if err := n.Notify(driftResults()); err != nil {
-		t.Errorf("Notify() error = %v, want nil on connection error", err)
+	if err := n.Notify(driftResults()); err == nil {
+		t.Error("Notify() error = nil, want a connection error")
 	}
 }
 
@@ -171,7 +170,7 @@ func TestNotify_UsesJSONContentType(t *testing.T) {
 	}
 }
 
-// TestNotify_HTTP200IsSuccess ensures status 200 does not write to ErrOut.
+// TestNotify_HTTP200IsSuccess ensures status 200 is not an error.
 // Kills CONDITIONALS_BOUNDARY mutant on `resp.StatusCode < 200`.
 func TestNotify_HTTP200IsSuccess(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -179,32 +178,24 @@ func TestNotify_HTTP200IsSuccess(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	var errBuf strings.Builder
-	n := &notify.SlackNotifier{WebhookURL: srv.URL, ErrOut: &errBuf}
+	n := &notify.SlackNotifier{WebhookURL: srv.URL}
 	if err := n.Notify(driftResults()); err != nil {
 		t.Errorf("Notify() error = %v, want nil", err)
 	}
-	if errBuf.Len() > 0 {
-		t.Errorf("Notify() wrote to ErrOut on HTTP 200 success: %q", errBuf.String())
-	}
 }
 
-// TestNotify_HTTP300IsError ensures status 300 (redirect boundary) writes to ErrOut.
+// TestNotify_HTTP300IsError ensures status 300 (redirect boundary) is an error.
 // Kills CONDITIONALS_BOUNDARY mutant on `resp.StatusCode >= 300`.
 func TestNotify_HTTP300IsError(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// 300 is outside 2xx — must log an error
+		// 300 is outside 2xx — must be an error
 		w.WriteHeader(300)
 	}))
 	defer srv.Close()
 
-	var errBuf strings.Builder
-	n := &notify.SlackNotifier{WebhookURL: srv.URL, ErrOut: &errBuf}
-	if err := n.Notify(driftResults()); err != nil {
-		t.Errorf("Notify() error = %v, want nil", err)
-	}
-	if errBuf.Len() == 0 {
-		t.Error("Notify() did not write to ErrOut for HTTP 300 status")
+	n := &notify.SlackNotifier{WebhookURL: srv.URL}
+	if err := n.Notify(driftResults()); err == nil {
+		t.Error("Notify() error = nil, want an error for HTTP 300")
 	}
 }
 
@@ -299,3 +290,283 @@ func TestNotify_MixedResultsOnlyListsDriftedWorkspaces(t *testing.T) {
 		t.Errorf("expected 'clean' (no drift) to NOT appear in affected list, got: %s", bodyStr)
 	}
 }
+
+func TestNotify_PostsOnCheckFailures(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	results := []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			CheckFailures: []report.CheckFailure{{Address: "check.health", Status: "fail"}},
+		},
+	}
+	n := &notify.SlackNotifier{WebhookURL: srv.URL}
+	if err := n.Notify(results); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if !strings.Contains(string(body), "Health Check Failures") {
+		t.Errorf("expected health check failures section, got body: %s", body)
+	}
+	if !strings.Contains(string(body), "check.health") {
+		t.Errorf("expected failing check address in body, got: %s", body)
+	}
+}
+
+func TestNotify_BodyContainsBreakdown(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	results := []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			Duration:      2 * time.Second,
+			ResourceChanges: []report.ResourceChange{
+				{Address: "aws_instance.web", Action: "update", Type: "aws_instance"},
+				{Address: "aws_instance.api", Action: "delete", Type: "aws_instance"},
+			},
+		},
+		{
+			WorkspacePath: "./infra/production",
+			Duration:      4 * time.Second,
+			ResourceChanges: []report.ResourceChange{
+				{Address: "aws_s3_bucket.logs", Action: "update", Type: "aws_s3_bucket"},
+			},
+		},
+	}
+	n := &notify.SlackNotifier{WebhookURL: srv.URL}
+	if err := n.Notify(results); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	for _, want := range []string{
+		"By action: update 2, delete 1",
+		"By resource type: aws_instance 2, aws_s3_bucket 1",
+		"Scan duration: 6s (slowest: ./infra/production, 4s)",
+		"Most Drifted",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected %q in body, got: %s", want, body)
+		}
+	}
+}
+
+// slackPayload is the Block Kit message shape the tests inspect.
+type slackPayload struct {
+	Text        string       `json:"text"`
+	Blocks      []slackBlock `json:"blocks"`
+	Attachments []struct {
+		Color  string       `json:"color"`
+		Blocks []slackBlock `json:"blocks"`
+	} `json:"attachments"`
+}
+
+type slackBlock struct {
+	Type string `json:"type"`
+	Text *struct {
+		Text string `json:"text"`
+	} `json:"text"`
+	Fields   []struct{ Text string } `json:"fields"`
+	Elements []struct{ Text string } `json:"elements"`
+}
+
+// postToSlack notifies a test webhook with results and decodes the payload.
+func postToSlack(t *testing.T, n *notify.SlackNotifier, results []report.ScanResult) (slackPayload, string) {
+	t.Helper()
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	n.WebhookURL = srv.URL
+	if err := n.Notify(results); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	var msg slackPayload
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatalf("payload is not valid JSON: %v\n%s", err, body)
+	}
+	return msg, string(body)
+}
+
+func TestNotifyEvent_Recovery(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	n := &notify.SlackNotifier{WebhookURL: srv.URL}
+	ev := notify.Event{Triggers: []string{notify.TriggerOnRecovery}, Recovered: []string{"./infra/staging"}}
+	if err := n.NotifyEvent(noDriftResults(), ev); err != nil {
+		t.Fatalf("NotifyEvent() error = %v", err)
+	}
+	var msg slackPayload
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatalf("payload is not valid JSON: %v\n%s", err, body)
+	}
+	if msg.Text != "✅ Terraform Drift Resolved" {
+		t.Errorf("text = %q, want the recovery title", msg.Text)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].Color != "good" {
+		t.Fatalf("attachments = %+v, want one green attachment", msg.Attachments)
+	}
+	blocks := msg.Attachments[0].Blocks
+	if len(blocks) != 1 || blocks[0].Text == nil || !strings.Contains(blocks[0].Text.Text, "*Recovered:*\n:white_check_mark: ./infra/staging") {
+		t.Errorf("attachment blocks = %+v, want the recovered workspace", blocks)
+	}
+}
+
+func TestNotifyEvent_ScanErrors(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	results := []report.ScanResult{{WorkspacePath: "./infra/prod", Err: errors.New("no valid credential sources found")}}
+	n := &notify.SlackNotifier{WebhookURL: srv.URL}
+	if err := n.NotifyEvent(results, notify.Event{Triggers: []string{notify.TriggerOnError}}); err != nil {
+		t.Fatalf("NotifyEvent() error = %v", err)
+	}
+	var msg slackPayload
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatalf("payload is not valid JSON: %v\n%s", err, body)
+	}
+	if msg.Text != "❌ Terraform Scan Errors" || msg.Attachments[0].Color != "danger" {
+		t.Errorf("text = %q, color = %q; want the scan error title in red", msg.Text, msg.Attachments[0].Color)
+	}
+	if !strings.Contains(string(body), "./infra/prod: `no valid credential sources found`") {
+		t.Errorf("payload does not list the scan error:\n%s", body)
+	}
+}
+
+func TestNotify_BlockKitLayout(t *testing.T) {
+	results := []report.ScanResult{{
+		WorkspacePath: "./infra/staging",
+		ResourceChanges: []report.ResourceChange{
+			{Address: "aws_instance.web", Action: "update"},
+			{Address: "aws_iam_role.old", Action: "delete"},
+		},
+	}}
+	msg, _ := postToSlack(t, &notify.SlackNotifier{}, results)
+
+	if len(msg.Blocks) == 0 || msg.Blocks[0].Type != "header" || !strings.Contains(msg.Blocks[0].Text.Text, "Terraform Drift Detected") {
+		t.Fatalf("first block = %+v, want the title header", msg.Blocks)
+	}
+	if len(msg.Attachments) != 1 || len(msg.Attachments[0].Blocks) < 2 {
+		t.Fatalf("attachments = %+v, want one with workspace blocks", msg.Attachments)
+	}
+	section, context := msg.Attachments[0].Blocks[0], msg.Attachments[0].Blocks[1]
+	if section.Type != "section" || !strings.Contains(section.Text.Text, "*./infra/staging*") {
+		t.Errorf("workspace section = %+v, want bold workspace path", section)
+	}
+	for _, want := range []string{":pencil2: `aws_instance.web` update", ":x: `aws_iam_role.old` delete"} {
+		if !strings.Contains(section.Text.Text, want) {
+			t.Errorf("workspace section missing %q:\n%s", want, section.Text.Text)
+		}
+	}
+	if context.Type != "context" || !strings.Contains(context.Elements[0].Text, "2 resource(s): delete 1, update 1") {
+		t.Errorf("workspace context = %+v, want action counts", context)
+	}
+}
+
+func TestNotify_TruncatesLongResourceLists(t *testing.T) {
+	var changes []report.ResourceChange
+	for i := 0; i < 57; i++ {
+		changes = append(changes, report.ResourceChange{Address: fmt.Sprintf("aws_instance.web[%d]", i), Action: "update"})
+	}
+	_, body := postToSlack(t, &notify.SlackNotifier{}, []report.ScanResult{{WorkspacePath: "./infra/staging", ResourceChanges: changes}})
+
+	if !strings.Contains(body, "…and 37 more resources") {
+		t.Errorf("payload does not summarize truncated resources:\n%s", body)
+	}
+	if strings.Contains(body, "aws_instance.web[20]") {
+		t.Errorf("payload lists resources past the limit:\n%s", body)
+	}
+}
+
+func TestNotify_RespectsSlackLimits(t *testing.T) {
+	var results []report.ScanResult
+	for i := 0; i < 60; i++ {
+		var changes []report.ResourceChange
+		for j := 0; j < 30; j++ {
+			changes = append(changes, report.ResourceChange{
+				Address: fmt.Sprintf("module.service_%d.aws_security_group_rule.%s[%d]", i, strings.Repeat("ingress_", 20), j),
+				Action:  "update",
+			})
+		}
+		results = append(results, report.ScanResult{WorkspacePath: fmt.Sprintf("./infra/ws-%02d", i), ResourceChanges: changes})
+	}
+	results[0].CheckFailures = []report.CheckFailure{{Address: "check.health", Status: "fail"}}
+	msg, body := postToSlack(t, &notify.SlackNotifier{}, results)
+
+	blocks := append([]slackBlock(nil), msg.Blocks...)
+	for _, a := range msg.Attachments {
+		blocks = append(blocks, a.Blocks...)
+	}
+	if len(blocks) > 50 {
+		t.Errorf("message has %d blocks, want at most 50", len(blocks))
+	}
+	for _, b := range blocks {
+		if b.Text != nil && len(b.Text.Text) > 3000 {
+			t.Errorf("%s block text is %d characters, want at most 3000", b.Type, len(b.Text.Text))
+		}
+	}
+	if !strings.Contains(body, "more workspace(s) with drift") {
+		t.Errorf("payload does not summarize workspaces past the limits")
+	}
+	if !strings.Contains(body, "check.health") {
+		t.Errorf("health check failures were crowded out by drift")
+	}
+}
+
+func TestNotify_LinksCIRun(t *testing.T) {
+	t.Setenv("GITHUB_SERVER_URL", "https://github.com")
+	t.Setenv("GITHUB_REPOSITORY", "acme/infra")
+	t.Setenv("GITHUB_RUN_ID", "42")
+	msg, _ := postToSlack(t, &notify.SlackNotifier{}, driftResults())
+	if want := "<https://github.com/acme/infra/actions/runs/42|View CI run>"; !contextContains(msg.Blocks, want) {
+		t.Errorf("blocks %+v do not link the GitHub Actions run", msg.Blocks)
+	}
+
+	msg, _ = postToSlack(t, &notify.SlackNotifier{RunURL: "https://ci.example.com/run/7"}, driftResults())
+	if want := "<https://ci.example.com/run/7|View CI run>"; !contextContains(msg.Blocks, want) {
+		t.Errorf("blocks %+v do not use the configured run URL", msg.Blocks)
+	}
+}
+
+func TestNew_SlackExpandsEnv(t *testing.T) {
+	t.Setenv("SLACK_HOOK", "https://hooks.slack.com/services/T/B/X")
+	n, err := notify.New("slack", notify.Settings{
+		"webhook_url": "${SLACK_HOOK}",
+		"run_url":     "https://ci.example.com/run?id=$RUN",
+	}, nil)
+	if err != nil {
+		t.Fatalf("New(slack) error = %v", err)
+	}
+	s, ok := n.(*notify.SlackNotifier)
+	if !ok || s.WebhookURL != "https://hooks.slack.com/services/T/B/X" || s.RunURL != "https://ci.example.com/run?id=$RUN" {
+		t.Errorf("New(slack) = %+v, want webhook_url from env and a literal $ kept in run_url", n)
+	}
+}
+
+// contextContains reports whether a context block in blocks contains text.
+func contextContains(blocks []slackBlock, text string) bool {
+	for _, b := range blocks {
+		for _, e := range b.Elements {
+			if strings.Contains(e.Text, text) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,295 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/daemonship/driftwatch/internal/config"
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+// Microsoft Teams limits the card builder stays within.
+const (
+	// teamsMaxPayload caps the marshaled message, leaving headroom below
+	// the 28 KB Teams accepts from a webhook.
+	teamsMaxPayload = 24000
+	// teamsMaxResources is the number of resources listed per workspace
+	// before the rest are summarized.
+	teamsMaxResources = 20
+	// teamsMaxListItems is the number of health check failures, scan
+	// errors or recovered workspaces listed before the rest are summarized.
+	teamsMaxListItems = 20
+	// teamsMaxLineText is the longest text of one listed item.
+	teamsMaxLineText = 300
+)
+
+// teamsActionStyles maps resource actions to Adaptive Card container styles
+// used for the action badges.
+var teamsActionStyles = map[string]string{
+	"create":  "good",
+	"update":  "warning",
+	"delete":  "attention",
+	"replace": "attention",
+	"read":    "accent",
+}
+
+// TeamsNotifier posts drift summaries to Microsoft Teams as an Adaptive Card,
+// through an incoming webhook or a Workflows (Power Automate) webhook URL.
+type TeamsNotifier struct {
+	// WebhookURL is the Teams incoming webhook or Workflows URL.
+	WebhookURL string
+	// Retry controls retries of failed requests; see RetryPolicy.
+	Retry RetryPolicy
+}
+
+func init() {
+	Register("teams", func(settings Settings, _ *config.Config) (Notifier, error) {
+		url, err := settings.String("webhook_url")
+		if err != nil {
+			return nil, err
+		}
+		url = expandEnv(url)
+		if url == "" {
+			return nil, fmt.Errorf("teams: webhook_url is required")
+		}
+		return &TeamsNotifier{WebhookURL: url}, nil
+	})
+}
+
+// teamsMessage is the webhook payload wrapping an Adaptive Card.
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	ContentURL  *string      `json:"contentUrl"`
+	Content     adaptiveCard `json:"content"`
+}
+
+type adaptiveCard struct {
+	Schema  string            `json:"$schema"`
+	Type    string            `json:"type"`
+	Version string            `json:"version"`
+	Body    []cardElement     `json:"body"`
+	MSTeams map[string]string `json:"msteams,omitempty"`
+}
+
+// cardElement is the subset of Adaptive Card elements used by the notifier:
+// TextBlock, FactSet, ColumnSet, Column and Container.
+type cardElement struct {
+	Type      string        `json:"type"`
+	Text      string        `json:"text,omitempty"`
+	Size      string        `json:"size,omitempty"`
+	Weight    string        `json:"weight,omitempty"`
+	Color     string        `json:"color,omitempty"`
+	FontType  string        `json:"fontType,omitempty"`
+	Wrap      bool          `json:"wrap,omitempty"`
+	Spacing   string        `json:"spacing,omitempty"`
+	Separator bool          `json:"separator,omitempty"`
+	Style     string        `json:"style,omitempty"`
+	Width     string        `json:"width,omitempty"`
+	Facts     []cardFact    `json:"facts,omitempty"`
+	Columns   []cardElement `json:"columns,omitempty"`
+	Items     []cardElement `json:"items,omitempty"`
+}
+
+type cardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// Notify posts an Adaptive Card to the Teams webhook if drift or failing
+// health checks were detected. Silent (no POST) otherwise. Delivery errors
+// are returned for the dispatcher to report.
+func (n *TeamsNotifier) Notify(results []report.ScanResult) error {
+	d := buildDigest(results)
+	if !d.notable() {
+		return nil
+	}
+	return n.send(d)
+}
+
+// NotifyEvent posts a card because a trigger fired: drift as in Notify, and
+// otherwise the scan errors, the recovered workspaces or an all-clear.
+func (n *TeamsNotifier) NotifyEvent(results []report.ScanResult, ev Event) error {
+	return n.send(buildEventDigest(results, ev))
+}
+
+// send posts the card for d to the webhook.
+func (n *TeamsNotifier) send(d digest) error {
+	jsonData, err := json.Marshal(buildTeamsMessage(d))
+	if err != nil {
+		return fmt.Errorf("marshaling Teams message: %w", err)
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	_, err = newSender(n.Retry).deliver("Teams webhook", http.MethodPost, n.WebhookURL, header, jsonData)
+	return err
+}
+
+// buildTeamsMessage renders a scan digest as an Adaptive Card: a headline,
+// summary facts, then each drifted workspace with its resources and action
+// badges, any failing health checks, scan errors and recovered workspaces.
+// Long lists are summarized, and workspaces that would push the card past
+// teamsMaxPayload are counted in a closing note instead.
+func buildTeamsMessage(d digest) teamsMessage {
+	summary := d.Summary
+	titleColor := "warning"
+	switch {
+	case summary.WorkspacesWithDrift > 1, !d.notable() && summary.ScanErrors > 0:
+		titleColor = "attention"
+	case !d.notable():
+		titleColor = "good" // recoveries and all-clears
+	}
+
+	facts := []cardFact{
+		{Title: "Workspaces scanned", Value: fmt.Sprint(summary.WorkspacesScanned)},
+		{Title: "Workspaces with drift", Value: fmt.Sprint(summary.WorkspacesWithDrift)},
+		{Title: "Drifted resources", Value: fmt.Sprint(summary.TotalDriftedResources)},
+		{Title: "Scan errors", Value: fmt.Sprint(summary.ScanErrors)},
+	}
+	if summary.FailedChecks > 0 {
+		facts = append(facts, cardFact{Title: "Health check failures", Value: fmt.Sprint(summary.FailedChecks)})
+	}
+
+	top := []cardElement{
+		{Type: "TextBlock", Text: d.Title, Size: "Large", Weight: "Bolder", Color: titleColor, Wrap: true},
+		{Type: "FactSet", Facts: facts},
+	}
+	for _, line := range d.Breakdown {
+		top = append(top, cardElement{Type: "TextBlock", Text: line, Size: "Small", Wrap: true, Spacing: "None"})
+	}
+
+	checks := make([]string, len(d.CheckFailures))
+	for i, c := range d.CheckFailures {
+		checks[i] = fmt.Sprintf("%s: %s (%s)", c.Workspace, c.Address, c.Status)
+	}
+	errs := make([]string, len(d.Errors))
+	for i, e := range d.Errors {
+		errs[i] = fmt.Sprintf("%s: %s", e.Workspace, e.Message)
+	}
+	// Health checks, errors and recoveries come last but are budgeted
+	// first, so drift in many workspaces cannot crowd them out.
+	tail := teamsListElements("Health Check Failures", "attention", checks)
+	tail = append(tail, teamsListElements("Scan Errors", "attention", errs)...)
+	tail = append(tail, teamsListElements("Recovered", "good", d.Recovered)...)
+
+	// Reserve room for the note about workspaces that do not fit
+	note := func(n int) cardElement {
+		return cardElement{Type: "TextBlock", Text: fmt.Sprintf("…and %d more workspace(s) with drift", n), Wrap: true, Separator: true, Spacing: "Medium"}
+	}
+	left := teamsMaxPayload - jsonLen(newTeamsMessage(append(top, tail...))) - jsonLen(note(len(d.Workspaces))) - 1
+	body := top
+	for i, ws := range d.Workspaces {
+		elements := teamsWorkspaceElements(ws)
+		size := 0
+		for _, e := range elements {
+			size += jsonLen(e) + 1 // and the comma before it
+		}
+		if size > left {
+			body = append(body, note(len(d.Workspaces)-i))
+			break
+		}
+		body = append(body, elements...)
+		left -= size
+	}
+	return newTeamsMessage(append(body, tail...))
+}
+
+// newTeamsMessage wraps card body elements in a webhook message.
+func newTeamsMessage(body []cardElement) teamsMessage {
+	return teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{
+			{
+				ContentType: "application/vnd.microsoft.card.adaptive",
+				Content: adaptiveCard{
+					Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+					Type:    "AdaptiveCard",
+					Version: "1.4",
+					Body:    body,
+					MSTeams: map[string]string{"width": "Full"},
+				},
+			},
+		},
+	}
+}
+
+// teamsWorkspaceElements renders one drifted workspace: its path, then its
+// resources up to teamsMaxResources and how many were left out.
+func teamsWorkspaceElements(ws workspaceDigest) []cardElement {
+	elements := []cardElement{{
+		Type: "TextBlock", Text: truncateText(ws.Path, teamsMaxLineText), Weight: "Bolder", Wrap: true, Separator: true, Spacing: "Medium",
+	}}
+	resources := ws.Resources
+	if len(resources) > teamsMaxResources {
+		resources = resources[:teamsMaxResources]
+	}
+	for _, rc := range resources {
+		elements = append(elements, teamsResourceRow(rc))
+	}
+	if more := len(ws.Resources) - len(resources); more > 0 {
+		elements = append(elements, cardElement{Type: "TextBlock", Text: fmt.Sprintf("…and %d more", more), Size: "Small", Spacing: "None"})
+	}
+	return elements
+}
+
+// teamsListElements renders a titled section listing lines, up to
+// teamsMaxListItems each shortened to teamsMaxLineText, or nothing if lines
+// is empty.
+func teamsListElements(title, color string, lines []string) []cardElement {
+	if len(lines) == 0 {
+		return nil
+	}
+	elements := []cardElement{{
+		Type: "TextBlock", Text: title, Weight: "Bolder", Color: color, Separator: true, Spacing: "Medium",
+	}}
+	shown := lines
+	if len(shown) > teamsMaxListItems {
+		shown = shown[:teamsMaxListItems]
+	}
+	for _, line := range shown {
+		elements = append(elements, cardElement{Type: "TextBlock", Text: truncateText(line, teamsMaxLineText), Wrap: true, Spacing: "None"})
+	}
+	if more := len(lines) - len(shown); more > 0 {
+		elements = append(elements, cardElement{Type: "TextBlock", Text: fmt.Sprintf("…and %d more", more), Size: "Small", Spacing: "None"})
+	}
+	return elements
+}
+
+// jsonLen is the length of v marshaled as JSON.
+func jsonLen(v interface{}) int {
+	b, _ := json.Marshal(v)
+	return len(b)
+}
+
+// teamsResourceRow renders one resource as an action badge next to its address.
+func teamsResourceRow(rc report.ResourceChange) cardElement {
+	style, ok := teamsActionStyles[rc.Action]
+	if !ok {
+		style = "warning"
+	}
+	return cardElement{
+		Type:    "ColumnSet",
+		Spacing: "Small",
+		Columns: []cardElement{
+			{
+				Type:  "Column",
+				Width: "auto",
+				Items: []cardElement{{
+					Type:  "Container",
+					Style: style,
+					Items: []cardElement{{Type: "TextBlock", Text: rc.Action, Size: "Small", Weight: "Bolder"}},
+				}},
+			},
+			{
+				Type:  "Column",
+				Width: "stretch",
+				Items: []cardElement{{Type: "TextBlock", Text: rc.Address, FontType: "Monospace", Wrap: true}},
+			},
+		},
+	}
+}
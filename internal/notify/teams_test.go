@@ -0,0 +1,213 @@
+package notify_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daemonship/driftwatch/internal/notify"
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+func TestTeamsNotify_SilentOnNoDrift(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	n := &notify.TeamsNotifier{WebhookURL: srv.URL}
+	if err := n.Notify(noDriftResults()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if called {
+		t.Error("Notify() posted on a clean scan, want silence")
+	}
+}
+
+func TestTeamsNotify_PostsAdaptiveCard(t *testing.T) {
+	var body []byte
+	var contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		contentType = r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	results := []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			ResourceChanges: []report.ResourceChange{
+				{Address: "aws_instance.web", Action: "update"},
+				{Address: "aws_iam_role.old", Action: "delete"},
+			},
+		},
+		{WorkspacePath: "./infra/clean"},
+	}
+	n := &notify.TeamsNotifier{WebhookURL: srv.URL}
+	if err := n.Notify(results); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", contentType)
+	}
+
+	var msg struct {
+		Type        string `json:"type"`
+		Attachments []struct {
+			ContentType string `json:"contentType"`
+			Content     struct {
+				Type    string            `json:"type"`
+				Version string            `json:"version"`
+				Body    []json.RawMessage `json:"body"`
+			} `json:"content"`
+		} `json:"attachments"`
+	}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatalf("payload is not valid JSON: %v\n%s", err, body)
+	}
+	if msg.Type != "message" || len(msg.Attachments) != 1 {
+		t.Fatalf("payload = %s, want one message attachment", body)
+	}
+	card := msg.Attachments[0]
+	if card.ContentType != "application/vnd.microsoft.card.adaptive" || card.Content.Type != "AdaptiveCard" {
+		t.Errorf("attachment = %+v, want an Adaptive Card", card)
+	}
+
+	payload := string(body)
+	for _, want := range []string{
+		"Terraform Drift Detected",
+		"./infra/staging",
+		"aws_instance.web",
+		`"style":"warning","items":[{"type":"TextBlock","text":"update"`,
+		`"style":"attention","items":[{"type":"TextBlock","text":"delete"`,
+		"By action: delete 1, update 1",
+	} {
+		if !strings.Contains(payload, want) {
+			t.Errorf("payload missing %q:\n%s", want, payload)
+		}
+	}
+	if strings.Contains(payload, "./infra/clean") {
+		t.Errorf("payload lists clean workspace:\n%s", payload)
+	}
+}
+
+func TestTeamsNotify_TruncatesLongResourceLists(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	var changes []report.ResourceChange
+	for i := 0; i < 25; i++ {
+		changes = append(changes, report.ResourceChange{Address: "aws_instance.web", Action: "update"})
+	}
+	n := &notify.TeamsNotifier{WebhookURL: srv.URL}
+	if err := n.Notify([]report.ScanResult{{WorkspacePath: "./infra/staging", ResourceChanges: changes}}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if !strings.Contains(string(body), "and 5 more") {
+		t.Errorf("payload does not summarize truncated resources:\n%s", body)
+	}
+}
+
+func TestTeamsNotify_RespectsPayloadLimit(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	var results []report.ScanResult
+	for i := 0; i < 200; i++ {
+		var changes []report.ResourceChange
+		for j := 0; j < 5; j++ {
+			changes = append(changes, report.ResourceChange{
+				Address: fmt.Sprintf("module.service_%d.aws_security_group_rule.ingress[%d]", i, j),
+				Action:  "update",
+			})
+		}
+		results = append(results, report.ScanResult{WorkspacePath: fmt.Sprintf("./infra/ws-%03d", i), ResourceChanges: changes})
+	}
+	for i := 0; i < 30; i++ {
+		results = append(results, report.ScanResult{
+			WorkspacePath: fmt.Sprintf("./infra/broken-%02d", i),
+			Err:           errors.New(strings.Repeat("provider error ", 100)),
+		})
+	}
+	n := &notify.TeamsNotifier{WebhookURL: srv.URL}
+	if err := n.Notify(results); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if len(body) > 28*1024 {
+		t.Errorf("payload is %d bytes, want at most the 28 KB Teams accepts", len(body))
+	}
+	for _, want := range []string{"more workspace(s) with drift", "Scan Errors", "./infra/broken-00", "…and 10 more"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("payload missing %q", want)
+		}
+	}
+}
+
+func TestTeamsNotifyEvent_ScanErrors(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	results := []report.ScanResult{{WorkspacePath: "./infra/prod", Err: errors.New("no valid credential sources found\nmore detail")}}
+	n := &notify.TeamsNotifier{WebhookURL: srv.URL}
+	if err := n.NotifyEvent(results, notify.Event{Triggers: []string{notify.TriggerOnError}}); err != nil {
+		t.Fatalf("NotifyEvent() error = %v", err)
+	}
+	for _, want := range []string{"❌ Terraform Scan Errors", "Scan Errors", "./infra/prod: no valid credential sources found"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("card missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(string(body), "more detail") {
+		t.Error("card includes more than the first line of the error")
+	}
+}
+
+func TestTeamsNotify_HTTPErrorReturnsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	n := &notify.TeamsNotifier{WebhookURL: srv.URL}
+	if err := n.Notify(driftResults()); err == nil {
+		t.Error("Notify() error = nil, want error on HTTP 400")
+	}
+}
+
+func TestNew_Teams(t *testing.T) {
+	if _, err := notify.New("teams", notify.Settings{}, nil); err == nil {
+		t.Error("New(teams) error = nil, want error without webhook_url")
+	}
+	n, err := notify.New("teams", notify.Settings{"webhook_url": "https://example.webhook.office.com/x"}, nil)
+	if err != nil {
+		t.Fatalf("New(teams) error = %v", err)
+	}
+	if _, ok := n.(*notify.TeamsNotifier); !ok {
+		t.Errorf("New(teams) = %T, want *notify.TeamsNotifier", n)
+	}
+
+	t.Setenv("TEAMS_WEBHOOK", "https://example.webhook.office.com/env")
+	n, err = notify.New("teams", notify.Settings{"webhook_url": "${TEAMS_WEBHOOK}"}, nil)
+	if err != nil {
+		t.Fatalf("New(teams) error = %v", err)
+	}
+	if teams := n.(*notify.TeamsNotifier); teams.WebhookURL != "https://example.webhook.office.com/env" {
+		t.Errorf("WebhookURL = %q, want it read from the environment", teams.WebhookURL)
+	}
+}
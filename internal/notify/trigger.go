@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/daemonship/driftwatch/internal/report"
+	"github.com/daemonship/driftwatch/internal/state"
+)
+
+// Triggers decide when a target that lists them is notified. A target
+// without triggers is notified on drift or failing health checks, like
+// TriggerOnDrift.
+const (
+	// TriggerOnDrift fires when there is drift or a failing health check.
+	TriggerOnDrift = "on_drift"
+	// TriggerOnError fires when a workspace could not be scanned.
+	TriggerOnError = "on_error"
+	// TriggerOnRecovery fires when a workspace that drifted or errored in
+	// the previous scan is clean.
+	TriggerOnRecovery = "on_recovery"
+	// TriggerOnChange fires when the drifted resources differ from the
+	// previous scan's.
+	TriggerOnChange = "on_change"
+	// TriggerAlways fires when the target has not been notified for
+	// HeartbeatInterval, give or take heartbeatSlack, so silence means the
+	// scans stopped.
+	TriggerAlways = "always"
+)
+
+// Triggers lists the trigger names, in the order fired triggers are
+// reported.
+var Triggers = []string{TriggerOnDrift, TriggerOnError, TriggerOnRecovery, TriggerOnChange, TriggerAlways}
+
+// HeartbeatInterval is how often TriggerAlways fires.
+const HeartbeatInterval = 24 * time.Hour
+
+// heartbeatSlack is how much earlier than HeartbeatInterval TriggerAlways
+// still fires, so a daily scan that starts a little earlier than the day
+// before does not skip its heartbeat.
+const heartbeatSlack = time.Hour
+
+// Event says why a target with triggers is notified.
+type Event struct {
+	// Triggers lists the target's triggers that fired.
+	Triggers []string
+	// Recovered lists the workspaces that drifted or errored in the previous
+	// scan and are clean now.
+	Recovered []string
+}
+
+// recoveredIn returns the workspaces of results that ev lists as recovered.
+func recoveredIn(results []report.ScanResult, ev Event) []string {
+	var out []string
+	for _, r := range results {
+		if slices.Contains(ev.Recovered, r.WorkspacePath) {
+			out = append(out, r.WorkspacePath)
+		}
+	}
+	return out
+}
+
+// TriggeredNotifier is a Notifier that can be configured with triggers. It
+// is sent results whenever a trigger fires, even if they hold no drift.
+type TriggeredNotifier interface {
+	Notifier
+	// NotifyEvent sends results, which may be clean, because ev fired.
+	NotifyEvent(results []report.ScanResult, ev Event) error
+}
+
+// StatefulTriggeredNotifier is a TriggeredNotifier that remembers what it
+// delivered between runs, as a StatefulNotifier does.
+type StatefulTriggeredNotifier interface {
+	TriggeredNotifier
+	// NotifyEventWithState is NotifyEvent given what the target kept in st
+	// on earlier runs.
+	NotifyEventWithState(results []report.ScanResult, ev Event, st TargetState) error
+}
+
+// triggerRecord is what a target with triggers remembers between runs.
+type triggerRecord struct {
+	// Workspaces is the last known state of each workspace as the target
+	// saw it, after its filters.
+	Workspaces map[string]state.Workspace `json:"workspaces,omitempty"`
+	// LastSent is when the target was last notified.
+	LastSent time.Time `json:"last_sent,omitempty"`
+}
+
+// evaluate returns the event for a target with triggers, given the results
+// it receives and what it saw last. No trigger fired if ev.Triggers is
+// empty.
+func evaluate(triggers []string, results []report.ScanResult, last triggerRecord, now time.Time) Event {
+	var ev Event
+	fired := make(map[string]bool)
+	summary := report.Summarize(results)
+	fired[TriggerOnDrift] = summary.WorkspacesWithDrift > 0 || summary.FailedChecks > 0
+	fired[TriggerOnError] = summary.ScanErrors > 0
+	fired[TriggerAlways] = now.Sub(last.LastSent) >= HeartbeatInterval-heartbeatSlack
+
+	for _, r := range results {
+		prev, seen := last.Workspaces[r.WorkspacePath]
+		status := r.Status()
+		if seen && prev.Status != report.StatusClean && status == report.StatusClean {
+			ev.Recovered = append(ev.Recovered, r.WorkspacePath)
+		}
+		// What drifted in an errored workspace is unknown, not changed
+		if status != report.StatusError && !maps.Equal(driftedResources(r), prev.Drifted) {
+			fired[TriggerOnChange] = true
+		}
+	}
+	fired[TriggerOnRecovery] = len(ev.Recovered) > 0
+
+	for _, t := range Triggers {
+		if fired[t] && slices.Contains(triggers, t) {
+			ev.Triggers = append(ev.Triggers, t)
+		}
+	}
+	return ev
+}
+
+// driftedResources maps the address of each drifted resource in r to its
+// action, as recorded in state.Workspace.Drifted.
+func driftedResources(r report.ScanResult) map[string]string {
+	drifted := make(map[string]string, len(r.ResourceChanges))
+	for _, rc := range r.ResourceChanges {
+		drifted[rc.Address] = rc.Action
+	}
+	return drifted
+}
@@ -0,0 +1,191 @@
+package notify_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/daemonship/driftwatch/internal/config"
+	"github.com/daemonship/driftwatch/internal/notify"
+	"github.com/daemonship/driftwatch/internal/report"
+	"github.com/daemonship/driftwatch/internal/state"
+)
+
+// eventNotifier records the events it is sent and returns err.
+type eventNotifier struct {
+	recordingNotifier
+	events []notify.Event
+}
+
+func (n *eventNotifier) NotifyEvent(results []report.ScanResult, ev notify.Event) error {
+	n.events = append(n.events, ev)
+	return n.Notify(results)
+}
+
+// triggerRun dispatches results to one target with triggers and returns
+// its delivery and the event it was sent, if any.
+func triggerRun(t *testing.T, prev *state.Snapshot, n *eventNotifier, triggers []string, results []report.ScanResult) (notify.Delivery, *notify.Event) {
+	t.Helper()
+	sent := len(n.events)
+	d := &notify.Dispatcher{
+		Targets: []notify.Target{{Name: "chat", Notifier: n, Triggers: triggers}},
+		State:   prev,
+	}
+	delivery := d.Dispatch(results)[0]
+	if len(n.events) > sent {
+		return delivery, &n.events[len(n.events)-1]
+	}
+	return delivery, nil
+}
+
+func TestTriggers_OnRecovery(t *testing.T) {
+	prev, n := state.New(), &eventNotifier{}
+	triggers := []string{notify.TriggerOnRecovery}
+
+	if d, ev := triggerRun(t, prev, n, triggers, driftResults()); ev != nil || !d.Skipped {
+		t.Errorf("first run: delivery %+v, event %+v; want skipped", d, ev)
+	}
+	_, ev := triggerRun(t, prev, n, triggers, noDriftResults())
+	if ev == nil {
+		t.Fatal("drift fixed: no notification, want on_recovery")
+	}
+	if !slices.Equal(ev.Triggers, triggers) || !slices.Equal(ev.Recovered, []string{"./infra/staging"}) {
+		t.Errorf("event = %+v, want on_recovery for ./infra/staging", ev)
+	}
+	if _, ev := triggerRun(t, prev, n, triggers, noDriftResults()); ev != nil {
+		t.Errorf("still clean: event %+v, want none", ev)
+	}
+}
+
+func TestTriggers_OnChange(t *testing.T) {
+	prev, n := state.New(), &eventNotifier{}
+	triggers := []string{notify.TriggerOnChange}
+
+	if _, ev := triggerRun(t, prev, n, triggers, driftResults()); ev == nil {
+		t.Error("new drift: no notification, want on_change")
+	}
+	if _, ev := triggerRun(t, prev, n, triggers, driftResults()); ev != nil {
+		t.Errorf("same drift: event %+v, want none", ev)
+	}
+	changed := driftResults()
+	changed[0].ResourceChanges[0].Action = "delete"
+	if _, ev := triggerRun(t, prev, n, triggers, changed); ev == nil {
+		t.Error("action changed: no notification, want on_change")
+	}
+	// An errored scan says nothing about the drift set
+	errored := []report.ScanResult{{WorkspacePath: "./infra/staging", Err: errors.New("no credentials")}}
+	if _, ev := triggerRun(t, prev, n, triggers, errored); ev != nil {
+		t.Errorf("scan error: event %+v, want none", ev)
+	}
+	if _, ev := triggerRun(t, prev, n, triggers, changed); ev != nil {
+		t.Errorf("same drift after an error: event %+v, want none", ev)
+	}
+}
+
+func TestTriggers_OnErrorAndOnDrift(t *testing.T) {
+	triggers := []string{notify.TriggerOnDrift, notify.TriggerOnError}
+	errored := []report.ScanResult{{WorkspacePath: "./infra/prod", Err: errors.New("no credentials")}}
+
+	_, ev := triggerRun(t, state.New(), &eventNotifier{}, triggers, errored)
+	if ev == nil || !slices.Equal(ev.Triggers, []string{notify.TriggerOnError}) {
+		t.Errorf("scan error: event %+v, want on_error", ev)
+	}
+	_, ev = triggerRun(t, state.New(), &eventNotifier{}, triggers, append(errored, driftResults()...))
+	if ev == nil || !slices.Equal(ev.Triggers, triggers) {
+		t.Errorf("drift and error: event %+v, want on_drift and on_error", ev)
+	}
+	if d, ev := triggerRun(t, state.New(), &eventNotifier{}, triggers, noDriftResults()); ev != nil || !d.Skipped {
+		t.Errorf("clean scan: delivery %+v, event %+v; want skipped", d, ev)
+	}
+}
+
+func TestTriggers_AlwaysIsDailyHeartbeat(t *testing.T) {
+	prev, n := state.New(), &eventNotifier{}
+	triggers := []string{notify.TriggerAlways}
+
+	if _, ev := triggerRun(t, prev, n, triggers, noDriftResults()); ev == nil {
+		t.Fatal("first run: no notification, want a heartbeat")
+	}
+	if _, ev := triggerRun(t, prev, n, triggers, noDriftResults()); ev != nil {
+		t.Errorf("second run the same day: event %+v, want none", ev)
+	}
+}
+
+func TestTriggers_AlwaysToleratesEarlierDailyRuns(t *testing.T) {
+	for _, tc := range []struct {
+		ago  time.Duration
+		fire bool
+	}{
+		{23*time.Hour + 59*time.Minute, true},
+		{23*time.Hour + 30*time.Minute, true},
+		{12 * time.Hour, false},
+	} {
+		prev := state.New()
+		st := notify.TargetState{Snapshot: prev, Target: "chat"}
+		if err := st.Save("trigger", map[string]time.Time{"last_sent": time.Now().Add(-tc.ago)}); err != nil {
+			t.Fatal(err)
+		}
+		_, ev := triggerRun(t, prev, &eventNotifier{}, []string{notify.TriggerAlways}, noDriftResults())
+		if fired := ev != nil; fired != tc.fire {
+			t.Errorf("last sent %v ago: fired = %v, want %v", tc.ago, fired, tc.fire)
+		}
+	}
+}
+
+func TestTriggers_FailedDeliveryFiresAgain(t *testing.T) {
+	prev := state.New()
+	n := &eventNotifier{recordingNotifier: recordingNotifier{err: errors.New("boom")}}
+	triggers := []string{notify.TriggerOnChange}
+
+	if d, _ := triggerRun(t, prev, n, triggers, driftResults()); d.Err == nil {
+		t.Fatal("delivery error = nil, want boom")
+	}
+	n.err = nil
+	if _, ev := triggerRun(t, prev, n, triggers, driftResults()); ev == nil {
+		t.Error("after a failed delivery: no notification, want on_change again")
+	}
+}
+
+func TestTriggers_NeedState(t *testing.T) {
+	d := &notify.Dispatcher{Targets: []notify.Target{{Name: "chat", Notifier: &eventNotifier{}}}}
+	if d.NeedsState() {
+		t.Error("NeedsState() = true without triggers")
+	}
+	d.Targets[0].Triggers = []string{notify.TriggerOnRecovery}
+	if !d.NeedsState() {
+		t.Error("NeedsState() = false with triggers")
+	}
+}
+
+func TestTargetsFromConfig_Triggers(t *testing.T) {
+	t.Setenv("DRIFTWATCH_SLACK_WEBHOOK", "")
+	cfg := &config.Config{Notifications: []config.Notification{{
+		Name:     "chat",
+		Type:     "slack",
+		Triggers: []string{"on_error", "on_recovery"},
+		Settings: map[string]interface{}{"webhook_url": "https://hooks.slack.com/services/x"},
+	}}}
+	targets, err := notify.TargetsFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("TargetsFromConfig() error = %v", err)
+	}
+	if !slices.Equal(targets[0].Triggers, []string{"on_error", "on_recovery"}) {
+		t.Errorf("Triggers = %v, want on_error and on_recovery", targets[0].Triggers)
+	}
+
+	cfg.Notifications[0].Settings = map[string]interface{}{"token": "xoxb-x", "channel": "#drift"}
+	if _, err := notify.TargetsFromConfig(cfg); err != nil {
+		t.Errorf("TargetsFromConfig() error = %v, want triggers on a slack bot", err)
+	}
+
+	cfg.Notifications[0] = config.Notification{
+		Name:     "oncall",
+		Type:     "pagerduty",
+		Triggers: []string{"on_error"},
+		Settings: map[string]interface{}{"routing_key": "key"},
+	}
+	if _, err := notify.TargetsFromConfig(cfg); err == nil {
+		t.Error("TargetsFromConfig() error = nil, want error for triggers on pagerduty")
+	}
+}
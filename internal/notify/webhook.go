@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/daemonship/driftwatch/internal/config"
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+// DefaultSignatureHeader is the request header carrying the HMAC-SHA256
+// signature of the body when a webhook secret is configured.
+const DefaultSignatureHeader = "X-Driftwatch-Signature"
+
+// WebhookNotifier sends drift events to an arbitrary HTTP endpoint.
+type WebhookNotifier struct {
+	// URL is the endpoint to call.
+	URL string
+	// Method is the HTTP method (default POST).
+	Method string
+	// Headers are added to every request.
+	Headers map[string]string
+	// Body renders the request body; nil sends the JSON scan report with
+	// the event's "triggers" and "recovered" workspaces added.
+	Body *template.Template
+	// Secret, when set, signs the body with HMAC-SHA256. The signature is
+	// sent as "sha256=<hex>" in SignatureHeader.
+	Secret string
+	// SignatureHeader defaults to DefaultSignatureHeader.
+	SignatureHeader string
+	// Retry controls retries of failed requests; see RetryPolicy.
+	Retry RetryPolicy
+}
+
+// WebhookData is the data a webhook body template is rendered with.
+type WebhookData struct {
+	// Results are the scan results as in the "results" array of the JSON
+	// report (see report.JSONResults and the published schema), with
+	// sensitive values redacted. Each is a map with the keys workspace,
+	// status, terraform_version, duration_ms, error (nil, or message and
+	// stderr), resource_changes (address, action and attributes, which map
+	// names to before, after, sensitive and after_unknown), check_failures
+	// (address, status, problems) and informational (address, kind,
+	// reason). Numbers are json.Number. For example:
+	//
+	//	{{range .Results}}{{.workspace}}: {{len .resource_changes}}{{end}}
+	Results []interface{}
+	Summary report.Summary
+	// Triggers and Recovered are set when the target has triggers; see
+	// Event.
+	Triggers  []string
+	Recovered []string
+}
+
+// webhookFuncs are the functions available to body templates.
+var webhookFuncs = template.FuncMap{
+	// json encodes a value as JSON, e.g. {{json .Summary.ByAction}}.
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func init() {
+	Register("webhook", newWebhookNotifier)
+}
+
+// newWebhookNotifier builds a WebhookNotifier from settings: url, method,
+// headers, body_template, secret and signature_header. ${VAR} references to
+// environment variables are expanded in url, headers and secret, so
+// credentials can stay out of the config file.
+func newWebhookNotifier(settings Settings, _ *config.Config) (Notifier, error) {
+	n := &WebhookNotifier{}
+	var err error
+	for key, dst := range map[string]*string{
+		"url":              &n.URL,
+		"method":           &n.Method,
+		"secret":           &n.Secret,
+		"signature_header": &n.SignatureHeader,
+	} {
+		if *dst, err = settings.String(key); err != nil {
+			return nil, err
+		}
+	}
+	n.URL, n.Secret = expandEnv(n.URL), expandEnv(n.Secret)
+	if n.URL == "" {
+		return nil, fmt.Errorf("webhook: url is required")
+	}
+
+	if n.Headers, err = settings.StringMap("headers"); err != nil {
+		return nil, err
+	}
+	for k, v := range n.Headers {
+		n.Headers[k] = expandEnv(v)
+	}
+
+	body, err := settings.String("body_template")
+	if err != nil {
+		return nil, err
+	}
+	if body != "" {
+		if n.Body, err = template.New("webhook").Funcs(webhookFuncs).Parse(body); err != nil {
+			return nil, fmt.Errorf("webhook: parsing body_template: %w", err)
+		}
+	}
+	return n, nil
+}
+
+// Notify sends the rendered body to the webhook if drift or failing health
+// checks were detected. Silent (no request) otherwise.
+func (n *WebhookNotifier) Notify(results []report.ScanResult) error {
+	d := buildDigest(results)
+	if !d.notable() {
+		return nil
+	}
+	return n.send(results, Event{})
+}
+
+// NotifyEvent sends the rendered body because a trigger fired, with the
+// event's triggers and recovered workspaces available to the template.
+func (n *WebhookNotifier) NotifyEvent(results []report.ScanResult, ev Event) error {
+	return n.send(results, ev)
+}
+
+// send renders results and delivers them to the webhook.
+func (n *WebhookNotifier) send(results []report.ScanResult, ev Event) error {
+	body, err := n.render(results, ev)
+	if err != nil {
+		return err
+	}
+
+	method := n.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	for k, v := range n.Headers {
+		header.Set(k, v)
+	}
+	if n.Secret != "" {
+		header.Set(valueOr(n.SignatureHeader, DefaultSignatureHeader), Sign(n.Secret, body))
+	}
+	_, err = newSender(n.Retry).deliver("webhook", method, n.URL, header, body)
+	return err
+}
+
+// render produces the request body from the template, or the JSON scan
+// report with the event when no template is configured.
+func (n *WebhookNotifier) render(results []report.ScanResult, ev Event) ([]byte, error) {
+	var buf bytes.Buffer
+	if n.Body == nil {
+		body, err := webhookReport(results, ev)
+		if err != nil {
+			return nil, fmt.Errorf("rendering webhook body: %w", err)
+		}
+		return body, nil
+	}
+	jsonResults, err := report.JSONResults(results)
+	if err != nil {
+		return nil, fmt.Errorf("rendering webhook body: %w", err)
+	}
+	data := WebhookData{
+		Results:   jsonResults,
+		Summary:   report.Summarize(results),
+		Triggers:  ev.Triggers,
+		Recovered: ev.Recovered,
+	}
+	if err := n.Body.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("rendering webhook body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// webhookReport renders the JSON scan report with the event's triggers and
+// recovered workspaces as top-level "triggers" and "recovered" arrays, which
+// are empty when the target has no triggers.
+func webhookReport(results []report.ScanResult, ev Event) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf, results, report.Metadata{GeneratedAt: time.Now()}); err != nil {
+		return nil, err
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		return nil, err
+	}
+	for key, list := range map[string][]string{"triggers": ev.Triggers, "recovered": ev.Recovered} {
+		if list == nil {
+			list = []string{}
+		}
+		raw, err := json.Marshal(list)
+		if err != nil {
+			return nil, err
+		}
+		doc[key] = raw
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// Sign returns the signature of body under secret as sent by the webhook
+// notifier: "sha256=" followed by the hex-encoded HMAC-SHA256. Receivers
+// should recompute it over the raw request body and compare with
+// hmac.Equal.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
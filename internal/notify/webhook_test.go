@@ -0,0 +1,289 @@
+package notify_test
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daemonship/driftwatch/internal/notify"
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+// capturedRequest is what a test webhook server received.
+type capturedRequest struct {
+	Method string
+	Header http.Header
+	Body   []byte
+}
+
+func webhookServer(t *testing.T, status int) (*httptest.Server, *capturedRequest) {
+	t.Helper()
+	got := &capturedRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.Method = r.Method
+		got.Header = r.Header.Clone()
+		got.Body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, got
+}
+
+func TestWebhook_TemplatedBody(t *testing.T) {
+	srv, got := webhookServer(t, http.StatusOK)
+	t.Setenv("DRIFT_TOKEN", "s3cret")
+
+	n, err := notify.New("webhook", notify.Settings{
+		"url":    srv.URL,
+		"method": "PUT",
+		"headers": map[string]interface{}{
+			"Authorization": "Bearer ${DRIFT_TOKEN}",
+		},
+		"body_template": `{"drifted": {{.Summary.TotalDriftedResources}}, "workspaces": [{{range $i, $r := .Results}}{{if $i}}, {{end}}{{json $r.workspace}}{{end}}]}`,
+	}, nil)
+	if err != nil {
+		t.Fatalf("New(webhook) error = %v", err)
+	}
+	if err := n.Notify(driftResults()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if got.Method != "PUT" {
+		t.Errorf("method = %q, want PUT", got.Method)
+	}
+	if auth := got.Header.Get("Authorization"); auth != "Bearer s3cret" {
+		t.Errorf("Authorization = %q, want env var interpolated", auth)
+	}
+	var body struct {
+		Drifted    int      `json:"drifted"`
+		Workspaces []string `json:"workspaces"`
+	}
+	if err := json.Unmarshal(got.Body, &body); err != nil {
+		t.Fatalf("body is not valid JSON: %v\n%s", err, got.Body)
+	}
+	if body.Drifted != 1 || len(body.Workspaces) != 1 || body.Workspaces[0] != "./infra/staging" {
+		t.Errorf("body = %+v, want 1 drifted resource in ./infra/staging", body)
+	}
+}
+
+func TestWebhook_TemplateResultsAreRedacted(t *testing.T) {
+	srv, got := webhookServer(t, http.StatusOK)
+	n, err := notify.New("webhook", notify.Settings{
+		"url":           srv.URL,
+		"body_template": `{{json .Results}}`,
+	}, nil)
+	if err != nil {
+		t.Fatalf("New(webhook) error = %v", err)
+	}
+	results := []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			ResourceChanges: []report.ResourceChange{
+				{
+					Address: "aws_db_instance.main",
+					Action:  "update",
+					Attributes: map[string]report.AttributeChange{
+						"password": {Before: "hunter2", After: "hunter3", Sensitive: true},
+					},
+				},
+			},
+		},
+		{WorkspacePath: "./infra/prod", Err: errors.New("terraform init failed")},
+	}
+	if err := n.Notify(results); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	body := string(got.Body)
+	if strings.Contains(body, "hunter") {
+		t.Errorf("template data leaked a sensitive value:\n%s", body)
+	}
+	if !strings.Contains(body, `"sensitive":true`) {
+		t.Errorf("template data does not flag the sensitive attribute:\n%s", body)
+	}
+	if !strings.Contains(body, `"message":"terraform init failed"`) {
+		t.Errorf("template data does not carry the scan error message:\n%s", body)
+	}
+}
+
+func TestWebhook_KeepsLiteralDollarSigns(t *testing.T) {
+	srv, got := webhookServer(t, http.StatusOK)
+	t.Setenv("DRIFT_TOKEN", "s3cret")
+
+	n, err := notify.New("webhook", notify.Settings{
+		"url":    srv.URL,
+		"secret": "pa$$word",
+		"headers": map[string]interface{}{
+			"X-Token": "$DRIFT_TOKEN-${DRIFT_TOKEN}",
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("New(webhook) error = %v", err)
+	}
+	if err := n.Notify(driftResults()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if tok := got.Header.Get("X-Token"); tok != "$DRIFT_TOKEN-s3cret" {
+		t.Errorf("X-Token = %q, want only ${VAR} expanded", tok)
+	}
+	if sig, want := got.Header.Get(notify.DefaultSignatureHeader), notify.Sign("pa$$word", got.Body); sig != want {
+		t.Errorf("signature = %q, want one made with the literal secret %q", sig, want)
+	}
+}
+
+func TestWebhook_DefaultBodyIsJSONReport(t *testing.T) {
+	srv, got := webhookServer(t, http.StatusOK)
+	n, err := notify.New("webhook", notify.Settings{"url": srv.URL}, nil)
+	if err != nil {
+		t.Fatalf("New(webhook) error = %v", err)
+	}
+	if err := n.Notify(driftResults()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if got.Method != http.MethodPost {
+		t.Errorf("method = %q, want POST", got.Method)
+	}
+	if !strings.Contains(string(got.Body), `"schema_version": "1"`) || !strings.Contains(string(got.Body), "aws_instance.web") {
+		t.Errorf("default body is not the JSON report:\n%s", got.Body)
+	}
+}
+
+func TestWebhook_SignsBody(t *testing.T) {
+	srv, got := webhookServer(t, http.StatusOK)
+	n := &notify.WebhookNotifier{URL: srv.URL, Secret: "shared-secret"}
+	if err := n.Notify(driftResults()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	sig := got.Header.Get(notify.DefaultSignatureHeader)
+	if !strings.HasPrefix(sig, "sha256=") {
+		t.Fatalf("signature header = %q, want sha256= prefix", sig)
+	}
+	if want := notify.Sign("shared-secret", got.Body); !hmac.Equal([]byte(sig), []byte(want)) {
+		t.Errorf("signature = %q, want %q", sig, want)
+	}
+}
+
+func TestWebhook_NoSignatureWithoutSecret(t *testing.T) {
+	srv, got := webhookServer(t, http.StatusOK)
+	n := &notify.WebhookNotifier{URL: srv.URL}
+	if err := n.Notify(driftResults()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if sig := got.Header.Get(notify.DefaultSignatureHeader); sig != "" {
+		t.Errorf("signature header = %q, want none without a secret", sig)
+	}
+}
+
+func TestSign_KnownVector(t *testing.T) {
+	// HMAC-SHA256 test case 2 from RFC 4231.
+	got := notify.Sign("Jefe", []byte("what do ya want for nothing?"))
+	want := "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+}
+
+func TestWebhook_HTTPErrorReturnsError(t *testing.T) {
+	srv, _ := webhookServer(t, http.StatusInternalServerError)
+	n := &notify.WebhookNotifier{URL: srv.URL, Retry: fastRetry}
+	if err := n.Notify(driftResults()); err == nil {
+		t.Error("Notify() error = nil, want error on HTTP 500")
+	}
+}
+
+func TestWebhook_SilentOnNoDrift(t *testing.T) {
+	srv, got := webhookServer(t, http.StatusOK)
+	n := &notify.WebhookNotifier{URL: srv.URL}
+	if err := n.Notify(noDriftResults()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if got.Method != "" {
+		t.Error("Notify() sent a request on a clean scan, want silence")
+	}
+}
+
+func TestWebhook_NotifyEventSendsCleanScans(t *testing.T) {
+	srv, got := webhookServer(t, http.StatusOK)
+	n, err := notify.New("webhook", notify.Settings{
+		"url":           srv.URL,
+		"body_template": `{"triggers": {{json .Triggers}}, "recovered": {{json .Recovered}}}`,
+	}, nil)
+	if err != nil {
+		t.Fatalf("New(webhook) error = %v", err)
+	}
+	ev := notify.Event{Triggers: []string{notify.TriggerOnRecovery}, Recovered: []string{"./infra/staging"}}
+	if err := n.(notify.TriggeredNotifier).NotifyEvent(noDriftResults(), ev); err != nil {
+		t.Fatalf("NotifyEvent() error = %v", err)
+	}
+	want := `{"triggers": ["on_recovery"], "recovered": ["./infra/staging"]}`
+	if string(got.Body) != want {
+		t.Errorf("body = %s, want %s", got.Body, want)
+	}
+}
+
+func TestWebhook_DefaultBodyIncludesEvent(t *testing.T) {
+	srv, got := webhookServer(t, http.StatusOK)
+	n, err := notify.New("webhook", notify.Settings{"url": srv.URL}, nil)
+	if err != nil {
+		t.Fatalf("New(webhook) error = %v", err)
+	}
+	ev := notify.Event{Triggers: []string{notify.TriggerOnRecovery}, Recovered: []string{"./infra/staging"}}
+	if err := n.(notify.TriggeredNotifier).NotifyEvent(noDriftResults(), ev); err != nil {
+		t.Fatalf("NotifyEvent() error = %v", err)
+	}
+	var body struct {
+		SchemaVersion string   `json:"schema_version"`
+		Triggers      []string `json:"triggers"`
+		Recovered     []string `json:"recovered"`
+	}
+	if err := json.Unmarshal(got.Body, &body); err != nil {
+		t.Fatalf("body is not valid JSON: %v\n%s", err, got.Body)
+	}
+	if body.SchemaVersion != "1" || len(body.Triggers) != 1 || body.Triggers[0] != "on_recovery" ||
+		len(body.Recovered) != 1 || body.Recovered[0] != "./infra/staging" {
+		t.Errorf("body = %s, want the JSON report with the event", got.Body)
+	}
+
+	if err := n.Notify(driftResults()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if !strings.Contains(string(got.Body), `"triggers": []`) || !strings.Contains(string(got.Body), `"recovered": []`) {
+		t.Errorf("body without an event lacks empty triggers and recovered:\n%s", got.Body)
+	}
+}
+
+func TestWebhook_TemplateResultFields(t *testing.T) {
+	srv, got := webhookServer(t, http.StatusOK)
+	n, err := notify.New("webhook", notify.Settings{
+		"url":           srv.URL,
+		"body_template": `{{range .Results}}{{.workspace}} {{.status}}{{range .resource_changes}} {{.address}}={{.action}}{{end}}{{end}}`,
+	}, nil)
+	if err != nil {
+		t.Fatalf("New(webhook) error = %v", err)
+	}
+	if err := n.Notify(driftResults()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if want := "./infra/staging drifted aws_instance.web=update"; !strings.HasPrefix(string(got.Body), want) {
+		t.Errorf("body = %q, want it to start with %q", got.Body, want)
+	}
+}
+
+func TestNew_WebhookInvalidSettings(t *testing.T) {
+	for name, settings := range map[string]notify.Settings{
+		"missing url":  {},
+		"bad template": {"url": "https://example.com", "body_template": "{{.Nope"},
+		"bad headers":  {"url": "https://example.com", "headers": "X-Token: abc"},
+	} {
+		if _, err := notify.New("webhook", settings, nil); err == nil {
+			t.Errorf("%s: New(webhook) error = nil, want error", name)
+		}
+	}
+}
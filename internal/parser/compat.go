@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Known plan JSON format versions (driftwatch requires Terraform >= 1.0.0):
+//
+//	0.2 — Terraform 1.0
+//	1.0 — Terraform 1.1
+//	1.1 — Terraform 1.2–1.4 (adds action_reason; 1.2–1.3 emit experimental condition_results)
+//	1.2 — Terraform 1.5+ and OpenTofu 1.6+ (adds checks; Terraform 1.10 adds deferred_changes)
+//
+// OpenTofu reports its own version in the terraform_version field and shares
+// the Terraform 1.x schema, so both tools are validated the same way.
+const (
+	// maxFormatMajor is the newest format_version major driftwatch understands.
+	maxFormatMajor = 1
+	// supportedToolMajor is the Terraform/OpenTofu major version driftwatch supports.
+	supportedToolMajor = 1
+)
+
+// UnsupportedVersionError is returned when a plan uses a format_version or was
+// produced by a terraform_version that driftwatch does not understand.
+type UnsupportedVersionError struct {
+	// Field is the plan field that failed validation ("format_version" or "terraform_version").
+	Field string
+	// Version is the value found in the plan.
+	Version string
+	// Reason explains why the version is not supported.
+	Reason string
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("unsupported plan %s %q: %s", e.Field, e.Version, e.Reason)
+}
+
+// checkCompatibility validates the plan format_version and terraform_version.
+// Empty values are accepted, since neither field is guaranteed to be present.
+func checkCompatibility(formatVersion, terraformVersion string) error {
+	if formatVersion != "" {
+		major, minor, ok := parseMajorMinor(formatVersion)
+		switch {
+		case !ok:
+			return &UnsupportedVersionError{Field: "format_version", Version: formatVersion, Reason: "not a valid version"}
+		case major == 0 && minor < 2:
+			return &UnsupportedVersionError{Field: "format_version", Version: formatVersion, Reason: "produced by Terraform < 1.0; driftwatch requires Terraform >= 1.0.0"}
+		case major > maxFormatMajor:
+			return &UnsupportedVersionError{Field: "format_version", Version: formatVersion, Reason: fmt.Sprintf("unknown major version; driftwatch supports format_version 0.2 through %d.x", maxFormatMajor)}
+		}
+	}
+
+	if terraformVersion != "" {
+		major, _, ok := parseMajorMinor(terraformVersion)
+		switch {
+		case !ok:
+			return &UnsupportedVersionError{Field: "terraform_version", Version: terraformVersion, Reason: "not a valid version"}
+		case major < supportedToolMajor:
+			return &UnsupportedVersionError{Field: "terraform_version", Version: terraformVersion, Reason: "driftwatch requires Terraform >= 1.0.0"}
+		case major > supportedToolMajor:
+			return &UnsupportedVersionError{Field: "terraform_version", Version: terraformVersion, Reason: fmt.Sprintf("unknown major version; driftwatch supports Terraform and OpenTofu %d.x", supportedToolMajor)}
+		}
+	}
+
+	return nil
+}
+
+// parseMajorMinor extracts the numeric major and minor components of a
+// version string such as "1.2", "1.9.0" or "v1.10.0-beta1".
+func parseMajorMinor(version string) (major, minor int, ok bool) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	digits := parts[1]
+	if i := strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		digits = digits[:i]
+	}
+	minor, err = strconv.Atoi(digits)
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// rawConditionResult mirrors a condition_results entry emitted by
+// Terraform 1.2–1.3 before check results were stabilised as "checks".
+type rawConditionResult struct {
+	Address      string `json:"address"`
+	Type         string `json:"condition_type"`
+	Result       bool   `json:"result"`
+	Unknown      bool   `json:"unknown"`
+	ErrorMessage string `json:"error_message"`
+}
+
+// checksFromConditionResults converts legacy condition_results into CheckResults
+// so older plans report failing conditions the same way as Terraform 1.5+.
+func checksFromConditionResults(results []rawConditionResult) []CheckResult {
+	checks := make([]CheckResult, 0, len(results))
+	for _, cr := range results {
+		check := CheckResult{
+			Address: cr.Address,
+			Kind:    cr.Type,
+			Status:  CheckPass,
+		}
+		switch {
+		case cr.Unknown:
+			check.Status = CheckUnknown
+		case !cr.Result:
+			check.Status = CheckFail
+			if cr.ErrorMessage != "" {
+				check.Problems = []string{cr.ErrorMessage}
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
@@ -0,0 +1,140 @@
+package parser_test
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/daemonship/driftwatch/internal/parser"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// TestParse_GoldenPlans parses plan fixtures produced by each supported
+// Terraform/OpenTofu generation and compares a stable dump to testdata/*.golden.
+// Run `go test ./internal/parser -update` to regenerate the golden files.
+func TestParse_GoldenPlans(t *testing.T) {
+	fixtures := []string{
+		"terraform-1.0",
+		"terraform-1.3",
+		"terraform-1.5",
+		"terraform-1.9",
+		"opentofu-1.8",
+	}
+	for _, name := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join("testdata", name+".json"))
+			if err != nil {
+				t.Fatalf("reading fixture: %v", err)
+			}
+			plan, err := parser.Parse(input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got := dumpPlan(plan)
+
+			goldenPath := filepath.Join("testdata", name+".golden")
+			if *update {
+				if err := os.WriteFile(goldenPath, []byte(got), 0644); err != nil {
+					t.Fatalf("writing golden file: %v", err)
+				}
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("reading golden file: %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("parsed plan does not match %s\n--- got ---\n%s\n--- want ---\n%s", goldenPath, got, want)
+			}
+		})
+	}
+}
+
+func TestParse_UnsupportedFormatMajor(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("testdata", "unsupported-format-2.0.json"))
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+	_, err = parser.Parse(input)
+	var verr *parser.UnsupportedVersionError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Parse() error = %v, want *UnsupportedVersionError", err)
+	}
+	if verr.Field != "format_version" || verr.Version != "2.0" {
+		t.Errorf("UnsupportedVersionError = %+v, want format_version 2.0", verr)
+	}
+	if !strings.Contains(err.Error(), "unknown major version") {
+		t.Errorf("error %q does not explain the unknown major version", err)
+	}
+}
+
+func TestParse_VersionCompatibility(t *testing.T) {
+	tests := []struct {
+		name             string
+		formatVersion    string
+		terraformVersion string
+		wantErr          bool
+	}{
+		{"terraform 1.0", "0.2", "1.0.11", false},
+		{"terraform 1.1", "1.0", "1.1.9", false},
+		{"terraform 1.10 prerelease", "1.2", "1.10.0-beta1", false},
+		{"opentofu 1.8", "1.2", "1.8.5", false},
+		{"future minor format", "1.9", "1.14.0", false},
+		{"terraform 0.12 format", "0.1", "", true},
+		{"terraform 0.14", "", "0.14.11", true},
+		{"unknown tool major", "1.2", "2.0.0", true},
+		{"unknown format major", "3.0", "", true},
+		{"garbage format", "latest", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := fmt.Sprintf(`{"format_version":%q,"terraform_version":%q,"resource_changes":[]}`, tt.formatVersion, tt.terraformVersion)
+			_, err := parser.Parse([]byte(input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// dumpPlan renders plan in a stable, human-diffable form for golden files.
+func dumpPlan(plan *parser.Plan) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "format_version: %s\n", plan.FormatVersion)
+	fmt.Fprintf(&b, "terraform_version: %s\n", plan.TerraformVersion)
+	for _, rc := range plan.ResourceChanges {
+		fmt.Fprintf(&b, "change %s action=%s reason=%s\n", rc.Address, rc.Action, rc.Reason)
+		fmt.Fprintf(&b, "  [mode=%s type=%s name=%s module=%s provider=%s]\n", rc.Mode, rc.Type, rc.Name, rc.ModuleAddress, rc.ProviderName)
+		attrs := make([]string, 0, len(rc.AttributeChanges))
+		for k := range rc.AttributeChanges {
+			attrs = append(attrs, k)
+		}
+		sort.Strings(attrs)
+		for _, k := range attrs {
+			c := rc.AttributeChanges[k]
+			if c.AfterUnknown {
+				fmt.Fprintf(&b, "  %s: %v -> (known after apply)\n", k, c.Before)
+				continue
+			}
+			fmt.Fprintf(&b, "  %s: %v -> %v\n", k, c.Before, c.After)
+		}
+	}
+	for _, rc := range plan.DataSourceReads {
+		fmt.Fprintf(&b, "read %s reason=%s\n", rc.Address, rc.Reason)
+	}
+	for _, dc := range plan.DeferredChanges {
+		fmt.Fprintf(&b, "deferred %s action=%s reason=%s\n", dc.Address, dc.Action, dc.Reason)
+	}
+	for _, c := range plan.Checks {
+		fmt.Fprintf(&b, "check %s kind=%s status=%s\n", c.Address, c.Kind, c.Status)
+		for _, p := range c.Problems {
+			fmt.Fprintf(&b, "  problem: %s\n", p)
+		}
+	}
+	return b.String()
+}
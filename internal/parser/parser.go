@@ -3,8 +3,11 @@
 package parser
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"reflect"
 )
 
@@ -20,83 +23,355 @@ const (
 	ActionRead    Action = "read"
 )
 
+// CheckStatus is the aggregate status of a check block, precondition or postcondition.
+type CheckStatus string
+
+const (
+	CheckPass    CheckStatus = "pass"
+	CheckFail    CheckStatus = "fail"
+	CheckError   CheckStatus = "error"
+	CheckUnknown CheckStatus = "unknown"
+)
+
 // AttributeChange holds the before and after values for a single resource attribute.
+// Values keep their JSON types; numbers are decoded as json.Number so large
+// integers and IDs are not rounded through float64.
 type AttributeChange struct {
 	Before interface{}
 	After  interface{}
+	// Sensitive is true when Terraform marked the attribute (or any value
+	// nested in it) as sensitive before or after the change. Consumers must
+	// not display Before or After for sensitive attributes.
+	Sensitive bool
+	// AfterUnknown is true when Terraform marked the attribute (or any value
+	// nested in it) in after_unknown: the value is only known after apply
+	// and After is nil.
+	AfterUnknown bool
 }
 
 // ResourceChange describes a single resource that has drifted.
 type ResourceChange struct {
 	// Address is the fully-qualified resource address (e.g. "aws_instance.web").
 	Address string
+	// ModuleAddress is the address of the containing module ("" for the root module).
+	ModuleAddress string
+	// Mode is "managed" for resources and "data" for data sources.
+	Mode string
+	// Type is the resource type (e.g. "aws_instance").
+	Type string
+	// Name is the resource name from its declaring block (e.g. "web").
+	Name string
+	// ProviderName is the provider source address (e.g. "registry.terraform.io/hashicorp/aws").
+	ProviderName string
 	// Action is the planned change action.
 	Action Action
 	// AttributeChanges maps attribute name to its before/after values.
 	// Only attributes that differ between before and after are included.
 	AttributeChanges map[string]AttributeChange
+	// Reason is the action_reason reported by Terraform, if any
+	// (e.g. "read_because_config_unknown").
+	Reason string
+}
+
+// DeferredChange describes a resource change Terraform could not plan yet
+// (Terraform 1.10+ deferred_changes).
+type DeferredChange struct {
+	// Address is the fully-qualified resource address.
+	Address string
+	// Action is the change action Terraform expects once the change is planned.
+	Action Action
+	// Reason explains why the change was deferred (e.g. "provider_config_unknown").
+	Reason string
+}
+
+// CheckResult is the status of one checkable object (a check block or a
+// resource/output with pre- or postconditions) reported in the plan.
+type CheckResult struct {
+	// Address is the display address of the checkable object (e.g. "check.health").
+	Address string
+	// Kind is the checkable object kind ("resource", "output_value", "check", ...).
+	Kind string
+	// Status is the aggregate status across all instances of the object.
+	Status CheckStatus
+	// Problems holds the error messages reported by failing instances.
+	Problems []string
 }
 
 // Plan is the parsed result of terraform plan -json output.
+// Warnings and other diagnostics are not part of the plan document and
+// are not carried.
 type Plan struct {
 	// ResourceChanges lists resources with meaningful changes (non-no-op).
 	ResourceChanges []ResourceChange
 	// FormatVersion is the schema version reported by Terraform.
 	FormatVersion string
+	// TerraformVersion is the version of Terraform (or OpenTofu) that produced the plan.
+	TerraformVersion string
+	// DataSourceReads lists data sources that will be read during apply,
+	// usually because their configuration depends on unknown values.
+	// They are not drift and are kept separate from ResourceChanges.
+	DataSourceReads []ResourceChange
+	// DeferredChanges lists changes Terraform deferred to a later plan.
+	DeferredChanges []DeferredChange
+	// Checks lists check results (Terraform 1.5+), including passing ones.
+	Checks []CheckResult
 }
 
 // rawPlan mirrors the top-level terraform plan JSON schema.
+// resource_changes is streamed by ParseReader and has no field here.
 type rawPlan struct {
-	FormatVersion   string              `json:"format_version"`
-	ResourceChanges []rawResourceChange `json:"resource_changes"`
+	FormatVersion    string               `json:"format_version"`
+	TerraformVersion string               `json:"terraform_version"`
+	DeferredChanges  []rawDeferredChange  `json:"deferred_changes"`
+	Checks           []rawCheck           `json:"checks"`
+	ConditionResults []rawConditionResult `json:"condition_results"`
+}
+
+// rawCheck mirrors a single checks entry.
+type rawCheck struct {
+	Address   rawCheckAddress    `json:"address"`
+	Status    string             `json:"status"`
+	Instances []rawCheckInstance `json:"instances"`
+}
+
+// rawCheckAddress mirrors the address object of a checks entry.
+type rawCheckAddress struct {
+	Kind      string `json:"kind"`
+	ToDisplay string `json:"to_display"`
+}
+
+// rawCheckInstance mirrors a single instance of a checks entry.
+type rawCheckInstance struct {
+	Address  rawCheckAddress `json:"address"`
+	Status   string          `json:"status"`
+	Problems []struct {
+		Message string `json:"message"`
+	} `json:"problems"`
 }
 
 // rawResourceChange mirrors a single resource_changes entry.
 type rawResourceChange struct {
-	Address string    `json:"address"`
-	Change  rawChange `json:"change"`
+	Address       string    `json:"address"`
+	ModuleAddress string    `json:"module_address"`
+	Mode          string    `json:"mode"`
+	Type          string    `json:"type"`
+	Name          string    `json:"name"`
+	ProviderName  string    `json:"provider_name"`
+	Change        rawChange `json:"change"`
+	ActionReason  string    `json:"action_reason"`
+}
+
+// rawDeferredChange mirrors a single deferred_changes entry.
+type rawDeferredChange struct {
+	Reason         string            `json:"reason"`
+	ResourceChange rawResourceChange `json:"resource_change"`
 }
 
 // rawChange mirrors the change object within a resource_changes entry.
 type rawChange struct {
-	Actions []string               `json:"actions"`
-	Before  map[string]interface{} `json:"before"`
-	After   map[string]interface{} `json:"after"`
+	Actions         []string               `json:"actions"`
+	Before          map[string]interface{} `json:"before"`
+	After           map[string]interface{} `json:"after"`
+	AfterUnknown    interface{}            `json:"after_unknown"`
+	BeforeSensitive interface{}            `json:"before_sensitive"`
+	AfterSensitive  interface{}            `json:"after_sensitive"`
 }
 
 // Parse parses raw terraform plan JSON output and returns a Plan.
-// Returns an error if the JSON is malformed or missing required fields.
+// Returns an error if the JSON is malformed or missing required fields, or an
+// *UnsupportedVersionError if the plan's format_version or terraform_version
+// is not supported.
 func Parse(planJSON []byte) (*Plan, error) {
 	if len(planJSON) == 0 {
 		return nil, fmt.Errorf("empty plan JSON")
 	}
+	return ParseReader(bytes.NewReader(planJSON))
+}
+
+// ParseReader parses terraform plan JSON from r without holding the whole
+// document in memory. resource_changes entries are decoded one at a time and
+// no-op changes are discarded immediately; large sections driftwatch does not
+// use (planned_values, prior_state, configuration) are skipped token by token.
+// Errors match those returned by Parse.
+func ParseReader(r io.Reader) (*Plan, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty plan JSON")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing plan JSON: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("parsing plan JSON: expected object, found %v", tok)
+	}
 
 	var raw rawPlan
-	if err := json.Unmarshal(planJSON, &raw); err != nil {
+	plan := &Plan{ResourceChanges: []ResourceChange{}}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("parsing plan JSON: %w", err)
+		}
+		key, _ := tok.(string)
+
+		switch key {
+		case "format_version":
+			err = dec.Decode(&raw.FormatVersion)
+			if err == nil {
+				err = checkCompatibility(raw.FormatVersion, "")
+			}
+		case "terraform_version":
+			err = dec.Decode(&raw.TerraformVersion)
+			if err == nil {
+				err = checkCompatibility("", raw.TerraformVersion)
+			}
+		case "resource_changes":
+			err = decodeArray(dec, func() error {
+				var rc rawResourceChange
+				if err := dec.Decode(&rc); err != nil {
+					return err
+				}
+				plan.addResourceChange(rc)
+				return nil
+			})
+		case "deferred_changes":
+			err = dec.Decode(&raw.DeferredChanges)
+		case "checks":
+			err = dec.Decode(&raw.Checks)
+		case "condition_results":
+			err = dec.Decode(&raw.ConditionResults)
+		default:
+			err = skipValue(dec)
+		}
+		if err != nil {
+			var verr *UnsupportedVersionError
+			if errors.As(err, &verr) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("parsing plan JSON: %w", err)
+		}
+	}
+	if _, err := dec.Token(); err != nil {
 		return nil, fmt.Errorf("parsing plan JSON: %w", err)
 	}
-
-	plan := &Plan{
-		FormatVersion:   raw.FormatVersion,
-		ResourceChanges: make([]ResourceChange, 0, len(raw.ResourceChanges)),
+	// Like json.Unmarshal, reject anything after the top-level object
+	if tok, err := dec.Token(); err != io.EOF {
+		if err != nil {
+			return nil, fmt.Errorf("parsing plan JSON: %w", err)
+		}
+		return nil, fmt.Errorf("parsing plan JSON: unexpected %v after top-level value", tok)
 	}
 
-	for _, rc := range raw.ResourceChanges {
-		action := resolveAction(rc.Change.Actions)
-		if action == ActionNoOp || action == ActionRead {
-			continue
-		}
+	plan.FormatVersion = raw.FormatVersion
+	plan.TerraformVersion = raw.TerraformVersion
 
-		plan.ResourceChanges = append(plan.ResourceChanges, ResourceChange{
-			Address:          rc.Address,
-			Action:           action,
-			AttributeChanges: diffAttributes(rc.Change.Before, rc.Change.After),
+	for _, dc := range raw.DeferredChanges {
+		plan.DeferredChanges = append(plan.DeferredChanges, DeferredChange{
+			Address: dc.ResourceChange.Address,
+			Action:  resolveAction(dc.ResourceChange.Change.Actions),
+			Reason:  dc.Reason,
 		})
 	}
 
+	for _, c := range raw.Checks {
+		check := CheckResult{
+			Address: c.Address.ToDisplay,
+			Kind:    c.Address.Kind,
+			Status:  CheckStatus(c.Status),
+		}
+		for _, inst := range c.Instances {
+			for _, p := range inst.Problems {
+				check.Problems = append(check.Problems, p.Message)
+			}
+		}
+		plan.Checks = append(plan.Checks, check)
+	}
+	if len(raw.Checks) == 0 && len(raw.ConditionResults) > 0 {
+		plan.Checks = checksFromConditionResults(raw.ConditionResults)
+	}
+
 	return plan, nil
 }
 
+// addResourceChange records rc in the plan, routing data source reads to
+// DataSourceReads and dropping no-op changes.
+func (p *Plan) addResourceChange(rc rawResourceChange) {
+	action := resolveAction(rc.Change.Actions)
+	switch action {
+	case ActionNoOp:
+		return
+	case ActionRead:
+		change := rc.toResourceChange(action)
+		p.DataSourceReads = append(p.DataSourceReads, change)
+		return
+	}
+
+	change := rc.toResourceChange(action)
+	change.AttributeChanges = diffAttributes(rc.Change.Before, rc.Change.After, rc.Change.AfterUnknown)
+	markSensitive(change.AttributeChanges, rc.Change.BeforeSensitive, rc.Change.AfterSensitive)
+	p.ResourceChanges = append(p.ResourceChanges, change)
+}
+
+// toResourceChange copies the identifying fields of rc into a ResourceChange.
+func (rc rawResourceChange) toResourceChange(action Action) ResourceChange {
+	return ResourceChange{
+		Address:       rc.Address,
+		ModuleAddress: rc.ModuleAddress,
+		Mode:          rc.Mode,
+		Type:          rc.Type,
+		Name:          rc.Name,
+		ProviderName:  rc.ProviderName,
+		Action:        action,
+		Reason:        rc.ActionReason,
+	}
+}
+
+// decodeArray consumes a JSON array (or null) from dec, calling each once per
+// element with the decoder positioned at the element.
+func decodeArray(dec *json.Decoder, each func() error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected array, found %v", tok)
+	}
+	for dec.More() {
+		if err := each(); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
+
+// skipValue consumes the next JSON value from dec without retaining it.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
 // resolveAction maps the actions array from terraform plan JSON to an Action.
 // A ["delete", "create"] pair indicates a replace operation.
 func resolveAction(actions []string) Action {
@@ -121,8 +396,9 @@ func resolveAction(actions []string) Action {
 }
 
 // diffAttributes returns only the attributes whose values differ between before and after.
-// Attributes present in one map but absent in the other are included.
-func diffAttributes(before, after map[string]interface{}) map[string]AttributeChange {
+// Attributes present in one map but absent in the other are included, as are
+// attributes the afterUnknown mask marks as known only after apply.
+func diffAttributes(before, after map[string]interface{}, afterUnknown interface{}) map[string]AttributeChange {
 	changes := make(map[string]AttributeChange)
 
 	keys := make(map[string]struct{}, len(before)+len(after))
@@ -132,10 +408,19 @@ func diffAttributes(before, after map[string]interface{}) map[string]AttributeCh
 	for k := range after {
 		keys[k] = struct{}{}
 	}
+	if mask, ok := afterUnknown.(map[string]interface{}); ok {
+		for k := range mask {
+			keys[k] = struct{}{}
+		}
+	}
 
 	for k := range keys {
 		bVal := before[k]
 		aVal := after[k]
+		if maskCovers(afterUnknown, k) {
+			changes[k] = AttributeChange{Before: bVal, AfterUnknown: true}
+			continue
+		}
 		if !reflect.DeepEqual(bVal, aVal) {
 			changes[k] = AttributeChange{Before: bVal, After: aVal}
 		}
@@ -143,3 +428,52 @@ func diffAttributes(before, after map[string]interface{}) map[string]AttributeCh
 
 	return changes
 }
+
+// markSensitive flags attributes covered by the before_sensitive or
+// after_sensitive masks. A mask is either true (everything is sensitive) or an
+// object mirroring the resource's attributes with true at sensitive paths.
+func markSensitive(changes map[string]AttributeChange, masks ...interface{}) {
+	for name, change := range changes {
+		for _, mask := range masks {
+			if maskCovers(mask, name) {
+				change.Sensitive = true
+				changes[name] = change
+				break
+			}
+		}
+	}
+}
+
+// maskCovers reports whether mask, a before_sensitive, after_sensitive or
+// after_unknown value, marks attribute name or any value nested within it.
+func maskCovers(mask interface{}, name string) bool {
+	switch m := mask.(type) {
+	case bool:
+		return m
+	case map[string]interface{}:
+		return containsTrue(m[name])
+	default:
+		return false
+	}
+}
+
+// containsTrue reports whether v is true or a collection containing true.
+func containsTrue(v interface{}) bool {
+	switch val := v.(type) {
+	case bool:
+		return val
+	case map[string]interface{}:
+		for _, e := range val {
+			if containsTrue(e) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, e := range val {
+			if containsTrue(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
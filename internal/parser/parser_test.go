@@ -1,6 +1,7 @@
 package parser_test
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/daemonship/driftwatch/internal/parser"
@@ -269,3 +270,226 @@ func TestParse_OnlyDifferentAttributesReturned(t *testing.T) {
 		t.Errorf("AttributeChanges count = %d, want 2 (only changed attributes)", len(rc.AttributeChanges))
 	}
 }
+
+// Plan with a data source read caused by unknown configuration and a deferred change.
+const planWithReadAndDeferred = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {
+      "address": "data.aws_ami.latest",
+      "mode": "data",
+      "type": "aws_ami",
+      "name": "latest",
+      "action_reason": "read_because_config_unknown",
+      "change": {
+        "actions": ["read"],
+        "before": null,
+        "after": {"most_recent": true},
+        "after_unknown": {"id": true}
+      }
+    }
+  ],
+  "deferred_changes": [
+    {
+      "reason": "provider_config_unknown",
+      "resource_change": {
+        "address": "kubernetes_namespace.apps",
+        "change": {
+          "actions": ["create"],
+          "before": null,
+          "after": {"metadata": []}
+        }
+      }
+    }
+  ]
+}`
+
+func TestParse_DataSourceReadsKeptSeparate(t *testing.T) {
+	plan, err := parser.Parse([]byte(planWithReadAndDeferred))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(plan.ResourceChanges) != 0 {
+		t.Errorf("ResourceChanges count = %d, want 0 (reads are not drift)", len(plan.ResourceChanges))
+	}
+	if len(plan.DataSourceReads) != 1 {
+		t.Fatalf("DataSourceReads count = %d, want 1", len(plan.DataSourceReads))
+	}
+	read := plan.DataSourceReads[0]
+	if read.Address != "data.aws_ami.latest" {
+		t.Errorf("DataSourceReads[0].Address = %q, want %q", read.Address, "data.aws_ami.latest")
+	}
+	if read.Reason != "read_because_config_unknown" {
+		t.Errorf("DataSourceReads[0].Reason = %q, want %q", read.Reason, "read_because_config_unknown")
+	}
+}
+
+func TestParse_DeferredChanges(t *testing.T) {
+	plan, err := parser.Parse([]byte(planWithReadAndDeferred))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(plan.DeferredChanges) != 1 {
+		t.Fatalf("DeferredChanges count = %d, want 1", len(plan.DeferredChanges))
+	}
+	dc := plan.DeferredChanges[0]
+	if dc.Address != "kubernetes_namespace.apps" {
+		t.Errorf("DeferredChanges[0].Address = %q, want %q", dc.Address, "kubernetes_namespace.apps")
+	}
+	if dc.Action != parser.ActionCreate {
+		t.Errorf("DeferredChanges[0].Action = %q, want %q", dc.Action, parser.ActionCreate)
+	}
+	if dc.Reason != "provider_config_unknown" {
+		t.Errorf("DeferredChanges[0].Reason = %q, want %q", dc.Reason, "provider_config_unknown")
+	}
+}
+
+// Plan with one passing and one failing check block.
+const planWithChecks = `{
+  "format_version": "1.2",
+  "resource_changes": [],
+  "checks": [
+    {
+      "address": {"kind": "check", "name": "health", "to_display": "check.health"},
+      "status": "fail",
+      "instances": [
+        {
+          "address": {"kind": "check", "to_display": "check.health"},
+          "status": "fail",
+          "problems": [{"message": "health endpoint returned 503"}]
+        }
+      ]
+    },
+    {
+      "address": {"kind": "resource", "mode": "managed", "type": "aws_instance", "name": "web", "to_display": "aws_instance.web"},
+      "status": "pass",
+      "instances": [
+        {"address": {"to_display": "aws_instance.web"}, "status": "pass"}
+      ]
+    }
+  ]
+}`
+
+func TestParse_Checks(t *testing.T) {
+	plan, err := parser.Parse([]byte(planWithChecks))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(plan.Checks) != 2 {
+		t.Fatalf("Checks count = %d, want 2", len(plan.Checks))
+	}
+	failed := plan.Checks[0]
+	if failed.Address != "check.health" {
+		t.Errorf("Checks[0].Address = %q, want %q", failed.Address, "check.health")
+	}
+	if failed.Kind != "check" {
+		t.Errorf("Checks[0].Kind = %q, want %q", failed.Kind, "check")
+	}
+	if failed.Status != parser.CheckFail {
+		t.Errorf("Checks[0].Status = %q, want %q", failed.Status, parser.CheckFail)
+	}
+	if len(failed.Problems) != 1 || failed.Problems[0] != "health endpoint returned 503" {
+		t.Errorf("Checks[0].Problems = %v, want [health endpoint returned 503]", failed.Problems)
+	}
+	if plan.Checks[1].Status != parser.CheckPass {
+		t.Errorf("Checks[1].Status = %q, want %q", plan.Checks[1].Status, parser.CheckPass)
+	}
+}
+
+func TestParse_NumbersKeepPrecision(t *testing.T) {
+	input := `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {
+      "address": "aws_instance.web",
+      "change": {
+        "actions": ["update"],
+        "before": {"owner_id": 123456789012},
+        "after": {"owner_id": 123456789013}
+      }
+    }
+  ]
+}`
+	plan, err := parser.Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	change := plan.ResourceChanges[0].AttributeChanges["owner_id"]
+	before, ok := change.Before.(json.Number)
+	if !ok {
+		t.Fatalf("owner_id Before type = %T, want json.Number", change.Before)
+	}
+	if before.String() != "123456789012" {
+		t.Errorf("owner_id Before = %s, want 123456789012", before)
+	}
+}
+
+func TestParse_AfterUnknown(t *testing.T) {
+	input := `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {
+      "address": "aws_instance.web",
+      "change": {
+        "actions": ["delete", "create"],
+        "before": {"ami": "ami-old", "id": "i-123", "arn": "arn:aws:ec2:i-123", "tags": {"env": "prod"}},
+        "after": {"ami": "ami-new", "tags": {"env": "prod"}},
+        "after_unknown": {"id": true, "arn": true, "private_ip": true, "tags": {}},
+        "after_sensitive": {"arn": true}
+      }
+    }
+  ]
+}`
+	plan, err := parser.Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	attrs := plan.ResourceChanges[0].AttributeChanges
+	if id := attrs["id"]; !id.AfterUnknown || id.Before != "i-123" || id.After != nil {
+		t.Errorf("id = %+v, want i-123 -> known after apply", id)
+	}
+	if ip, ok := attrs["private_ip"]; !ok || !ip.AfterUnknown || ip.Before != nil {
+		t.Errorf("private_ip = %+v, want null -> known after apply", ip)
+	}
+	if arn := attrs["arn"]; !arn.AfterUnknown || !arn.Sensitive {
+		t.Errorf("arn = %+v, want unknown and sensitive", arn)
+	}
+	if attrs["ami"].AfterUnknown {
+		t.Error("ami AfterUnknown = true, want false")
+	}
+	if _, ok := attrs["tags"]; ok {
+		t.Error("tags included, want unchanged attributes without unknown values left out")
+	}
+}
+
+func TestParse_SensitiveAttributes(t *testing.T) {
+	input := `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {
+      "address": "aws_db_instance.main",
+      "change": {
+        "actions": ["update"],
+        "before": {"password": "old-secret", "tags": {"env": "prod", "token": "t1"}, "port": 5432},
+        "after": {"password": "new-secret", "tags": {"env": "prod", "token": "t2"}, "port": 5433},
+        "before_sensitive": {"password": true, "tags": {"token": true}},
+        "after_sensitive": {"password": true}
+      }
+    }
+  ]
+}`
+	plan, err := parser.Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	attrs := plan.ResourceChanges[0].AttributeChanges
+	if !attrs["password"].Sensitive {
+		t.Error("password Sensitive = false, want true")
+	}
+	if !attrs["tags"].Sensitive {
+		t.Error("tags Sensitive = false, want true (nested sensitive value)")
+	}
+	if attrs["port"].Sensitive {
+		t.Error("port Sensitive = true, want false")
+	}
+}
@@ -0,0 +1,165 @@
+package parser_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/daemonship/driftwatch/internal/parser"
+)
+
+func TestParseReader_MatchesParse(t *testing.T) {
+	for _, input := range []string{planWithOneUpdate, planWithReplace, planWithReadAndDeferred, planWithChecks} {
+		want, err := parser.Parse([]byte(input))
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		got, err := parser.ParseReader(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("ParseReader() error = %v", err)
+		}
+		if dumpPlan(got) != dumpPlan(want) {
+			t.Errorf("ParseReader() = %s, want %s", dumpPlan(got), dumpPlan(want))
+		}
+	}
+}
+
+func TestParseReader_SkipsUnusedSections(t *testing.T) {
+	input := `{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {"resources": [{"address": "aws_instance.web", "values": {"tags": {"a": [1, 2, {"b": null}]}}}]}},
+  "prior_state": {"values": {}},
+  "resource_changes": [
+    {"address": "aws_instance.web", "change": {"actions": ["delete"], "before": {"ami": "ami-1"}, "after": null}}
+  ],
+  "configuration": {"root_module": {}},
+  "timestamp": "2024-01-01T00:00:00Z"
+}`
+	plan, err := parser.ParseReader(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseReader() error = %v", err)
+	}
+	if len(plan.ResourceChanges) != 1 || plan.ResourceChanges[0].Action != parser.ActionDelete {
+		t.Errorf("ResourceChanges = %+v, want one delete", plan.ResourceChanges)
+	}
+}
+
+func TestParseReader_Errors(t *testing.T) {
+	tests := map[string]string{
+		"empty":              ``,
+		"not an object":      `[1, 2]`,
+		"truncated":          `{"format_version": "1.2", "resource_changes": [{"address": "a"`,
+		"bad resource array": `{"resource_changes": {"address": "a"}}`,
+		"trailing data":      `{"format_version": "1.2"} {"format_version": "1.2"}`,
+		"trailing garbage":   `{"format_version": "1.2"} x`,
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parser.ParseReader(strings.NewReader(input)); err == nil {
+				t.Error("ParseReader() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestParseReader_NullResourceChanges(t *testing.T) {
+	plan, err := parser.ParseReader(strings.NewReader(`{"format_version":"1.2","resource_changes":null}`))
+	if err != nil {
+		t.Fatalf("ParseReader() error = %v", err)
+	}
+	if len(plan.ResourceChanges) != 0 {
+		t.Errorf("ResourceChanges count = %d, want 0", len(plan.ResourceChanges))
+	}
+}
+
+// syntheticPlan is an io.Reader that generates a plan JSON document with n
+// resource changes on the fly, so benchmarks can feed very large plans to the
+// parser without allocating them up front. Every 1000th change is an update;
+// the rest are no-ops. It samples heap usage as it goes.
+type syntheticPlan struct {
+	n, next  int
+	buf      bytes.Buffer
+	done     bool
+	emitted  int64
+	peakHeap uint64
+}
+
+func newSyntheticPlan(n int) *syntheticPlan {
+	p := &syntheticPlan{n: n}
+	p.buf.WriteString(`{"format_version":"1.2","terraform_version":"1.9.8","planned_values":{"root_module":{}},"resource_changes":[`)
+	return p
+}
+
+func (p *syntheticPlan) Read(b []byte) (int, error) {
+	for p.buf.Len() < len(b) && !p.done {
+		p.fill()
+	}
+	n, err := p.buf.Read(b)
+	p.emitted += int64(n)
+	if err == io.EOF && !p.done {
+		err = nil
+	}
+	return n, err
+}
+
+func (p *syntheticPlan) fill() {
+	if p.next == p.n {
+		p.buf.WriteString(`]}`)
+		p.done = true
+		return
+	}
+	if p.next > 0 {
+		p.buf.WriteByte(',')
+	}
+	actions, after := `["no-op"]`, "t3.micro"
+	if p.next%1000 == 0 {
+		actions, after = `["update"]`, "t3.large"
+	}
+	fmt.Fprintf(&p.buf, `{"address":"aws_instance.node[%d]","mode":"managed","type":"aws_instance","name":"node","index":%d,`+
+		`"provider_name":"registry.terraform.io/hashicorp/aws","change":{"actions":%s,`+
+		`"before":{"ami":"ami-0abc123","instance_type":"t3.micro","subnet_id":"subnet-0123456789abcdef0","tags":{"Name":"node-%d","Team":"platform"}},`+
+		`"after":{"ami":"ami-0abc123","instance_type":%q,"subnet_id":"subnet-0123456789abcdef0","tags":{"Name":"node-%d","Team":"platform"}},`+
+		`"after_unknown":{}}}`, p.next, p.next, actions, p.next, after, p.next)
+	p.next++
+
+	if p.next%1000 == 0 {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		if ms.HeapInuse > p.peakHeap {
+			p.peakHeap = ms.HeapInuse
+		}
+	}
+}
+
+// BenchmarkParseReader_LargePlan streams synthetic plans of increasing size.
+// Peak heap usage should stay roughly flat as input size grows, since no-op
+// changes are discarded as they are decoded.
+func BenchmarkParseReader_LargePlan(b *testing.B) {
+	for _, n := range []int{10_000, 100_000, 250_000} {
+		b.Run(fmt.Sprintf("resources=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			var peak uint64
+			var emitted int64
+			for i := 0; i < b.N; i++ {
+				src := newSyntheticPlan(n)
+				plan, err := parser.ParseReader(src)
+				if err != nil {
+					b.Fatalf("ParseReader() error = %v", err)
+				}
+				if want := (n + 999) / 1000; len(plan.ResourceChanges) != want {
+					b.Fatalf("ResourceChanges count = %d, want %d", len(plan.ResourceChanges), want)
+				}
+				if src.peakHeap > peak {
+					peak = src.peakHeap
+				}
+				emitted = src.emitted
+			}
+			b.SetBytes(emitted)
+			b.ReportMetric(float64(emitted)/(1<<20), "input-MB")
+			b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+		})
+	}
+}
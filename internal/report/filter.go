@@ -0,0 +1,265 @@
+package report
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// filterKeys are the resource fields a Filter can match, mapped to accessors.
+// The workspace key matches whole results; the others match resource changes.
+var filterKeys = map[string]func(workspace string, rc ResourceChange) string{
+	"workspace": func(ws string, _ ResourceChange) string { return ws },
+	"action":    func(_ string, rc ResourceChange) string { return rc.Action },
+	"type":      func(_ string, rc ResourceChange) string { return rc.Type },
+	"address":   func(_ string, rc ResourceChange) string { return rc.Address },
+	"module":    func(_ string, rc ResourceChange) string { return rc.ModuleAddress },
+	"provider":  func(_ string, rc ResourceChange) string { return rc.ProviderName },
+}
+
+// groupKeys maps --group-by names to filter keys.
+var groupKeys = map[string]string{
+	"workspace":     "workspace",
+	"module":        "module",
+	"provider":      "provider",
+	"resource-type": "type",
+	"action":        "action",
+}
+
+// sortKeys maps --sort names to the resource field sorted on; "workspace"
+// and "drift" order workspaces instead of resources.
+var sortKeys = map[string]string{
+	"workspace": "",
+	"drift":     "",
+	"address":   "address",
+	"action":    "action",
+	"type":      "type",
+	"module":    "module",
+	"provider":  "provider",
+}
+
+// Filter selects resource changes whose Key field matches a glob Pattern,
+// in which * matches any run of characters. Negate inverts the match.
+type Filter struct {
+	Key     string
+	Pattern string
+	Negate  bool
+
+	re *regexp.Regexp
+}
+
+// ParseFilter parses a filter expression of the form key=glob or key!=glob,
+// e.g. "action=delete" or "type=aws_security_group*". Keys are workspace,
+// action, type, address, module and provider.
+func ParseFilter(expr string) (Filter, error) {
+	key, pattern, ok := strings.Cut(expr, "=")
+	if !ok {
+		return Filter{}, fmt.Errorf("filter %q: want key=pattern or key!=pattern", expr)
+	}
+	f := Filter{Key: key, Pattern: pattern}
+	if k, found := strings.CutSuffix(key, "!"); found {
+		f.Key, f.Negate = k, true
+	}
+	if _, known := filterKeys[f.Key]; !known {
+		return Filter{}, fmt.Errorf("filter %q: unknown key %q (want %s)", expr, f.Key, strings.Join(sortedNames(filterKeys), ", "))
+	}
+	f.re = globRegexp(pattern)
+	return f, nil
+}
+
+// globRegexp compiles a glob in which * matches any run of characters and
+// ? matches one character.
+func globRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// match reports whether rc in workspace ws satisfies f.
+func (f Filter) match(ws string, rc ResourceChange) bool {
+	re := f.re
+	if re == nil {
+		re = globRegexp(f.Pattern)
+	}
+	return re.MatchString(filterKeys[f.Key](ws, rc)) != f.Negate
+}
+
+// FilterResults returns results narrowed to the resource changes matching
+// every filter. Workspace filters drop whole results; other filters drop
+// resource changes, so a workspace whose drift is filtered out reports clean.
+// Scan errors, check failures and informational findings are kept for every
+// workspace that passes the workspace filters. The returned slice and its
+// ResourceChanges are copies, even without filters, so sorting them leaves
+// results untouched.
+func FilterResults(results []ScanResult, filters []Filter) []ScanResult {
+	out := make([]ScanResult, 0, len(results))
+	if len(filters) == 0 {
+		for _, r := range results {
+			r.ResourceChanges = slices.Clone(r.ResourceChanges)
+			out = append(out, r)
+		}
+		return out
+	}
+	for _, r := range results {
+		if !matchWorkspace(r.WorkspacePath, filters) {
+			continue
+		}
+		var kept []ResourceChange
+		for _, rc := range r.ResourceChanges {
+			if matchAll(r.WorkspacePath, rc, filters) {
+				kept = append(kept, rc)
+			}
+		}
+		r.ResourceChanges = kept
+		out = append(out, r)
+	}
+	return out
+}
+
+// matchWorkspace reports whether ws passes the workspace filters.
+func matchWorkspace(ws string, filters []Filter) bool {
+	for _, f := range filters {
+		if f.Key == "workspace" && !f.match(ws, ResourceChange{}) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAll reports whether rc passes every non-workspace filter.
+func matchAll(ws string, rc ResourceChange, filters []Filter) bool {
+	for _, f := range filters {
+		if f.Key != "workspace" && !f.match(ws, rc) {
+			return false
+		}
+	}
+	return true
+}
+
+// SortResults orders results in place by key: "workspace" sorts workspaces by
+// path, "drift" puts the workspaces with the most drifted resources first, and
+// address, action, type, module and provider sort each workspace's resource
+// changes by that field (then by address). An empty key keeps scan order.
+func SortResults(results []ScanResult, key string) error {
+	if key == "" {
+		return nil
+	}
+	field, ok := sortKeys[key]
+	if !ok {
+		return fmt.Errorf("unknown sort key %q (want %s)", key, strings.Join(sortedNames(sortKeys), ", "))
+	}
+	switch key {
+	case "workspace":
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].WorkspacePath < results[j].WorkspacePath
+		})
+	case "drift":
+		sort.SliceStable(results, func(i, j int) bool {
+			return len(results[i].ResourceChanges) > len(results[j].ResourceChanges)
+		})
+	default:
+		get := filterKeys[field]
+		for _, r := range results {
+			rcs := r.ResourceChanges
+			sort.SliceStable(rcs, func(i, j int) bool {
+				a, b := get(r.WorkspacePath, rcs[i]), get(r.WorkspacePath, rcs[j])
+				if a != b {
+					return a < b
+				}
+				return rcs[i].Address < rcs[j].Address
+			})
+		}
+	}
+	return nil
+}
+
+// GroupResults splits results into one ScanResult per workspace and group,
+// where key is workspace, module, provider, resource-type or action. Each
+// result's Group is set to a label such as "action: delete"; results are
+// ordered by group, then by their original order. Workspaces without drift
+// keep a single result with an empty Group, after all groups. Scan errors,
+// stderr, duration, check failures and informational findings stay on the
+// first result of each workspace so Summarize does not count them twice.
+// Grouping by workspace returns results unchanged.
+func GroupResults(results []ScanResult, key string) ([]ScanResult, error) {
+	field, ok := groupKeys[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown group key %q (want %s)", key, strings.Join(sortedNames(groupKeys), ", "))
+	}
+	if field == "workspace" {
+		return results, nil
+	}
+	get := filterKeys[field]
+
+	groups := make(map[string][]ScanResult)
+	var ungrouped []ScanResult
+	for _, r := range results {
+		if len(r.ResourceChanges) == 0 {
+			ungrouped = append(ungrouped, r)
+			continue
+		}
+		var values []string
+		byValue := make(map[string][]ResourceChange)
+		for _, rc := range r.ResourceChanges {
+			v := get(r.WorkspacePath, rc)
+			if _, seen := byValue[v]; !seen {
+				values = append(values, v)
+			}
+			byValue[v] = append(byValue[v], rc)
+		}
+		sort.Strings(values)
+		for i, v := range values {
+			gr := ScanResult{
+				WorkspacePath:    r.WorkspacePath,
+				ResourceChanges:  byValue[v],
+				TerraformVersion: r.TerraformVersion,
+				Group:            groupLabel(key, v),
+			}
+			if i == 0 {
+				gr.Err, gr.Stderr, gr.Duration = r.Err, r.Stderr, r.Duration
+				gr.CheckFailures, gr.Informational = r.CheckFailures, r.Informational
+			}
+			groups[gr.Group] = append(groups[gr.Group], gr)
+		}
+	}
+
+	out := make([]ScanResult, 0, len(results))
+	for _, label := range sortedNames(groups) {
+		out = append(out, groups[label]...)
+	}
+	return append(out, ungrouped...), nil
+}
+
+// groupLabel returns the display label of a group, e.g. "module: (root)".
+func groupLabel(key, value string) string {
+	if value == "" {
+		value = "(none)"
+		if key == "module" {
+			value = "(root)"
+		}
+	}
+	return key + ": " + value
+}
+
+// sortedNames returns the keys of m in sorted order.
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
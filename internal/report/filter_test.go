@@ -0,0 +1,182 @@
+package report_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+func groupingResults() []report.ScanResult {
+	return []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			ResourceChanges: []report.ResourceChange{
+				{Address: "aws_security_group.web", Action: "update", Type: "aws_security_group", ProviderName: "registry.terraform.io/hashicorp/aws"},
+				{Address: "module.net.aws_vpc.main", Action: "delete", Type: "aws_vpc", ModuleAddress: "module.net", ProviderName: "registry.terraform.io/hashicorp/aws"},
+				{Address: "google_storage_bucket.logs", Action: "delete", Type: "google_storage_bucket", ProviderName: "registry.terraform.io/hashicorp/google"},
+			},
+			CheckFailures: []report.CheckFailure{{Address: "check.health", Status: "fail"}},
+		},
+		{
+			WorkspacePath: "./infra/production",
+			ResourceChanges: []report.ResourceChange{
+				{Address: "aws_security_group_rule.ssh", Action: "create", Type: "aws_security_group_rule", ProviderName: "registry.terraform.io/hashicorp/aws"},
+			},
+		},
+		{WorkspacePath: "./infra/dns"},
+		{WorkspacePath: "./infra/broken", Err: errors.New("plan failed")},
+	}
+}
+
+func mustFilter(t *testing.T, expr string) report.Filter {
+	t.Helper()
+	f, err := report.ParseFilter(expr)
+	if err != nil {
+		t.Fatalf("ParseFilter(%q) error = %v", expr, err)
+	}
+	return f
+}
+
+func addresses(r report.ScanResult) []string {
+	var out []string
+	for _, rc := range r.ResourceChanges {
+		out = append(out, rc.Address)
+	}
+	return out
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	for _, expr := range []string{"action", "colour=red", "=delete"} {
+		if _, err := report.ParseFilter(expr); err == nil {
+			t.Errorf("ParseFilter(%q) error = nil, want error", expr)
+		}
+	}
+}
+
+func TestFilterResults_ActionAndGlob(t *testing.T) {
+	filters := []report.Filter{mustFilter(t, "action=delete"), mustFilter(t, "type=aws_*")}
+	results := report.FilterResults(groupingResults(), filters)
+
+	if len(results) != 4 {
+		t.Fatalf("FilterResults() returned %d results, want 4 (resource filters keep workspaces)", len(results))
+	}
+	if got := addresses(results[0]); len(got) != 1 || got[0] != "module.net.aws_vpc.main" {
+		t.Errorf("staging resources = %v, want [module.net.aws_vpc.main]", got)
+	}
+	if results[1].Status() != report.StatusClean {
+		t.Errorf("production status = %q, want clean once its drift is filtered out", results[1].Status())
+	}
+
+	summary := report.Summarize(results)
+	if summary.TotalDriftedResources != 1 || summary.WorkspacesWithDrift != 1 {
+		t.Errorf("Summarize() = %+v, want 1 drifted resource in 1 workspace", summary)
+	}
+}
+
+func TestFilterResults_NegatedWorkspace(t *testing.T) {
+	results := report.FilterResults(groupingResults(), []report.Filter{mustFilter(t, "workspace!=./infra/b*")})
+	for _, r := range results {
+		if r.WorkspacePath == "./infra/broken" {
+			t.Errorf("FilterResults() kept excluded workspace %q", r.WorkspacePath)
+		}
+	}
+	if len(results) != 3 {
+		t.Errorf("FilterResults() returned %d results, want 3", len(results))
+	}
+}
+
+func TestFilterResults_SortingLeavesInputUntouched(t *testing.T) {
+	for _, filters := range [][]report.Filter{nil, {mustFilter(t, "type=*")}} {
+		results := groupingResults()
+		filtered := report.FilterResults(results, filters)
+		if err := report.SortResults(filtered, "workspace"); err != nil {
+			t.Fatalf("SortResults() error = %v", err)
+		}
+		if err := report.SortResults(filtered, "address"); err != nil {
+			t.Fatalf("SortResults() error = %v", err)
+		}
+		if !reflect.DeepEqual(results, groupingResults()) {
+			t.Errorf("with %d filter(s), sorting the filtered results reordered the input", len(filters))
+		}
+	}
+}
+
+func TestSortResults_ResourcesByAction(t *testing.T) {
+	results := groupingResults()
+	if err := report.SortResults(results, "action"); err != nil {
+		t.Fatalf("SortResults() error = %v", err)
+	}
+	want := []string{"google_storage_bucket.logs", "module.net.aws_vpc.main", "aws_security_group.web"}
+	got := addresses(results[0])
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sorted resources = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSortResults_Drift(t *testing.T) {
+	results := groupingResults()
+	if err := report.SortResults(results, "drift"); err != nil {
+		t.Fatalf("SortResults() error = %v", err)
+	}
+	if results[0].WorkspacePath != "./infra/staging" || results[1].WorkspacePath != "./infra/production" {
+		t.Errorf("SortResults(drift) order = %s, %s; want staging, production", results[0].WorkspacePath, results[1].WorkspacePath)
+	}
+}
+
+func TestSortResults_UnknownKey(t *testing.T) {
+	if err := report.SortResults(groupingResults(), "colour"); err == nil {
+		t.Error("SortResults() error = nil, want error for unknown key")
+	}
+}
+
+func TestGroupResults_ByAction(t *testing.T) {
+	results, err := report.GroupResults(groupingResults(), "action")
+	if err != nil {
+		t.Fatalf("GroupResults() error = %v", err)
+	}
+
+	var groups []string
+	for _, r := range results {
+		groups = append(groups, r.Group+" "+r.WorkspacePath)
+	}
+	want := []string{
+		"action: create ./infra/production",
+		"action: delete ./infra/staging",
+		"action: update ./infra/staging",
+		" ./infra/dns",
+		" ./infra/broken",
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("GroupResults() groups = %q, want %q", groups, want)
+	}
+	for i := range want {
+		if groups[i] != want[i] {
+			t.Fatalf("GroupResults() groups = %q, want %q", groups, want)
+		}
+	}
+
+	// Splitting a workspace must not change the summary.
+	if got, want := report.Summarize(results), report.Summarize(groupingResults()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Summarize(grouped) = %+v, want %+v", got, want)
+	}
+}
+
+func TestGroupResults_RootModuleLabel(t *testing.T) {
+	results, err := report.GroupResults(groupingResults(), "module")
+	if err != nil {
+		t.Fatalf("GroupResults() error = %v", err)
+	}
+	if results[0].Group != "module: (root)" {
+		t.Errorf("first group = %q, want %q", results[0].Group, "module: (root)")
+	}
+}
+
+func TestGroupResults_UnknownKey(t *testing.T) {
+	if _, err := report.GroupResults(groupingResults(), "colour"); err == nil {
+		t.Error("GroupResults() error = nil, want error for unknown key")
+	}
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/daemonship/driftwatch/internal/source"
+)
+
+// Formatter writes scan results in a single output format.
+type Formatter interface {
+	Format(w io.Writer, results []ScanResult, meta Metadata) error
+}
+
+// FormatterFunc adapts an ordinary function to the Formatter interface.
+type FormatterFunc func(w io.Writer, results []ScanResult, meta Metadata) error
+
+// Format calls f(w, results, meta).
+func (f FormatterFunc) Format(w io.Writer, results []ScanResult, meta Metadata) error {
+	return f(w, results, meta)
+}
+
+// formatters holds registered formatters by name.
+var formatters = make(map[string]Formatter)
+
+// Register makes a formatter available under name. It panics if name is
+// already registered or f is nil, since that is a programming error.
+func Register(name string, f Formatter) {
+	if f == nil {
+		panic("report: Register formatter is nil")
+	}
+	if _, dup := formatters[name]; dup {
+		panic(fmt.Sprintf("report: Register called twice for formatter %q", name))
+	}
+	formatters[name] = f
+}
+
+// Lookup returns the formatter registered under name.
+func Lookup(name string) (Formatter, bool) {
+	f, ok := formatters[name]
+	return f, ok
+}
+
+// Formats returns the names of all registered formatters, sorted.
+func Formats() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register("text", TextFormatter{})
+	Register("json", FormatterFunc(WriteJSON))
+	Register("junit", FormatterFunc(WriteJUnit))
+	Register("sarif", FormatterFunc(func(w io.Writer, results []ScanResult, meta Metadata) error {
+		return WriteSARIF(w, results, meta, source.NewLocator())
+	}))
+	Register("markdown", FormatterFunc(func(w io.Writer, results []ScanResult, _ Metadata) error {
+		return WriteMarkdown(w, results)
+	}))
+	Register("html", FormatterFunc(WriteHTML))
+}
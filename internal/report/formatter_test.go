@@ -0,0 +1,72 @@
+package report_test
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"testing"
+
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+func TestFormats_BuiltinsRegistered(t *testing.T) {
+	got := report.Formats()
+	if !sort.StringsAreSorted(got) {
+		t.Errorf("Formats() = %v, want sorted names", got)
+	}
+	registered := make(map[string]bool, len(got))
+	for _, name := range got {
+		registered[name] = true
+	}
+	for _, name := range []string{"html", "json", "junit", "markdown", "sarif", "text"} {
+		if !registered[name] {
+			t.Errorf("Formats() = %v, missing built-in %q", got, name)
+		}
+	}
+}
+
+func TestLookup_WritesOutput(t *testing.T) {
+	for _, name := range report.Formats() {
+		f, ok := report.Lookup(name)
+		if !ok {
+			t.Fatalf("Lookup(%q) ok = false", name)
+		}
+		var buf bytes.Buffer
+		if err := f.Format(&buf, driftResults(), testMeta); err != nil {
+			t.Errorf("%s Format() error = %v", name, err)
+		}
+		if buf.Len() == 0 {
+			t.Errorf("%s Format() wrote no output", name)
+		}
+	}
+}
+
+func TestLookup_Unknown(t *testing.T) {
+	if _, ok := report.Lookup("yaml"); ok {
+		t.Error("Lookup(\"yaml\") ok = true, want false")
+	}
+}
+
+func TestRegister_CustomFormatter(t *testing.T) {
+	report.Register("test-count", report.FormatterFunc(func(w io.Writer, results []report.ScanResult, _ report.Metadata) error {
+		_, err := io.WriteString(w, "ok")
+		return err
+	}))
+	f, ok := report.Lookup("test-count")
+	if !ok {
+		t.Fatal("Lookup() did not find registered formatter")
+	}
+	var buf bytes.Buffer
+	if err := f.Format(&buf, nil, report.Metadata{}); err != nil || buf.String() != "ok" {
+		t.Errorf("Format() = %q, %v; want \"ok\", nil", buf.String(), err)
+	}
+}
+
+func TestRegister_DuplicatePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Register() did not panic on duplicate name")
+		}
+	}()
+	report.Register("text", report.FormatterFunc(func(io.Writer, []report.ScanResult, report.Metadata) error { return nil }))
+}
@@ -0,0 +1,69 @@
+package report
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"time"
+)
+
+//go:embed templates/report.html.tmpl templates/report.css templates/report.js
+var htmlAssets embed.FS
+
+// htmlTemplate is parsed once from the embedded report template.
+var htmlTemplate = template.Must(template.ParseFS(htmlAssets, "templates/report.html.tmpl"))
+
+// htmlData is the data passed to the HTML report template.
+type htmlData struct {
+	CSS         template.CSS
+	JS          template.JS
+	GeneratedAt string
+	Version     string
+	Summary     Summary
+	Workspaces  []workspaceView
+	// Static leaves out the script and filter controls and shows every
+	// resource expanded.
+	Static bool
+}
+
+// WriteHTML writes a self-contained HTML drift report to w: a summary
+// dashboard, a filterable list of workspaces and resources with color-coded
+// actions, and expandable attribute diffs. CSS and JavaScript are inlined so
+// the file can be emailed or archived on its own. All values are escaped by
+// html/template, and sensitive values are redacted as in Print.
+func WriteHTML(w io.Writer, results []ScanResult, meta Metadata) error {
+	return writeHTML(w, results, meta, false)
+}
+
+// WriteStaticHTML writes the HTML report of WriteHTML without JavaScript or
+// filter controls, with every resource's attribute diff shown, for email
+// bodies and other places where scripts do not run.
+func WriteStaticHTML(w io.Writer, results []ScanResult, meta Metadata) error {
+	return writeHTML(w, results, meta, true)
+}
+
+// writeHTML writes the HTML report, static or with its script.
+func writeHTML(w io.Writer, results []ScanResult, meta Metadata, static bool) error {
+	css, err := htmlAssets.ReadFile("templates/report.css")
+	if err != nil {
+		return err
+	}
+	data := htmlData{
+		CSS:        template.CSS(css),
+		Version:    meta.DriftwatchVersion,
+		Summary:    Summarize(results),
+		Workspaces: buildView(results),
+		Static:     static,
+	}
+	if !static {
+		js, err := htmlAssets.ReadFile("templates/report.js")
+		if err != nil {
+			return err
+		}
+		data.JS = template.JS(js)
+	}
+	if !meta.GeneratedAt.IsZero() {
+		data.GeneratedAt = meta.GeneratedAt.UTC().Format(time.RFC1123)
+	}
+	return htmlTemplate.Execute(w, data)
+}
@@ -0,0 +1,128 @@
+package report_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+func writeHTML(t *testing.T, results []report.ScanResult) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := report.WriteHTML(&buf, results, testMeta); err != nil {
+		t.Fatalf("WriteHTML() error = %v", err)
+	}
+	return buf.String()
+}
+
+func TestWriteHTML_SelfContained(t *testing.T) {
+	out := writeHTML(t, jsonFixtureResults())
+	if !strings.HasPrefix(out, "<!DOCTYPE html>") {
+		t.Errorf("WriteHTML() output does not start with a doctype")
+	}
+	if !strings.Contains(out, "<style>") || !strings.Contains(out, "<script>") {
+		t.Error("WriteHTML() output does not inline CSS and JS")
+	}
+	for _, external := range []string{`<link`, `src="http`, `href="http`} {
+		if strings.Contains(out, external) {
+			t.Errorf("WriteHTML() output references external asset %q", external)
+		}
+	}
+}
+
+func TestWriteHTML_ContainsResults(t *testing.T) {
+	out := writeHTML(t, jsonFixtureResults())
+	for _, want := range []string{
+		"./infra/staging",
+		"./infra/production",
+		`data-action="update"`,
+		"aws_instance.web",
+		"t3.micro",
+		"check.health",
+		"No valid credential sources found",
+		`class="badge error"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteHTML() output missing %q", want)
+		}
+	}
+}
+
+func TestWriteHTML_EscapesValues(t *testing.T) {
+	results := []report.ScanResult{
+		{
+			WorkspacePath: "./infra/<b>staging</b>",
+			ResourceChanges: []report.ResourceChange{
+				{
+					Address: "aws_instance.web",
+					Action:  "update",
+					Attributes: map[string]report.AttributeChange{
+						"user_data": {Before: "<script>alert(1)</script>", After: "ok"},
+					},
+				},
+			},
+		},
+	}
+	out := writeHTML(t, results)
+	if strings.Contains(out, "<script>alert(1)</script>") || strings.Contains(out, "<b>staging</b>") {
+		t.Errorf("WriteHTML() did not escape values:\n%s", out)
+	}
+	if !strings.Contains(out, "&lt;script&gt;alert(1)&lt;/script&gt;") {
+		t.Error("WriteHTML() output missing escaped value")
+	}
+}
+
+func TestWriteHTML_RedactsSensitiveValues(t *testing.T) {
+	results := []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			ResourceChanges: []report.ResourceChange{
+				{
+					Address: "aws_db_instance.main",
+					Action:  "update",
+					Attributes: map[string]report.AttributeChange{
+						"password": {Before: "hunter2", After: "hunter3", Sensitive: true},
+					},
+				},
+			},
+		},
+	}
+	out := writeHTML(t, results)
+	if strings.Contains(out, "hunter2") {
+		t.Error("WriteHTML() leaked sensitive value")
+	}
+	if !strings.Contains(out, "(sensitive value)") {
+		t.Error("WriteHTML() missing redaction marker")
+	}
+}
+
+func TestWriteHTML_KnownAfterApply(t *testing.T) {
+	out := writeHTML(t, unknownResults())
+	if !strings.Contains(out, `<td class="unknown"><pre>(known after apply)</pre></td>`) {
+		t.Error("WriteHTML() missing known after apply marker")
+	}
+	if strings.Contains(out, "hunter2") {
+		t.Error("WriteHTML() leaked sensitive value")
+	}
+}
+
+func TestWriteStaticHTML_NoScript(t *testing.T) {
+	var buf bytes.Buffer
+	if err := report.WriteStaticHTML(&buf, jsonFixtureResults(), testMeta); err != nil {
+		t.Fatalf("WriteStaticHTML() error = %v", err)
+	}
+	out := buf.String()
+	for _, unwanted := range []string{"<script", "<input", `id="filter-text"`} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("WriteStaticHTML() output contains %q", unwanted)
+		}
+	}
+	if !strings.Contains(out, "<style>") || !strings.Contains(out, "aws_instance.web") || !strings.Contains(out, "<details class=\"resource\"") {
+		t.Errorf("WriteStaticHTML() output lacks the styled report:\n%s", out)
+	}
+	if strings.Count(out, "<details class=\"resource\"") != strings.Count(out, " open>") {
+		t.Error("WriteStaticHTML() output has collapsed resources")
+	}
+}
@@ -0,0 +1,211 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// JSONSchemaVersion is the version of the JSON report schema written by
+// WriteJSON. It changes only on backwards-incompatible changes; the schema
+// itself is published at schema/driftwatch-scan.v1.schema.json.
+const JSONSchemaVersion = "1"
+
+// Metadata describes the scan run itself, for machine-readable formats.
+type Metadata struct {
+	// DriftwatchVersion is the version of the driftwatch binary.
+	DriftwatchVersion string
+	// GeneratedAt is when the report was produced.
+	GeneratedAt time.Time
+}
+
+// jsonReport is the top-level document written by WriteJSON.
+type jsonReport struct {
+	SchemaVersion     string       `json:"schema_version"`
+	DriftwatchVersion string       `json:"driftwatch_version"`
+	GeneratedAt       string       `json:"generated_at"`
+	Summary           jsonSummary  `json:"summary"`
+	Results           []jsonResult `json:"results"`
+}
+
+type jsonSummary struct {
+	WorkspacesScanned     int `json:"workspaces_scanned"`
+	WorkspacesWithDrift   int `json:"workspaces_with_drift"`
+	TotalDriftedResources int `json:"total_drifted_resources"`
+	ScanErrors            int `json:"scan_errors"`
+	InformationalFindings int `json:"informational_findings"`
+	FailedChecks          int `json:"failed_checks"`
+
+	ByAction        map[string]int      `json:"by_action"`
+	ByProvider      map[string]int      `json:"by_provider"`
+	ByResourceType  map[string]int      `json:"by_resource_type"`
+	MostDrifted     []jsonWorkspaceStat `json:"most_drifted"`
+	TotalDurationMS int64               `json:"total_duration_ms"`
+	Slowest         []jsonWorkspaceStat `json:"slowest"`
+}
+
+type jsonWorkspaceStat struct {
+	Workspace        string `json:"workspace"`
+	DriftedResources int    `json:"drifted_resources"`
+	DurationMS       int64  `json:"duration_ms"`
+}
+
+type jsonResult struct {
+	Workspace        string               `json:"workspace"`
+	Status           string               `json:"status"`
+	TerraformVersion string               `json:"terraform_version"`
+	DurationMS       int64                `json:"duration_ms"`
+	Error            *jsonError           `json:"error"`
+	ResourceChanges  []jsonResourceChange `json:"resource_changes"`
+	CheckFailures    []jsonCheckFailure   `json:"check_failures"`
+	Informational    []jsonFinding        `json:"informational"`
+}
+
+type jsonError struct {
+	Message string `json:"message"`
+	Stderr  string `json:"stderr"`
+}
+
+type jsonResourceChange struct {
+	Address    string                         `json:"address"`
+	Action     string                         `json:"action"`
+	Attributes map[string]jsonAttributeChange `json:"attributes"`
+}
+
+type jsonAttributeChange struct {
+	Before       interface{} `json:"before"`
+	After        interface{} `json:"after"`
+	Sensitive    bool        `json:"sensitive,omitempty"`
+	AfterUnknown bool        `json:"after_unknown,omitempty"`
+}
+
+type jsonCheckFailure struct {
+	Address  string   `json:"address"`
+	Status   string   `json:"status"`
+	Problems []string `json:"problems"`
+}
+
+type jsonFinding struct {
+	Address string `json:"address"`
+	Kind    string `json:"kind"`
+	Reason  string `json:"reason"`
+}
+
+// WriteJSON writes results and their summary to w as an indented JSON
+// document following schema version JSONSchemaVersion. Attribute values keep
+// the types decoded from the plan; sensitive values are written as null with
+// "sensitive": true, and values only known after apply have a null after
+// with "after_unknown": true.
+func WriteJSON(w io.Writer, results []ScanResult, meta Metadata) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newJSONReport(results, meta))
+}
+
+// JSONResults returns results as they appear in the "results" array of the
+// JSON report, decoded into maps and slices keyed by the report's field
+// names: sensitive values are redacted and errors are strings. Templates can
+// embed them without exposing what the reports hide.
+func JSONResults(results []ScanResult) ([]interface{}, error) {
+	data, err := json.Marshal(newJSONReport(results, Metadata{}).Results)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out []interface{}
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// newJSONReport builds the document written by WriteJSON.
+func newJSONReport(results []ScanResult, meta Metadata) jsonReport {
+	summary := Summarize(results)
+	doc := jsonReport{
+		SchemaVersion:     JSONSchemaVersion,
+		DriftwatchVersion: meta.DriftwatchVersion,
+		GeneratedAt:       meta.GeneratedAt.UTC().Format(time.RFC3339),
+		Summary: jsonSummary{
+			WorkspacesScanned:     summary.WorkspacesScanned,
+			WorkspacesWithDrift:   summary.WorkspacesWithDrift,
+			TotalDriftedResources: summary.TotalDriftedResources,
+			ScanErrors:            summary.ScanErrors,
+			InformationalFindings: summary.InformationalFindings,
+			FailedChecks:          summary.FailedChecks,
+			ByAction:              summary.ByAction,
+			ByProvider:            summary.ByProvider,
+			ByResourceType:        summary.ByResourceType,
+			MostDrifted:           jsonWorkspaceStats(summary.MostDrifted),
+			TotalDurationMS:       summary.TotalDuration.Milliseconds(),
+			Slowest:               jsonWorkspaceStats(summary.Slowest),
+		},
+		Results: make([]jsonResult, 0, len(results)),
+	}
+
+	for _, r := range results {
+		jr := jsonResult{
+			Workspace:        r.WorkspacePath,
+			Status:           r.Status(),
+			TerraformVersion: r.TerraformVersion,
+			DurationMS:       r.Duration.Milliseconds(),
+			ResourceChanges:  make([]jsonResourceChange, 0, len(r.ResourceChanges)),
+			CheckFailures:    make([]jsonCheckFailure, 0, len(r.CheckFailures)),
+			Informational:    make([]jsonFinding, 0, len(r.Informational)),
+		}
+		if r.Err != nil {
+			jr.Error = &jsonError{Message: r.Err.Error(), Stderr: r.Stderr}
+		}
+		for _, rc := range r.ResourceChanges {
+			attrs := make(map[string]jsonAttributeChange, len(rc.Attributes))
+			for name, c := range rc.Attributes {
+				if c.Sensitive {
+					attrs[name] = jsonAttributeChange{Sensitive: true, AfterUnknown: c.AfterUnknown}
+					continue
+				}
+				attrs[name] = jsonAttributeChange{Before: c.Before, After: c.After, AfterUnknown: c.AfterUnknown}
+			}
+			jr.ResourceChanges = append(jr.ResourceChanges, jsonResourceChange{
+				Address:    rc.Address,
+				Action:     rc.Action,
+				Attributes: attrs,
+			})
+		}
+		for _, c := range r.CheckFailures {
+			problems := c.Problems
+			if problems == nil {
+				problems = []string{}
+			}
+			jr.CheckFailures = append(jr.CheckFailures, jsonCheckFailure{
+				Address:  c.Address,
+				Status:   c.Status,
+				Problems: problems,
+			})
+		}
+		for _, f := range r.Informational {
+			jr.Informational = append(jr.Informational, jsonFinding{
+				Address: f.Address,
+				Kind:    f.Kind,
+				Reason:  f.Reason,
+			})
+		}
+		doc.Results = append(doc.Results, jr)
+	}
+	return doc
+}
+
+// jsonWorkspaceStats converts summary rankings, never returning nil so the
+// arrays are always present in the output.
+func jsonWorkspaceStats(stats []WorkspaceStat) []jsonWorkspaceStat {
+	out := make([]jsonWorkspaceStat, 0, len(stats))
+	for _, s := range stats {
+		out = append(out, jsonWorkspaceStat{
+			Workspace:        s.WorkspacePath,
+			DriftedResources: s.DriftedResources,
+			DurationMS:       s.Duration.Milliseconds(),
+		})
+	}
+	return out
+}
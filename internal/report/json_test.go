@@ -0,0 +1,284 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+var testMeta = report.Metadata{
+	DriftwatchVersion: "0.2.0",
+	GeneratedAt:       time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+// jsonFixtureResults covers every status and optional section of the schema.
+func jsonFixtureResults() []report.ScanResult {
+	return []report.ScanResult{
+		{
+			WorkspacePath:    "./infra/staging",
+			TerraformVersion: "1.9.8",
+			Duration:         1500 * time.Millisecond,
+			ResourceChanges: []report.ResourceChange{
+				{
+					Address:      "aws_instance.web",
+					Action:       "update",
+					Type:         "aws_instance",
+					Name:         "web",
+					ProviderName: "registry.terraform.io/hashicorp/aws",
+					Attributes: map[string]report.AttributeChange{
+						"instance_type": {Before: "t2.micro", After: "t3.micro"},
+						"owner_id":      {Before: json.Number("123456789012"), After: json.Number("123456789013")},
+						"monitoring":    {Before: false, After: true},
+						"tags":          {Before: map[string]interface{}{"Name": "web"}, After: nil},
+					},
+				},
+			},
+			CheckFailures: []report.CheckFailure{
+				{Address: "check.health", Status: "fail", Problems: []string{"health endpoint returned 503"}},
+			},
+			Informational: []report.Finding{
+				{Address: "data.aws_ami.latest", Kind: report.FindingDataSourceRead, Reason: "read_because_config_unknown"},
+			},
+		},
+		{WorkspacePath: "./infra/production", TerraformVersion: "1.9.8", Duration: 900 * time.Millisecond},
+		{
+			WorkspacePath: "./infra/legacy",
+			Duration:      20 * time.Millisecond,
+			Err:           errors.New("running terraform plan in ./infra/legacy: exit status 1"),
+			Stderr:        "Error: No valid credential sources found\n",
+		},
+	}
+}
+
+func TestWriteJSON_Golden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf, jsonFixtureResults(), testMeta); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+
+	goldenPath := filepath.Join("testdata", "scan.v1.golden.json")
+	if *update {
+		if err := os.WriteFile(goldenPath, buf.Bytes(), 0644); err != nil {
+			t.Fatalf("writing golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("reading golden file: %v", err)
+	}
+	if buf.String() != string(want) {
+		t.Errorf("WriteJSON() output changed; if intentional and backwards compatible, run with -update.\n--- got ---\n%s\n--- want ---\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteJSON_MatchesPublishedSchema(t *testing.T) {
+	schemaData, err := os.ReadFile(filepath.Join("..", "..", "schema", "driftwatch-scan.v1.schema.json"))
+	if err != nil {
+		t.Fatalf("reading schema: %v", err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(schemaData, &schema); err != nil {
+		t.Fatalf("parsing schema: %v", err)
+	}
+
+	for name, results := range map[string][]report.ScanResult{
+		"fixture": jsonFixtureResults(),
+		"unknown": unknownResults(),
+		"empty":   nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := report.WriteJSON(&buf, results, testMeta); err != nil {
+				t.Fatalf("WriteJSON() error = %v", err)
+			}
+			var doc interface{}
+			dec := json.NewDecoder(&buf)
+			dec.UseNumber()
+			if err := dec.Decode(&doc); err != nil {
+				t.Fatalf("output is not valid JSON: %v", err)
+			}
+			for _, problem := range validate(schema, schema, doc, "$") {
+				t.Error(problem)
+			}
+		})
+	}
+}
+
+func TestWriteJSON_KeepsValueTypes(t *testing.T) {
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf, jsonFixtureResults(), testMeta); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `"before": 123456789012`) {
+		t.Errorf("number attribute not written as a JSON number:\n%s", out)
+	}
+	if !strings.Contains(out, `"after": true`) {
+		t.Errorf("bool attribute not written as a JSON bool:\n%s", out)
+	}
+	if !strings.Contains(out, `"stderr": "Error: No valid credential sources found\n"`) {
+		t.Errorf("error stderr missing from output:\n%s", out)
+	}
+}
+
+// validate checks doc against the subset of JSON Schema used by the published
+// schema (type, enum, required, properties, additionalProperties, items, oneOf
+// and local $ref) and returns a description of each violation.
+func validate(root, schema map[string]interface{}, doc interface{}, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		def := strings.TrimPrefix(ref, "#/$defs/")
+		defs, _ := root["$defs"].(map[string]interface{})
+		target, ok := defs[def].(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: unresolved $ref %q", path, ref)}
+		}
+		return validate(root, target, doc, path)
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, s := range oneOf {
+			if len(validate(root, s.(map[string]interface{}), doc, path)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return []string{fmt.Sprintf("%s: matches %d oneOf branches, want 1", path, matches)}
+		}
+		return nil
+	}
+
+	var problems []string
+	if typ, ok := schema["type"].(string); ok && !hasJSONType(doc, typ) {
+		return []string{fmt.Sprintf("%s: value %v is not of type %s", path, doc, typ)}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if fmt.Sprint(e) == fmt.Sprint(doc) {
+				found = true
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: value %v not in enum %v", path, doc, enum))
+		}
+	}
+
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, r := range required {
+				if _, ok := v[r.(string)]; !ok {
+					problems = append(problems, fmt.Sprintf("%s: missing required property %q", path, r))
+				}
+			}
+		}
+		for key, val := range v {
+			if ps, ok := props[key].(map[string]interface{}); ok {
+				problems = append(problems, validate(root, ps, val, path+"."+key)...)
+				continue
+			}
+			switch ap := schema["additionalProperties"].(type) {
+			case bool:
+				if !ap {
+					problems = append(problems, fmt.Sprintf("%s: property %q is not in the schema", path, key))
+				}
+			case map[string]interface{}:
+				problems = append(problems, validate(root, ap, val, path+"."+key)...)
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				problems = append(problems, validate(root, items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return problems
+}
+
+func hasJSONType(v interface{}, typ string) bool {
+	switch typ {
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	case "null":
+		return v == nil
+	}
+	return true
+}
+
+func TestWriteJSON_AfterUnknown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf, unknownResults(), testMeta); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var doc struct {
+		Results []struct {
+			ResourceChanges []struct {
+				Attributes map[string]map[string]interface{} `json:"attributes"`
+			} `json:"resource_changes"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteJSON() output is not valid JSON: %v", err)
+	}
+	attrs := doc.Results[0].ResourceChanges[0].Attributes
+	if id := attrs["id"]; id["before"] != "i-123" || id["after"] != nil || id["after_unknown"] != true {
+		t.Errorf("id = %v, want before i-123, after null and after_unknown", id)
+	}
+	if pw := attrs["password"]; pw["before"] != nil || pw["sensitive"] != true || pw["after_unknown"] != true {
+		t.Errorf("password = %v, want redacted, sensitive and after_unknown", pw)
+	}
+}
+
+func TestWriteJSON_RedactsSensitiveValues(t *testing.T) {
+	results := []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			ResourceChanges: []report.ResourceChange{
+				{
+					Address: "aws_db_instance.main",
+					Action:  "update",
+					Attributes: map[string]report.AttributeChange{
+						"password": {Before: "hunter2", After: "hunter3", Sensitive: true},
+					},
+				},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf, results, testMeta); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "hunter") {
+		t.Errorf("WriteJSON() leaked sensitive value:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"sensitive": true`) {
+		t.Errorf("WriteJSON() did not flag sensitive attribute:\n%s", buf.String())
+	}
+}
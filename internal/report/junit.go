@@ -0,0 +1,156 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// junitTestSuites is the root element of a JUnit XML report.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite holds the test cases for one workspace.
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+// junitProblem is the body of a <failure> or <error> element.
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes results to w as a JUnit XML report. Each workspace is a
+// testsuite; each drifted resource and failing health check is a failing
+// testcase, a scan error is an <error> testcase carrying terraform's stderr,
+// and a clean workspace has a single passing "no drift" testcase.
+func WriteJUnit(w io.Writer, results []ScanResult, meta Metadata) error {
+	doc := junitTestSuites{Name: "driftwatch"}
+	var total time.Duration
+
+	for _, r := range results {
+		suite := junitTestSuite{
+			Name: r.WorkspacePath,
+			Time: junitSeconds(r.Duration),
+		}
+		if !meta.GeneratedAt.IsZero() {
+			suite.Timestamp = meta.GeneratedAt.UTC().Format("2006-01-02T15:04:05")
+		}
+		if r.TerraformVersion != "" {
+			suite.Properties = append(suite.Properties, junitProperty{Name: "terraform_version", Value: r.TerraformVersion})
+		}
+
+		switch {
+		case r.Err != nil:
+			suite.Errors++
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      "terraform plan",
+				ClassName: r.WorkspacePath,
+				Time:      junitSeconds(r.Duration),
+				Error: &junitProblem{
+					Message: r.Err.Error(),
+					Type:    "scan-error",
+					Body:    r.Stderr,
+				},
+			})
+		case len(r.ResourceChanges) == 0 && len(r.CheckFailures) == 0:
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      "no drift",
+				ClassName: r.WorkspacePath,
+				Time:      junitSeconds(r.Duration),
+			})
+		}
+
+		for _, rc := range r.ResourceChanges {
+			suite.Failures++
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      rc.Address,
+				ClassName: r.WorkspacePath,
+				Time:      junitSeconds(0),
+				Failure: &junitProblem{
+					Message: fmt.Sprintf("%s has drifted (action: %s)", rc.Address, rc.Action),
+					Type:    rc.Action,
+					Body:    junitAttributeDiff(rc.Attributes),
+				},
+			})
+		}
+		for _, c := range r.CheckFailures {
+			suite.Failures++
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      c.Address,
+				ClassName: r.WorkspacePath,
+				Time:      junitSeconds(0),
+				Failure: &junitProblem{
+					Message: fmt.Sprintf("health check %s (status: %s)", c.Address, c.Status),
+					Type:    "check-failure",
+					Body:    strings.Join(c.Problems, "\n"),
+				},
+			})
+		}
+
+		suite.Tests = len(suite.Cases)
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Errors += suite.Errors
+		total += r.Duration
+		doc.Suites = append(doc.Suites, suite)
+	}
+	doc.Time = junitSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitSeconds formats d as fractional seconds, as JUnit's time attributes expect.
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// junitAttributeDiff renders changed attributes one per line, sorted by name,
+// with sensitive values redacted.
+func junitAttributeDiff(attrs map[string]AttributeChange) string {
+	var b strings.Builder
+	for _, attr := range attributeViews(attrs) {
+		fmt.Fprintf(&b, "%s: %s -> %s\n", attr.Name, attr.Before, attr.After)
+	}
+	return b.String()
+}
@@ -0,0 +1,126 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+// junitDoc is a minimal view of a JUnit report for assertions.
+type junitDoc struct {
+	Tests    int `xml:"tests,attr"`
+	Failures int `xml:"failures,attr"`
+	Errors   int `xml:"errors,attr"`
+	Suites   []struct {
+		Name     string `xml:"name,attr"`
+		Tests    int    `xml:"tests,attr"`
+		Failures int    `xml:"failures,attr"`
+		Errors   int    `xml:"errors,attr"`
+		Cases    []struct {
+			Name    string `xml:"name,attr"`
+			Failure *struct {
+				Type string `xml:"type,attr"`
+				Body string `xml:",chardata"`
+			} `xml:"failure"`
+			Error *struct {
+				Message string `xml:"message,attr"`
+				Body    string `xml:",chardata"`
+			} `xml:"error"`
+		} `xml:"testcase"`
+	} `xml:"testsuite"`
+}
+
+func writeJUnitDoc(t *testing.T, results []report.ScanResult) (junitDoc, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := report.WriteJUnit(&buf, results, testMeta); err != nil {
+		t.Fatalf("WriteJUnit() error = %v", err)
+	}
+	var doc junitDoc
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteJUnit() produced invalid XML: %v\n%s", err, buf.String())
+	}
+	return doc, buf.String()
+}
+
+func TestWriteJUnit_SuitePerWorkspace(t *testing.T) {
+	doc, _ := writeJUnitDoc(t, jsonFixtureResults())
+	if len(doc.Suites) != 3 {
+		t.Fatalf("testsuite count = %d, want 3", len(doc.Suites))
+	}
+	if doc.Suites[0].Name != "./infra/staging" {
+		t.Errorf("testsuite[0] name = %q, want %q", doc.Suites[0].Name, "./infra/staging")
+	}
+	// staging: 1 drifted resource + 1 check failure; production: 1 passing case; legacy: 1 error.
+	if doc.Tests != 4 || doc.Failures != 2 || doc.Errors != 1 {
+		t.Errorf("totals tests=%d failures=%d errors=%d, want 4/2/1", doc.Tests, doc.Failures, doc.Errors)
+	}
+}
+
+func TestWriteJUnit_DriftedResourceIsFailure(t *testing.T) {
+	doc, _ := writeJUnitDoc(t, jsonFixtureResults())
+	tc := doc.Suites[0].Cases[0]
+	if tc.Name != "aws_instance.web" {
+		t.Errorf("testcase name = %q, want %q", tc.Name, "aws_instance.web")
+	}
+	if tc.Failure == nil {
+		t.Fatal("drifted resource testcase has no <failure>")
+	}
+	if tc.Failure.Type != "update" {
+		t.Errorf("failure type = %q, want %q", tc.Failure.Type, "update")
+	}
+	if !strings.Contains(tc.Failure.Body, "instance_type: t2.micro -> t3.micro") {
+		t.Errorf("failure body missing attribute diff: %q", tc.Failure.Body)
+	}
+}
+
+func TestWriteJUnit_ScanErrorIsError(t *testing.T) {
+	doc, _ := writeJUnitDoc(t, jsonFixtureResults())
+	legacy := doc.Suites[2]
+	if legacy.Errors != 1 || len(legacy.Cases) != 1 {
+		t.Fatalf("legacy suite errors=%d cases=%d, want 1/1", legacy.Errors, len(legacy.Cases))
+	}
+	tc := legacy.Cases[0]
+	if tc.Error == nil {
+		t.Fatal("scan error testcase has no <error>")
+	}
+	if !strings.Contains(tc.Error.Body, "No valid credential sources found") {
+		t.Errorf("error body missing stderr: %q", tc.Error.Body)
+	}
+}
+
+func TestWriteJUnit_CleanWorkspacePasses(t *testing.T) {
+	doc, _ := writeJUnitDoc(t, noDriftResults())
+	for _, s := range doc.Suites {
+		if s.Tests != 1 || s.Failures != 0 || s.Errors != 0 {
+			t.Errorf("suite %s tests=%d failures=%d errors=%d, want 1/0/0", s.Name, s.Tests, s.Failures, s.Errors)
+		}
+	}
+}
+
+func TestWriteJUnit_EscapesValues(t *testing.T) {
+	results := []report.ScanResult{
+		{
+			WorkspacePath: "./infra/<staging>",
+			ResourceChanges: []report.ResourceChange{
+				{
+					Address: `aws_iam_policy.p["a&b"]`,
+					Action:  "update",
+					Attributes: map[string]report.AttributeChange{
+						"policy": {Before: `{"Effect":"<Allow>"}`, After: `{"Effect":"Deny"}`},
+					},
+				},
+			},
+		},
+	}
+	doc, out := writeJUnitDoc(t, results)
+	if strings.Contains(out, "<staging>") || strings.Contains(out, "<Allow>") {
+		t.Errorf("WriteJUnit() did not escape markup in values:\n%s", out)
+	}
+	if doc.Suites[0].Cases[0].Name != `aws_iam_policy.p["a&b"]` {
+		t.Errorf("testcase name = %q, want round-trip of address", doc.Suites[0].Cases[0].Name)
+	}
+}
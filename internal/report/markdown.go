@@ -0,0 +1,165 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// markdownMaxValue is the maximum number of characters shown for an attribute value.
+	markdownMaxValue = 200
+	// markdownMaxStderr is the maximum number of characters of stderr shown for a scan error.
+	markdownMaxStderr = 4000
+)
+
+// markdownStatusIcons maps workspace statuses to the emoji shown in summaries.
+var markdownStatusIcons = map[string]string{
+	StatusClean:   "✅",
+	StatusDrifted: "⚠️",
+	StatusError:   "❌",
+}
+
+// WriteMarkdown writes a GitHub-flavoured Markdown report suitable for pull
+// request comments, issues and wiki pages: a summary table followed by one
+// collapsible <details> block per workspace with attribute diff tables.
+// Values are escaped and truncated, and sensitive values are redacted the
+// same way as in Print.
+func WriteMarkdown(w io.Writer, results []ScanResult) error {
+	summary := Summarize(results)
+	var b strings.Builder
+
+	b.WriteString("## Terraform Drift Report\n\n")
+	b.WriteString("| Workspaces scanned | With drift | Drifted resources | Scan errors |\n")
+	b.WriteString("|---:|---:|---:|---:|\n")
+	fmt.Fprintf(&b, "| %d | %d | %d | %d |\n\n",
+		summary.WorkspacesScanned, summary.WorkspacesWithDrift, summary.TotalDriftedResources, summary.ScanErrors)
+
+	group := ""
+	for _, v := range buildView(results) {
+		if v.Group != group {
+			group = v.Group
+			if group != "" {
+				fmt.Fprintf(&b, "### %s\n\n", html.EscapeString(group))
+			}
+		}
+		fmt.Fprintf(&b, "<details%s>\n", markdownOpenAttr(v))
+		fmt.Fprintf(&b, "<summary>%s <code>%s</code> — %s</summary>\n\n",
+			markdownStatusIcons[v.Status], html.EscapeString(v.Path), markdownStatusText(v))
+
+		if v.Err != nil {
+			fmt.Fprintf(&b, "**Error:** %s\n\n", markdownCode(v.Err.Error()))
+			if v.Stderr != "" {
+				writeMarkdownFence(&b, truncate(v.Stderr, markdownMaxStderr))
+			}
+		}
+
+		for _, rc := range v.Resources {
+			fmt.Fprintf(&b, "#### %s `%s`\n\n", markdownCode(rc.Address), rc.Action)
+			if len(rc.Attributes) == 0 {
+				b.WriteString("_No attribute changes recorded._\n\n")
+				continue
+			}
+			b.WriteString("| Attribute | Before | After |\n")
+			b.WriteString("|---|---|---|\n")
+			for _, attr := range rc.Attributes {
+				fmt.Fprintf(&b, "| %s | %s | %s |\n",
+					markdownCode(attr.Name), markdownValueCell(attr.Before, attr.Sensitive), markdownValueCell(attr.After, attr.Sensitive || attr.Unknown))
+			}
+			b.WriteString("\n")
+		}
+
+		if len(v.CheckFailures) > 0 {
+			b.WriteString("**Health check failures**\n\n")
+			for _, c := range v.CheckFailures {
+				fmt.Fprintf(&b, "- %s (%s)", markdownCode(c.Address), c.Status)
+				for _, p := range c.Problems {
+					fmt.Fprintf(&b, "<br>%s", markdownCode(p))
+				}
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+		}
+
+		if len(v.Informational) > 0 {
+			b.WriteString("**Informational (not drift)**\n\n")
+			for _, f := range v.Informational {
+				fmt.Fprintf(&b, "- %s: %s", f.Kind, markdownCode(f.Address))
+				if f.Reason != "" {
+					fmt.Fprintf(&b, " (%s)", markdownCode(f.Reason))
+				}
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+		}
+
+		b.WriteString("</details>\n\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownOpenAttr expands workspaces that need attention by default.
+func markdownOpenAttr(v workspaceView) string {
+	if v.Status == StatusClean {
+		return ""
+	}
+	return " open"
+}
+
+// markdownStatusText summarises a workspace for its <summary> line.
+func markdownStatusText(v workspaceView) string {
+	switch v.Status {
+	case StatusError:
+		return "scan error"
+	case StatusDrifted:
+		if len(v.Resources) == 1 {
+			return "1 drifted resource"
+		}
+		return fmt.Sprintf("%d drifted resources", len(v.Resources))
+	default:
+		return "no drift"
+	}
+}
+
+// markdownValueCell renders an attribute value as a table cell. Placeholders
+// such as redacted values are shown in italics rather than as code.
+func markdownValueCell(s string, placeholder bool) string {
+	if placeholder {
+		return "_" + s + "_"
+	}
+	return markdownCode(truncate(s, markdownMaxValue))
+}
+
+// markdownCode renders s as inline code. It uses an HTML <code> element so
+// that backticks, pipes and newlines in s cannot break tables or lists;
+// markdown is not interpreted inside it.
+func markdownCode(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, "|", "&#124;")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\n", "<br>")
+	return "<code>" + s + "</code>"
+}
+
+// writeMarkdownFence writes s in a fenced code block whose fence is longer
+// than any run of backticks in s.
+func writeMarkdownFence(b *strings.Builder, s string) {
+	fence := "```"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "%s\n%s\n%s\n\n", fence, strings.TrimRight(s, "\n"), fence)
+}
+
+// truncate shortens s to at most max characters, marking the cut with an ellipsis.
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max-1]) + "…"
+}
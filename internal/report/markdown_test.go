@@ -0,0 +1,139 @@
+package report_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+func writeMarkdown(t *testing.T, results []report.ScanResult) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := report.WriteMarkdown(&buf, results); err != nil {
+		t.Fatalf("WriteMarkdown() error = %v", err)
+	}
+	return buf.String()
+}
+
+func TestWriteMarkdown_SummaryTable(t *testing.T) {
+	out := writeMarkdown(t, jsonFixtureResults())
+	if !strings.Contains(out, "| 3 | 1 | 1 | 1 |") {
+		t.Errorf("WriteMarkdown() missing summary row:\n%s", out)
+	}
+}
+
+func TestWriteMarkdown_DetailsPerWorkspace(t *testing.T) {
+	out := writeMarkdown(t, jsonFixtureResults())
+	if n := strings.Count(out, "<details"); n != 3 {
+		t.Errorf("<details> count = %d, want 3", n)
+	}
+	if strings.Count(out, "<details") != strings.Count(out, "</details>") {
+		t.Errorf("unbalanced <details> blocks:\n%s", out)
+	}
+	if !strings.Contains(out, "<code>./infra/staging</code> — 1 drifted resource") {
+		t.Errorf("WriteMarkdown() missing drifted workspace summary:\n%s", out)
+	}
+	if !strings.Contains(out, "No valid credential sources found") {
+		t.Errorf("WriteMarkdown() missing scan error stderr:\n%s", out)
+	}
+}
+
+func TestWriteMarkdown_AttributeTableSorted(t *testing.T) {
+	out := writeMarkdown(t, jsonFixtureResults())
+	if !strings.Contains(out, "| <code>instance_type</code> | <code>t2.micro</code> | <code>t3.micro</code> |") {
+		t.Errorf("WriteMarkdown() missing attribute row:\n%s", out)
+	}
+	if strings.Index(out, "instance_type") > strings.Index(out, "owner_id") {
+		t.Errorf("attributes not sorted by name:\n%s", out)
+	}
+}
+
+func TestWriteMarkdown_EscapesAndTruncatesValues(t *testing.T) {
+	long := strings.Repeat("x", 500)
+	results := []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			ResourceChanges: []report.ResourceChange{
+				{
+					Address: "aws_iam_policy.p",
+					Action:  "update",
+					Attributes: map[string]report.AttributeChange{
+						"policy":      {Before: "a|b\n<script>`x`", After: "ok"},
+						"description": {Before: long, After: ""},
+					},
+				},
+			},
+		},
+	}
+	out := writeMarkdown(t, results)
+	if strings.Contains(out, "<script>") {
+		t.Errorf("WriteMarkdown() did not escape HTML:\n%s", out)
+	}
+	if !strings.Contains(out, "a&#124;b<br>&lt;script&gt;`x`") {
+		t.Errorf("WriteMarkdown() did not escape pipe/newline in table cell:\n%s", out)
+	}
+	if strings.Contains(out, long) || !strings.Contains(out, "…") {
+		t.Errorf("WriteMarkdown() did not truncate long value")
+	}
+}
+
+func TestWriteMarkdown_RedactsSensitiveValues(t *testing.T) {
+	results := []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			ResourceChanges: []report.ResourceChange{
+				{
+					Address: "aws_db_instance.main",
+					Action:  "update",
+					Attributes: map[string]report.AttributeChange{
+						"password": {Before: "hunter2", After: "hunter3", Sensitive: true},
+					},
+				},
+			},
+		},
+	}
+	out := writeMarkdown(t, results)
+	if strings.Contains(out, "hunter2") || strings.Contains(out, "hunter3") {
+		t.Errorf("WriteMarkdown() leaked sensitive value:\n%s", out)
+	}
+	if !strings.Contains(out, "(sensitive value)") {
+		t.Errorf("WriteMarkdown() missing redaction marker:\n%s", out)
+	}
+}
+
+func TestWriteMarkdown_KnownAfterApply(t *testing.T) {
+	out := writeMarkdown(t, unknownResults())
+	if !strings.Contains(out, "_(known after apply)_") {
+		t.Errorf("WriteMarkdown() missing known after apply marker:\n%s", out)
+	}
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("WriteMarkdown() leaked sensitive value:\n%s", out)
+	}
+}
+
+func TestPrint_RedactsSensitiveValues(t *testing.T) {
+	results := []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			ResourceChanges: []report.ResourceChange{
+				{
+					Address: "aws_db_instance.main",
+					Action:  "update",
+					Attributes: map[string]report.AttributeChange{
+						"password": {Before: "hunter2", After: "hunter3", Sensitive: true},
+					},
+				},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	report.Print(&buf, results)
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("Print() leaked sensitive value:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "(sensitive value)") {
+		t.Errorf("Print() missing redaction marker:\n%s", buf.String())
+	}
+}
@@ -2,9 +2,12 @@
 package report
 
 import (
+	"encoding/json"
 	"fmt"
-	"io"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/daemonship/driftwatch/internal/parser"
 	"github.com/daemonship/driftwatch/internal/runner"
@@ -18,6 +21,67 @@ type ScanResult struct {
 	ResourceChanges []ResourceChange
 	// Err is set if the workspace could not be scanned.
 	Err error
+	// Informational holds findings that explain why a plan is not fully
+	// known (data source reads, deferred changes). They are not drift.
+	Informational []Finding
+	// CheckFailures holds check blocks and conditions that failed or errored.
+	CheckFailures []CheckFailure
+	// Stderr is the captured stderr of the terraform plan invocation.
+	Stderr string
+	// Duration is how long the plan took to run.
+	Duration time.Duration
+	// TerraformVersion is the Terraform (or OpenTofu) version reported by the plan.
+	TerraformVersion string
+	// Group is the label of the group this result belongs to after
+	// GroupResults (e.g. "action: delete"); empty when results are not grouped.
+	Group string
+}
+
+// Workspace statuses returned by ScanResult.Status.
+const (
+	StatusClean   = "clean"
+	StatusDrifted = "drifted"
+	StatusError   = "error"
+)
+
+// Status returns StatusError if the workspace could not be scanned,
+// StatusDrifted if it has drifted resources, and StatusClean otherwise.
+func (r ScanResult) Status() string {
+	switch {
+	case r.Err != nil:
+		return StatusError
+	case len(r.ResourceChanges) > 0:
+		return StatusDrifted
+	default:
+		return StatusClean
+	}
+}
+
+// CheckFailure is a failing (or erroring) health check reported by the plan.
+type CheckFailure struct {
+	Address  string
+	Status   string
+	Problems []string
+}
+
+// Finding kinds for informational findings.
+const (
+	FindingDataSourceRead = "data-source-read"
+	FindingDeferred       = "deferred"
+)
+
+// Finding is an informational, non-drift observation about a plan.
+type Finding struct {
+	Address string
+	Kind    string
+	Reason  string
+}
+
+// ConvertOptions controls how runner results are converted to ScanResults.
+type ConvertOptions struct {
+	// IncludeInformational populates ScanResult.Informational with data
+	// source reads and deferred changes found in the plan.
+	IncludeInformational bool
 }
 
 // ResourceChange is a report-level resource change (for display).
@@ -25,20 +89,125 @@ type ResourceChange struct {
 	Address    string
 	Action     string
 	Attributes map[string]AttributeChange
+	// ModuleAddress, Mode, Type, Name and ProviderName identify the resource's
+	// declaring block; they may be empty for results not built from a plan.
+	ModuleAddress string
+	Mode          string
+	Type          string
+	Name          string
+	ProviderName  string
 }
 
-// AttributeChange is a report-level attribute change (for display).
+// AttributeChange is a report-level attribute change.
+// Before and After keep the typed values decoded from the plan (string, bool,
+// json.Number, []interface{}, map[string]interface{} or nil); they are only
+// rendered to strings at display time.
 type AttributeChange struct {
-	Before string
-	After  string
+	Before interface{}
+	After  interface{}
+	// Sensitive marks values Terraform flagged as sensitive; renderers must
+	// redact Before and After.
+	Sensitive bool
+	// AfterUnknown marks values only known after apply; After is nil and
+	// renderers show knownAfterApply.
+	AfterUnknown bool
 }
 
 // Summary contains aggregate drift statistics.
 type Summary struct {
-	WorkspacesScanned    int
-	WorkspacesWithDrift  int
+	WorkspacesScanned     int
+	WorkspacesWithDrift   int
 	TotalDriftedResources int
-	ScanErrors           int
+	ScanErrors            int
+	// InformationalFindings counts informational findings; not included in drift counts.
+	InformationalFindings int
+	// FailedChecks counts health check failures across all workspaces.
+	FailedChecks int
+	// ByAction, ByProvider and ByResourceType count drifted resources by
+	// action, provider and resource type. Resources with an unknown provider
+	// or type are not counted in those maps.
+	ByAction       map[string]int
+	ByProvider     map[string]int
+	ByResourceType map[string]int
+	// MostDrifted lists up to SummaryTopN workspaces with the most drifted
+	// resources, most first.
+	MostDrifted []WorkspaceStat
+	// TotalDuration is the sum of plan durations across all workspaces.
+	TotalDuration time.Duration
+	// Slowest lists up to SummaryTopN workspaces with the longest plan
+	// durations, slowest first.
+	Slowest []WorkspaceStat
+}
+
+// SummaryTopN is the number of workspaces listed in Summary.MostDrifted and
+// Summary.Slowest.
+const SummaryTopN = 5
+
+// WorkspaceStat is a per-workspace figure used in summary rankings.
+type WorkspaceStat struct {
+	WorkspacePath    string
+	DriftedResources int
+	Duration         time.Duration
+}
+
+// Count is a name and count pair, as returned by SortedCounts.
+type Count struct {
+	Name  string
+	Count int
+}
+
+// SortedCounts returns the entries of counts ordered by count, highest
+// first, then by name.
+func SortedCounts(counts map[string]int) []Count {
+	out := make([]Count, 0, len(counts))
+	for name, n := range counts {
+		out = append(out, Count{Name: name, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// How Policy.ErrorsAs treats scan errors, and Policy.NotificationErrorsAs
+// failed notifications.
+const (
+	ErrorsAsError  = "error"
+	ErrorsAsDrift  = "drift"
+	ErrorsAsIgnore = "ignore"
+)
+
+// Exit code modes for Policy.Mode.
+const (
+	ExitModePriority = "priority"
+	ExitModeBitmask  = "bitmask"
+)
+
+// Exit code bits: ExitDrift and ExitError, combined in bitmask mode.
+const (
+	ExitClean = 0
+	ExitDrift = 1
+	ExitError = 2
+)
+
+// Policy controls how scan results map to a process exit code.
+type Policy struct {
+	// FailOnCheckFailures treats failing health checks like drift (exit 1).
+	FailOnCheckFailures bool
+	// FailOn lists the resource actions that count as drift; empty means
+	// every action.
+	FailOn []string
+	// ErrorsAs is ErrorsAsError (default), ErrorsAsDrift or ErrorsAsIgnore.
+	ErrorsAs string
+	// Mode is ExitModePriority (default), where errors take precedence over
+	// drift, or ExitModeBitmask, where the exit code is ExitDrift|ExitError.
+	Mode string
+	// NotificationErrorsAs is how a failed notification affects the exit
+	// code: ErrorsAsIgnore (default), ErrorsAsError or ErrorsAsDrift.
+	NotificationErrorsAs string
 }
 
 // ExitCode returns the appropriate process exit code for the scan results:
@@ -47,66 +216,111 @@ type Summary struct {
 //	1 — drift detected
 //	2 — scan error occurred
 func ExitCode(results []ScanResult) int {
+	return Policy{}.ExitCode(results)
+}
+
+// ExitCode returns the process exit code for results under policy p. All
+// results are evaluated before deciding. In priority mode the codes match
+// the package-level ExitCode; in bitmask mode drift and errors together
+// give 3.
+func (p Policy) ExitCode(results []ScanResult) int {
+	return p.ExitCodeAfterNotify(results, false)
+}
+
+// ExitCodeAfterNotify is ExitCode for a scan whose results were sent to
+// notification targets; notifyFailed reports whether any delivery failed,
+// which counts as NotificationErrorsAs says.
+func (p Policy) ExitCodeAfterNotify(results []ScanResult, notifyFailed bool) int {
 	hasError := false
 	hasDrift := false
+	if notifyFailed {
+		switch p.NotificationErrorsAs {
+		case ErrorsAsError:
+			hasError = true
+		case ErrorsAsDrift:
+			hasDrift = true
+		}
+	}
 
 	for _, r := range results {
 		if r.Err != nil {
-			hasError = true
-			break
+			switch p.ErrorsAs {
+			case ErrorsAsDrift:
+				hasDrift = true
+			case ErrorsAsIgnore:
+			default:
+				hasError = true
+			}
+			continue
 		}
-		if len(r.ResourceChanges) > 0 {
+		for _, rc := range r.ResourceChanges {
+			if p.failsOn(rc.Action) {
+				hasDrift = true
+			}
+		}
+		if p.FailOnCheckFailures && len(r.CheckFailures) > 0 {
 			hasDrift = true
 		}
 	}
 
+	if p.Mode == ExitModeBitmask {
+		code := ExitClean
+		if hasDrift {
+			code |= ExitDrift
+		}
+		if hasError {
+			code |= ExitError
+		}
+		return code
+	}
 	if hasError {
-		return 2
+		return ExitError
 	}
 	if hasDrift {
-		return 1
+		return ExitDrift
 	}
-	return 0
+	return ExitClean
 }
 
-// Print writes a human-readable drift report to w.
-// Format: workspace → resource → attribute → before/after table.
-func Print(w io.Writer, results []ScanResult) {
-	summary := Summarize(results)
-
-	// Print summary header
-	fmt.Fprintf(w, "Drift Scan Summary\n")
-	fmt.Fprintf(w, "===================\n")
-	fmt.Fprintf(w, "Workspaces scanned: %d\n", summary.WorkspacesScanned)
-	fmt.Fprintf(w, "Workspaces with drift: %d\n", summary.WorkspacesWithDrift)
-	fmt.Fprintf(w, "Total drifted resources: %d\n", summary.TotalDriftedResources)
-	fmt.Fprintf(w, "Scan errors: %d\n", summary.ScanErrors)
-	fmt.Fprintln(w)
+// WithError returns code, an exit code from ExitCode, raised to report an
+// error that is not tied to a workspace, such as a report that could not be
+// written: ExitError in priority mode, or code with the error bit set in
+// bitmask mode.
+func (p Policy) WithError(code int) int {
+	if p.Mode == ExitModeBitmask {
+		return code | ExitError
+	}
+	return ExitError
+}
 
-	// Print detailed results per workspace
-	for _, r := range results {
-		if r.Err != nil {
-			fmt.Fprintf(w, "ERROR: %s\n", r.WorkspacePath)
-			fmt.Fprintf(w, "  %v\n", r.Err)
-			continue
+// failsOn reports whether a resource change with action counts as drift.
+func (p Policy) failsOn(action string) bool {
+	if len(p.FailOn) == 0 {
+		return true
+	}
+	for _, a := range p.FailOn {
+		if a == action {
+			return true
 		}
+	}
+	return false
+}
 
-		fmt.Fprintf(w, "Workspace: %s\n", r.WorkspacePath)
+// FormatCounts renders counts as a comma-separated list, e.g. "update 2, delete 1".
+func FormatCounts(counts []Count) string {
+	parts := make([]string, len(counts))
+	for i, c := range counts {
+		parts[i] = fmt.Sprintf("%s %d", c.Name, c.Count)
+	}
+	return strings.Join(parts, ", ")
+}
 
-		if len(r.ResourceChanges) == 0 {
-			fmt.Fprintf(w, "  No drift detected\n")
-		} else {
-			for _, rc := range r.ResourceChanges {
-				fmt.Fprintf(w, "  Resource: %s (action: %s)\n", rc.Address, rc.Action)
-				for attr, change := range rc.Attributes {
-					beforeStr := formatValue(change.Before)
-					afterStr := formatValue(change.After)
-					fmt.Fprintf(w, "    %s:\n      before: %s\n      after:  %s\n", attr, beforeStr, afterStr)
-				}
-			}
-		}
-		fmt.Fprintln(w)
+// formatDuration rounds d for display, e.g. "1.5s".
+func formatDuration(d time.Duration) string {
+	if d >= time.Second {
+		return d.Round(100 * time.Millisecond).String()
 	}
+	return d.Round(time.Millisecond).String()
 }
 
 // formatValue converts an interface{} value to a string for display.
@@ -119,8 +333,14 @@ func formatValue(v interface{}) string {
 		return val
 	case bool:
 		return fmt.Sprintf("%t", val)
+	case json.Number:
+		return val.String()
 	case float64:
-		return fmt.Sprintf("%g", val)
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
 	case []interface{}:
 		parts := make([]string, len(val))
 		for i, p := range val {
@@ -128,9 +348,14 @@ func formatValue(v interface{}) string {
 		}
 		return "[" + strings.Join(parts, ", ") + "]"
 	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
 		parts := make([]string, 0, len(val))
-		for k, v := range val {
-			parts = append(parts, fmt.Sprintf("%s=%s", k, formatValue(v)))
+		for _, k := range keys {
+			parts = append(parts, fmt.Sprintf("%s=%s", k, formatValue(val[k])))
 		}
 		return "{" + strings.Join(parts, ", ") + "}"
 	default:
@@ -138,32 +363,93 @@ func formatValue(v interface{}) string {
 	}
 }
 
-// Summarize computes aggregate statistics from scan results.
+// Summarize computes aggregate statistics from scan results. Workspaces are
+// counted once even when GroupResults split them across several results.
 func Summarize(results []ScanResult) Summary {
 	summary := Summary{
-		WorkspacesScanned: len(results),
+		ByAction:       make(map[string]int),
+		ByProvider:     make(map[string]int),
+		ByResourceType: make(map[string]int),
 	}
+	scanned := make(map[string]bool)
+	drifted := make(map[string]bool)
+	errored := make(map[string]bool)
+	var stats []WorkspaceStat
+	statIndex := make(map[string]int)
 
 	for _, r := range results {
+		if !scanned[r.WorkspacePath] {
+			scanned[r.WorkspacePath] = true
+			summary.WorkspacesScanned++
+			statIndex[r.WorkspacePath] = len(stats)
+			stats = append(stats, WorkspaceStat{WorkspacePath: r.WorkspacePath})
+		}
+		stat := &stats[statIndex[r.WorkspacePath]]
+		stat.Duration += r.Duration
+		summary.TotalDuration += r.Duration
+
 		if r.Err != nil {
-			summary.ScanErrors++
+			if !errored[r.WorkspacePath] {
+				errored[r.WorkspacePath] = true
+				summary.ScanErrors++
+			}
 		} else if len(r.ResourceChanges) > 0 {
-			summary.WorkspacesWithDrift++
+			if !drifted[r.WorkspacePath] {
+				drifted[r.WorkspacePath] = true
+				summary.WorkspacesWithDrift++
+			}
 			summary.TotalDriftedResources += len(r.ResourceChanges)
+			stat.DriftedResources += len(r.ResourceChanges)
+			for _, rc := range r.ResourceChanges {
+				summary.ByAction[rc.Action]++
+				if rc.ProviderName != "" {
+					summary.ByProvider[rc.ProviderName]++
+				}
+				if rc.Type != "" {
+					summary.ByResourceType[rc.Type]++
+				}
+			}
 		}
+		summary.InformationalFindings += len(r.Informational)
+		summary.FailedChecks += len(r.CheckFailures)
 	}
 
+	summary.MostDrifted = topWorkspaces(stats, func(a, b WorkspaceStat) bool {
+		return a.DriftedResources > b.DriftedResources
+	}, func(s WorkspaceStat) bool { return s.DriftedResources > 0 })
+	summary.Slowest = topWorkspaces(stats, func(a, b WorkspaceStat) bool {
+		return a.Duration > b.Duration
+	}, func(s WorkspaceStat) bool { return s.Duration > 0 })
+
 	return summary
 }
 
+// topWorkspaces returns up to SummaryTopN stats for which keep is true,
+// ordered by less and then by scan order.
+func topWorkspaces(stats []WorkspaceStat, less func(a, b WorkspaceStat) bool, keep func(WorkspaceStat) bool) []WorkspaceStat {
+	var top []WorkspaceStat
+	for _, s := range stats {
+		if keep(s) {
+			top = append(top, s)
+		}
+	}
+	sort.SliceStable(top, func(i, j int) bool { return less(top[i], top[j]) })
+	if len(top) > SummaryTopN {
+		top = top[:SummaryTopN]
+	}
+	return top
+}
+
 // WorkspaceResultsFromRunnerResults converts runner results (with raw plan JSON)
 // into report ScanResults. Requires parsing each plan.
-func WorkspaceResultsFromRunnerResults(runnerResults []runner.Result) ([]ScanResult, error) {
+func WorkspaceResultsFromRunnerResults(runnerResults []runner.Result, opts ConvertOptions) ([]ScanResult, error) {
 	results := make([]ScanResult, 0, len(runnerResults))
 
 	for _, r := range runnerResults {
 		sr := ScanResult{
 			WorkspacePath: r.WorkspacePath,
+			Stderr:        string(r.Stderr),
+			Duration:      r.Duration,
 		}
 
 		if r.Err != nil {
@@ -172,33 +458,86 @@ func WorkspaceResultsFromRunnerResults(runnerResults []runner.Result) ([]ScanRes
 			continue
 		}
 
-		// Parse the JSON plan output
-		plan, err := parser.Parse(r.PlanOutput)
+		// Parse the JSON plan output, unless the runner already streamed it
+		plan, err := r.Plan, r.PlanErr
+		if plan == nil && err == nil {
+			plan, err = parser.Parse(r.PlanOutput)
+		}
 		if err != nil {
 			sr.Err = fmt.Errorf("parsing plan JSON: %w", err)
 			results = append(results, sr)
 			continue
 		}
 
+		sr.TerraformVersion = plan.TerraformVersion
+
 		// Convert parser.ResourceChange to report.ResourceChange
 		sr.ResourceChanges = make([]ResourceChange, 0, len(plan.ResourceChanges))
 		for _, rc := range plan.ResourceChanges {
 			attrs := make(map[string]AttributeChange)
 			for attr, change := range rc.AttributeChanges {
 				attrs[attr] = AttributeChange{
-					Before: formatValue(change.Before),
-					After:  formatValue(change.After),
+					Before:       change.Before,
+					After:        change.After,
+					Sensitive:    change.Sensitive,
+					AfterUnknown: change.AfterUnknown,
 				}
 			}
 			sr.ResourceChanges = append(sr.ResourceChanges, ResourceChange{
-				Address:    rc.Address,
-				Action:     string(rc.Action),
-				Attributes: attrs,
+				Address:       rc.Address,
+				Action:        string(rc.Action),
+				Attributes:    attrs,
+				ModuleAddress: rc.ModuleAddress,
+				Mode:          rc.Mode,
+				Type:          rc.Type,
+				Name:          rc.Name,
+				ProviderName:  rc.ProviderName,
 			})
 		}
 
+		if opts.IncludeInformational {
+			sr.Informational = informationalFindings(plan)
+		}
+		sr.CheckFailures = checkFailures(plan)
+
 		results = append(results, sr)
 	}
 
 	return results, nil
 }
+
+// informationalFindings collects data source reads and deferred changes from plan.
+func informationalFindings(plan *parser.Plan) []Finding {
+	var findings []Finding
+	for _, rc := range plan.DataSourceReads {
+		findings = append(findings, Finding{
+			Address: rc.Address,
+			Kind:    FindingDataSourceRead,
+			Reason:  rc.Reason,
+		})
+	}
+	for _, dc := range plan.DeferredChanges {
+		findings = append(findings, Finding{
+			Address: dc.Address,
+			Kind:    FindingDeferred,
+			Reason:  dc.Reason,
+		})
+	}
+	return findings
+}
+
+// checkFailures collects failing and erroring checks from plan.
+func checkFailures(plan *parser.Plan) []CheckFailure {
+	var failures []CheckFailure
+	for _, c := range plan.Checks {
+		if c.Status != parser.CheckFail && c.Status != parser.CheckError {
+			continue
+		}
+		failures = append(failures, CheckFailure{
+			Address:  c.Address,
+			Status:   string(c.Status),
+			Problems: c.Problems,
+		})
+	}
+	return failures
+}
@@ -2,11 +2,15 @@ package report_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/daemonship/driftwatch/internal/report"
+	"github.com/daemonship/driftwatch/internal/runner"
 )
 
 func noDriftResults() []report.ScanResult {
@@ -170,3 +174,375 @@ func TestPrint_ErrorWorkspace(t *testing.T) {
 		t.Errorf("Print() output does not mention error for errored workspace:\n%s", output)
 	}
 }
+
+const planWithDataSourceRead = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {
+      "address": "data.aws_ami.latest",
+      "action_reason": "read_because_config_unknown",
+      "change": {"actions": ["read"], "before": null, "after": {}}
+    }
+  ]
+}`
+
+func TestWorkspaceResultsFromRunnerResults_InformationalOptIn(t *testing.T) {
+	runnerResults := []runner.Result{
+		{WorkspacePath: "./infra/staging", PlanOutput: []byte(planWithDataSourceRead)},
+	}
+
+	results, err := report.WorkspaceResultsFromRunnerResults(runnerResults, report.ConvertOptions{})
+	if err != nil {
+		t.Fatalf("WorkspaceResultsFromRunnerResults() error = %v", err)
+	}
+	if len(results[0].Informational) != 0 {
+		t.Errorf("Informational count = %d, want 0 when not opted in", len(results[0].Informational))
+	}
+
+	results, err = report.WorkspaceResultsFromRunnerResults(runnerResults, report.ConvertOptions{IncludeInformational: true})
+	if err != nil {
+		t.Fatalf("WorkspaceResultsFromRunnerResults() error = %v", err)
+	}
+	if len(results[0].Informational) != 1 {
+		t.Fatalf("Informational count = %d, want 1", len(results[0].Informational))
+	}
+	f := results[0].Informational[0]
+	if f.Kind != report.FindingDataSourceRead || f.Address != "data.aws_ami.latest" {
+		t.Errorf("Informational[0] = %+v, want data source read of data.aws_ami.latest", f)
+	}
+	if len(results[0].ResourceChanges) != 0 {
+		t.Errorf("ResourceChanges count = %d, want 0 (reads are not drift)", len(results[0].ResourceChanges))
+	}
+}
+
+func TestSummarize_InformationalNotCountedAsDrift(t *testing.T) {
+	results := []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			Informational: []report.Finding{
+				{Address: "data.aws_ami.latest", Kind: report.FindingDataSourceRead},
+			},
+		},
+	}
+	summary := report.Summarize(results)
+	if summary.WorkspacesWithDrift != 0 {
+		t.Errorf("WorkspacesWithDrift = %d, want 0", summary.WorkspacesWithDrift)
+	}
+	if summary.InformationalFindings != 1 {
+		t.Errorf("InformationalFindings = %d, want 1", summary.InformationalFindings)
+	}
+	if code := report.ExitCode(results); code != 0 {
+		t.Errorf("ExitCode() = %d, want 0 for informational-only results", code)
+	}
+}
+
+func TestPrint_ShowsInformationalFindings(t *testing.T) {
+	results := []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			Informational: []report.Finding{
+				{Address: "kubernetes_namespace.apps", Kind: report.FindingDeferred, Reason: "provider_config_unknown"},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	report.Print(&buf, results)
+	output := buf.String()
+	if !strings.Contains(output, "kubernetes_namespace.apps") {
+		t.Errorf("Print() output does not contain deferred resource:\n%s", output)
+	}
+	if !strings.Contains(output, "provider_config_unknown") {
+		t.Errorf("Print() output does not contain deferral reason:\n%s", output)
+	}
+}
+
+func checkFailureResults() []report.ScanResult {
+	return []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			CheckFailures: []report.CheckFailure{
+				{Address: "check.health", Status: "fail", Problems: []string{"health endpoint returned 503"}},
+			},
+		},
+	}
+}
+
+func TestExitCode_CheckFailuresIgnoredByDefault(t *testing.T) {
+	if code := report.ExitCode(checkFailureResults()); code != 0 {
+		t.Errorf("ExitCode() = %d, want 0 for check failures without policy", code)
+	}
+}
+
+func TestPolicyExitCode_FailOnCheckFailures(t *testing.T) {
+	p := report.Policy{FailOnCheckFailures: true}
+	if code := p.ExitCode(checkFailureResults()); code != 1 {
+		t.Errorf("Policy.ExitCode() = %d, want 1 when FailOnCheckFailures is set", code)
+	}
+}
+
+func TestSummarize_FailedChecks(t *testing.T) {
+	summary := report.Summarize(checkFailureResults())
+	if summary.FailedChecks != 1 {
+		t.Errorf("FailedChecks = %d, want 1", summary.FailedChecks)
+	}
+	if summary.WorkspacesWithDrift != 0 {
+		t.Errorf("WorkspacesWithDrift = %d, want 0", summary.WorkspacesWithDrift)
+	}
+}
+
+func TestPrint_ShowsCheckFailures(t *testing.T) {
+	var buf bytes.Buffer
+	report.Print(&buf, checkFailureResults())
+	output := buf.String()
+	if !strings.Contains(output, "Health check failures") {
+		t.Errorf("Print() output missing health check failures section:\n%s", output)
+	}
+	if !strings.Contains(output, "check.health") || !strings.Contains(output, "health endpoint returned 503") {
+		t.Errorf("Print() output missing failing check details:\n%s", output)
+	}
+}
+
+const planWithFailingCheck = `{
+  "format_version": "1.2",
+  "resource_changes": [],
+  "checks": [
+    {"address": {"kind": "check", "to_display": "check.health"}, "status": "fail"},
+    {"address": {"kind": "check", "to_display": "check.dns"}, "status": "pass"}
+  ]
+}`
+
+func TestWorkspaceResultsFromRunnerResults_OnlyFailingChecks(t *testing.T) {
+	runnerResults := []runner.Result{
+		{WorkspacePath: "./infra/staging", PlanOutput: []byte(planWithFailingCheck)},
+	}
+	results, err := report.WorkspaceResultsFromRunnerResults(runnerResults, report.ConvertOptions{})
+	if err != nil {
+		t.Fatalf("WorkspaceResultsFromRunnerResults() error = %v", err)
+	}
+	if len(results[0].CheckFailures) != 1 {
+		t.Fatalf("CheckFailures count = %d, want 1 (passing checks excluded)", len(results[0].CheckFailures))
+	}
+	if results[0].CheckFailures[0].Address != "check.health" {
+		t.Errorf("CheckFailures[0].Address = %q, want %q", results[0].CheckFailures[0].Address, "check.health")
+	}
+}
+
+func TestPrint_RendersTypedValues(t *testing.T) {
+	results := []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			ResourceChanges: []report.ResourceChange{
+				{
+					Address: "aws_instance.web",
+					Action:  "update",
+					Attributes: map[string]report.AttributeChange{
+						"owner_id": {Before: json.Number("1234567890123"), After: json.Number("1234567890124")},
+						"tags": {
+							Before: map[string]interface{}{"b": "2", "a": "1", "c": "3"},
+							After:  nil,
+						},
+						"monitoring": {Before: false, After: true},
+					},
+				},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	report.Print(&buf, results)
+	output := buf.String()
+	if !strings.Contains(output, "1234567890123") {
+		t.Errorf("Print() output does not render large number verbatim:\n%s", output)
+	}
+	if strings.Contains(output, "e+") {
+		t.Errorf("Print() output uses exponent notation:\n%s", output)
+	}
+	if !strings.Contains(output, "{a=1, b=2, c=3}") {
+		t.Errorf("Print() output does not render map with sorted keys:\n%s", output)
+	}
+	if !strings.Contains(output, "true") || !strings.Contains(output, "false") {
+		t.Errorf("Print() output does not render bool values:\n%s", output)
+	}
+}
+
+func TestWorkspaceResultsFromRunnerResults_KeepsTypedValues(t *testing.T) {
+	plan := `{"format_version":"1.2","resource_changes":[{"address":"aws_instance.web","change":{"actions":["update"],"before":{"count":1,"enabled":false},"after":{"count":2,"enabled":true}}}]}`
+	results, err := report.WorkspaceResultsFromRunnerResults([]runner.Result{
+		{WorkspacePath: "./infra/staging", PlanOutput: []byte(plan)},
+	}, report.ConvertOptions{})
+	if err != nil {
+		t.Fatalf("WorkspaceResultsFromRunnerResults() error = %v", err)
+	}
+	attrs := results[0].ResourceChanges[0].Attributes
+	if _, ok := attrs["count"].After.(json.Number); !ok {
+		t.Errorf("count After type = %T, want json.Number", attrs["count"].After)
+	}
+	if v, ok := attrs["enabled"].After.(bool); !ok || !v {
+		t.Errorf("enabled After = %#v, want true", attrs["enabled"].After)
+	}
+}
+
+func breakdownResults() []report.ScanResult {
+	return []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			Duration:      3 * time.Second,
+			ResourceChanges: []report.ResourceChange{
+				{Address: "aws_instance.web", Action: "update", Type: "aws_instance", ProviderName: "registry.terraform.io/hashicorp/aws"},
+				{Address: "aws_instance.api", Action: "delete", Type: "aws_instance", ProviderName: "registry.terraform.io/hashicorp/aws"},
+				{Address: "google_dns_record_set.www", Action: "update", Type: "google_dns_record_set", ProviderName: "registry.terraform.io/hashicorp/google"},
+			},
+		},
+		{
+			WorkspacePath: "./infra/production",
+			Duration:      5 * time.Second,
+			ResourceChanges: []report.ResourceChange{
+				{Address: "aws_s3_bucket.logs", Action: "create", Type: "aws_s3_bucket", ProviderName: "registry.terraform.io/hashicorp/aws"},
+			},
+		},
+		{WorkspacePath: "./infra/dns", Duration: time.Second},
+	}
+}
+
+func TestSummarize_Breakdown(t *testing.T) {
+	summary := report.Summarize(breakdownResults())
+
+	wantAction := map[string]int{"update": 2, "delete": 1, "create": 1}
+	if !reflect.DeepEqual(summary.ByAction, wantAction) {
+		t.Errorf("ByAction = %v, want %v", summary.ByAction, wantAction)
+	}
+	if got := summary.ByProvider["registry.terraform.io/hashicorp/aws"]; got != 3 {
+		t.Errorf("ByProvider[aws] = %d, want 3", got)
+	}
+	if got := summary.ByResourceType["aws_instance"]; got != 2 {
+		t.Errorf("ByResourceType[aws_instance] = %d, want 2", got)
+	}
+	if summary.TotalDuration != 9*time.Second {
+		t.Errorf("TotalDuration = %v, want 9s", summary.TotalDuration)
+	}
+
+	if len(summary.MostDrifted) != 2 || summary.MostDrifted[0].WorkspacePath != "./infra/staging" {
+		t.Errorf("MostDrifted = %+v, want staging then production", summary.MostDrifted)
+	}
+	if len(summary.Slowest) != 3 || summary.Slowest[0].WorkspacePath != "./infra/production" {
+		t.Errorf("Slowest = %+v, want production first", summary.Slowest)
+	}
+}
+
+func TestSortedCounts_OrdersByCountThenName(t *testing.T) {
+	got := report.SortedCounts(map[string]int{"update": 2, "delete": 1, "create": 1})
+	want := []report.Count{{Name: "update", Count: 2}, {Name: "create", Count: 1}, {Name: "delete", Count: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SortedCounts() = %v, want %v", got, want)
+	}
+}
+
+func TestPrint_ShowsBreakdown(t *testing.T) {
+	var buf bytes.Buffer
+	report.Print(&buf, breakdownResults())
+	output := buf.String()
+
+	for _, want := range []string{
+		"Total scan duration: 9s",
+		"By action: update 2, create 1, delete 1",
+		"By resource type: aws_instance 2, aws_s3_bucket 1, google_dns_record_set 1",
+		"Most drifted workspaces:\n  ./infra/staging: 3 resource(s)\n  ./infra/production: 1 resource(s)\n",
+		"Slowest workspaces:\n  ./infra/production: 5s\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Print() output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestExitCode_ErrorDoesNotHideLaterDrift(t *testing.T) {
+	// Drift after an errored workspace must still be seen.
+	mixed := append(errorResults(), driftResults()...)
+	p := report.Policy{Mode: report.ExitModeBitmask}
+	if code := p.ExitCode(mixed); code != 3 {
+		t.Errorf("Policy.ExitCode() = %d, want 3 (drift|error) in bitmask mode", code)
+	}
+}
+
+func TestPolicyExitCode_FailOn(t *testing.T) {
+	p := report.Policy{FailOn: []string{"delete", "replace"}}
+	if code := p.ExitCode(driftResults()); code != 0 {
+		t.Errorf("Policy.ExitCode() = %d, want 0 for update when failing only on delete/replace", code)
+	}
+	results := []report.ScanResult{
+		{WorkspacePath: "./infra/staging", ResourceChanges: []report.ResourceChange{
+			{Address: "aws_instance.web", Action: "update"},
+			{Address: "aws_iam_role.old", Action: "delete"},
+		}},
+	}
+	if code := p.ExitCode(results); code != 1 {
+		t.Errorf("Policy.ExitCode() = %d, want 1 for delete", code)
+	}
+}
+
+func TestPolicyExitCode_ErrorsAs(t *testing.T) {
+	tests := []struct {
+		errorsAs string
+		mode     string
+		want     int
+	}{
+		{report.ErrorsAsError, "", 2},
+		{report.ErrorsAsDrift, "", 1},
+		{report.ErrorsAsIgnore, "", 0},
+		{report.ErrorsAsDrift, report.ExitModeBitmask, 1},
+		{report.ErrorsAsError, report.ExitModeBitmask, 2},
+	}
+	for _, tt := range tests {
+		p := report.Policy{ErrorsAs: tt.errorsAs, Mode: tt.mode}
+		if code := p.ExitCode(errorResults()); code != tt.want {
+			t.Errorf("Policy{ErrorsAs: %q, Mode: %q}.ExitCode() = %d, want %d", tt.errorsAs, tt.mode, code, tt.want)
+		}
+	}
+}
+
+func TestPolicyExitCodeAfterNotify(t *testing.T) {
+	tests := []struct {
+		notificationErrorsAs string
+		mode                 string
+		results              []report.ScanResult
+		want                 int
+	}{
+		{"", "", nil, 0},
+		{report.ErrorsAsIgnore, "", nil, 0},
+		{report.ErrorsAsError, "", nil, 2},
+		{report.ErrorsAsDrift, "", nil, 1},
+		{report.ErrorsAsError, report.ExitModeBitmask, driftResults(), 3},
+		{report.ErrorsAsDrift, report.ExitModeBitmask, driftResults(), 1},
+	}
+	for _, tt := range tests {
+		p := report.Policy{NotificationErrorsAs: tt.notificationErrorsAs, Mode: tt.mode}
+		if code := p.ExitCodeAfterNotify(tt.results, true); code != tt.want {
+			t.Errorf("Policy{NotificationErrorsAs: %q, Mode: %q}.ExitCodeAfterNotify(failed) = %d, want %d",
+				tt.notificationErrorsAs, tt.mode, code, tt.want)
+		}
+	}
+
+	p := report.Policy{NotificationErrorsAs: report.ErrorsAsError}
+	if code := p.ExitCodeAfterNotify(nil, false); code != 0 {
+		t.Errorf("ExitCodeAfterNotify(delivered) = %d, want 0", code)
+	}
+}
+
+func TestPolicyWithError(t *testing.T) {
+	tests := []struct {
+		mode string
+		code int
+		want int
+	}{
+		{"", 0, 2},
+		{"", 1, 2},
+		{report.ExitModePriority, 1, 2},
+		{report.ExitModeBitmask, 0, 2},
+		{report.ExitModeBitmask, 1, 3},
+		{report.ExitModeBitmask, 3, 3},
+	}
+	for _, tt := range tests {
+		if got := (report.Policy{Mode: tt.mode}).WithError(tt.code); got != tt.want {
+			t.Errorf("Policy{Mode: %q}.WithError(%d) = %d, want %d", tt.mode, tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/daemonship/driftwatch/internal/source"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolInfoURI  = "https://github.com/daemonship/driftwatch"
+)
+
+// sarifRule describes one drift rule, one per plan action.
+type sarifRule struct {
+	action      string
+	level       string
+	description string
+}
+
+// sarifRules lists the rules reported in SARIF output, in ruleIndex order.
+var sarifRules = []sarifRule{
+	{"create", "note", "Resource is declared in configuration but missing from the infrastructure and will be created."},
+	{"update", "warning", "Resource attributes have drifted from configuration and will be updated in place."},
+	{"delete", "error", "Resource exists in state but will be destroyed."},
+	{"replace", "error", "Resource has drifted in a way that forces it to be destroyed and recreated."},
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                        `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Invocations        []sarifInvocation                `json:"invocations"`
+	Results            []sarifResult                    `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string               `json:"name"`
+	Version        string               `json:"version,omitempty"`
+	InformationURI string               `json:"informationUri"`
+	Rules          []sarifReportingRule `json:"rules"`
+}
+
+type sarifReportingRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// WriteSARIF writes drifted resources to w as a SARIF 2.1.0 log. Each drifted
+// resource is a result whose rule is "drift/<action>". When loc is non-nil,
+// results point at the file and line of the declaring resource block,
+// relative to %SRCROOT%: the enclosing git repository, or the working
+// directory outside one. Scan errors are reported as tool execution
+// notifications.
+func WriteSARIF(w io.Writer, results []ScanResult, meta Metadata, loc *source.Locator) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "driftwatch",
+			Version:        meta.DriftwatchVersion,
+			InformationURI: toolInfoURI,
+		}},
+		Results: []sarifResult{},
+	}
+	ruleIndex := make(map[string]int, len(sarifRules))
+	for i, rule := range sarifRules {
+		ruleIndex[rule.action] = i
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifReportingRule{
+			ID:                   "drift/" + rule.action,
+			Name:                 "Drift" + strings.ToUpper(rule.action[:1]) + rule.action[1:],
+			ShortDescription:     sarifMessage{Text: rule.description},
+			DefaultConfiguration: sarifConfiguration{Level: rule.level},
+		})
+	}
+
+	var root string
+	if loc != nil {
+		var err error
+		if root, err = sarifSourceRoot(); err != nil {
+			loc = nil // without a root, files cannot be mapped to the repository
+		} else {
+			run.OriginalURIBaseIDs = map[string]sarifArtifactLocation{"%SRCROOT%": {URI: fileURI(root)}}
+		}
+	}
+
+	invocation := sarifInvocation{ExecutionSuccessful: true}
+	for _, r := range results {
+		if r.Err != nil {
+			invocation.ExecutionSuccessful = false
+			invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications, sarifNotification{
+				Level:   "error",
+				Message: sarifMessage{Text: fmt.Sprintf("%s: %v", r.WorkspacePath, r.Err)},
+			})
+			continue
+		}
+		for _, rc := range r.ResourceChanges {
+			idx, ok := ruleIndex[rc.Action]
+			if !ok {
+				continue
+			}
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    "drift/" + rc.Action,
+				RuleIndex: idx,
+				Level:     sarifRules[idx].level,
+				Message:   sarifMessage{Text: sarifResultMessage(r.WorkspacePath, rc)},
+				Locations: []sarifLocation{sarifResourceLocation(r.WorkspacePath, rc, loc, root)},
+				PartialFingerprints: map[string]string{
+					"driftwatchResource/v1": r.WorkspacePath + "|" + rc.Address,
+				},
+			})
+		}
+	}
+	run.Invocations = []sarifInvocation{invocation}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sarifResultMessage describes a drifted resource and the attributes that changed.
+func sarifResultMessage(workspace string, rc ResourceChange) string {
+	msg := fmt.Sprintf("%s in workspace %s has drifted (action: %s)", rc.Address, workspace, rc.Action)
+	if len(rc.Attributes) == 0 {
+		return msg + "."
+	}
+	names := make([]string, 0, len(rc.Attributes))
+	for name := range rc.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return msg + "; changed attributes: " + strings.Join(names, ", ") + "."
+}
+
+// sarifResourceLocation returns the logical location of rc and, if loc can
+// find its declaring block in a file under root, the physical file and line.
+func sarifResourceLocation(workspace string, rc ResourceChange, loc *source.Locator, root string) sarifLocation {
+	location := sarifLocation{
+		LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: rc.Address, Kind: "resource"}},
+	}
+	if loc == nil {
+		return location
+	}
+	found, ok := loc.Find(workspace, source.Resource{
+		ModuleAddress: rc.ModuleAddress,
+		Mode:          rc.Mode,
+		Type:          rc.Type,
+		Name:          rc.Name,
+	})
+	if !ok {
+		return location
+	}
+	uri, ok := sarifURI(found.File, root)
+	if !ok {
+		return location
+	}
+	location.PhysicalLocation = &sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: uri, URIBaseID: "%SRCROOT%"},
+		Region:           &sarifRegion{StartLine: found.Line},
+	}
+	return location
+}
+
+// sarifSourceRoot returns the directory SARIF URIs are relative to: the top
+// level of the git repository containing the working directory, which is
+// what code-scanning tools resolve them against, or the working directory
+// itself outside a repository.
+func sarifSourceRoot() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for dir := wd; ; {
+		// .git is a directory, or a file in worktrees and submodules
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return wd, nil
+		}
+		dir = parent
+	}
+}
+
+// sarifURI converts a file path to the forward-slash URI relative to root.
+// It reports false for files outside root, which cannot be mapped to the
+// repository, and for module sources Terraform downloaded under
+// .terraform/modules, which are not part of it.
+func sarifURI(path, root string) (string, bool) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	if path, err = filepath.Rel(root, path); err != nil {
+		return "", false
+	}
+	uri := filepath.ToSlash(path)
+	if uri == ".." || strings.HasPrefix(uri, "../") {
+		return "", false
+	}
+	if strings.HasPrefix(uri, ".terraform/modules/") || strings.Contains(uri, "/.terraform/modules/") {
+		return "", false
+	}
+	return uri, true
+}
+
+// fileURI returns the file URI of directory dir, with the trailing slash
+// SARIF requires of a base URI.
+func fileURI(dir string) string {
+	p := filepath.ToSlash(dir)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p // Windows drive paths
+	}
+	if !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
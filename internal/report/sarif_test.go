@@ -0,0 +1,249 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/daemonship/driftwatch/internal/report"
+	"github.com/daemonship/driftwatch/internal/source"
+)
+
+// sarifDoc is a minimal view of a SARIF log for assertions.
+type sarifDoc struct {
+	Version string `json:"version"`
+	Runs    []struct {
+		Tool struct {
+			Driver struct {
+				Name  string `json:"name"`
+				Rules []struct {
+					ID string `json:"id"`
+				} `json:"rules"`
+			} `json:"driver"`
+		} `json:"tool"`
+		OriginalURIBaseIDs map[string]struct {
+			URI string `json:"uri"`
+		} `json:"originalUriBaseIds"`
+		Invocations []struct {
+			ExecutionSuccessful        bool `json:"executionSuccessful"`
+			ToolExecutionNotifications []struct {
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+			} `json:"toolExecutionNotifications"`
+		} `json:"invocations"`
+		Results []struct {
+			RuleID    string `json:"ruleId"`
+			RuleIndex int    `json:"ruleIndex"`
+			Level     string `json:"level"`
+			Message   struct {
+				Text string `json:"text"`
+			} `json:"message"`
+			Locations []struct {
+				PhysicalLocation *struct {
+					ArtifactLocation struct {
+						URI       string `json:"uri"`
+						URIBaseID string `json:"uriBaseId"`
+					} `json:"artifactLocation"`
+					Region struct {
+						StartLine int `json:"startLine"`
+					} `json:"region"`
+				} `json:"physicalLocation"`
+				LogicalLocations []struct {
+					FullyQualifiedName string `json:"fullyQualifiedName"`
+				} `json:"logicalLocations"`
+			} `json:"locations"`
+		} `json:"results"`
+	} `json:"runs"`
+}
+
+func writeSARIFDoc(t *testing.T, results []report.ScanResult, loc *source.Locator) sarifDoc {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := report.WriteSARIF(&buf, results, testMeta, loc); err != nil {
+		t.Fatalf("WriteSARIF() error = %v", err)
+	}
+	var doc sarifDoc
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteSARIF() produced invalid JSON: %v", err)
+	}
+	return doc
+}
+
+// sarifWorkspace creates a workspace at infra/web in a temporary repository
+// root, which becomes the working directory, and returns its absolute path.
+func sarifWorkspace(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	t.Chdir(root)
+	ws := filepath.Join(root, "infra", "web")
+	if err := os.MkdirAll(ws, 0755); err != nil {
+		t.Fatal(err)
+	}
+	tf := "# web tier\n\nresource \"aws_instance\" \"web\" {\n  ami = \"ami-new\"\n}\n\nresource \"aws_s3_bucket\" \"old\" {}\n"
+	if err := os.WriteFile(filepath.Join(ws, "main.tf"), []byte(tf), 0644); err != nil {
+		t.Fatalf("writing main.tf: %v", err)
+	}
+	return ws
+}
+
+func sarifResults(ws string) []report.ScanResult {
+	return []report.ScanResult{
+		{
+			WorkspacePath: ws,
+			ResourceChanges: []report.ResourceChange{
+				{
+					Address: "aws_instance.web", Action: "update", Mode: "managed", Type: "aws_instance", Name: "web",
+					Attributes: map[string]report.AttributeChange{"ami": {Before: "ami-old", After: "ami-new"}},
+				},
+				{Address: "aws_s3_bucket.old", Action: "delete", Mode: "managed", Type: "aws_s3_bucket", Name: "old"},
+				{Address: "aws_iam_role.gone", Action: "create", Mode: "managed", Type: "aws_iam_role", Name: "gone"},
+			},
+		},
+		{WorkspacePath: "./infra/legacy", Err: errors.New("credentials not configured")},
+	}
+}
+
+func TestWriteSARIF_RulesAndLevels(t *testing.T) {
+	ws := sarifWorkspace(t)
+	doc := writeSARIFDoc(t, sarifResults(ws), source.NewLocator())
+	if doc.Version != "2.1.0" || len(doc.Runs) != 1 {
+		t.Fatalf("version=%q runs=%d, want 2.1.0 with one run", doc.Version, len(doc.Runs))
+	}
+	run := doc.Runs[0]
+	if run.Tool.Driver.Name != "driftwatch" {
+		t.Errorf("driver name = %q, want driftwatch", run.Tool.Driver.Name)
+	}
+	want := map[string]string{
+		"drift/update": "warning",
+		"drift/delete": "error",
+		"drift/create": "note",
+	}
+	if len(run.Results) != len(want) {
+		t.Fatalf("results count = %d, want %d", len(run.Results), len(want))
+	}
+	for _, r := range run.Results {
+		if want[r.RuleID] != r.Level {
+			t.Errorf("%s level = %q, want %q", r.RuleID, r.Level, want[r.RuleID])
+		}
+		if run.Tool.Driver.Rules[r.RuleIndex].ID != r.RuleID {
+			t.Errorf("%s ruleIndex %d points at rule %q", r.RuleID, r.RuleIndex, run.Tool.Driver.Rules[r.RuleIndex].ID)
+		}
+	}
+}
+
+func TestWriteSARIF_LocatesDeclaringBlock(t *testing.T) {
+	ws := sarifWorkspace(t)
+	doc := writeSARIFDoc(t, sarifResults(ws), source.NewLocator())
+	results := doc.Runs[0].Results
+
+	web := results[0].Locations[0]
+	if web.PhysicalLocation == nil {
+		t.Fatal("aws_instance.web has no physical location")
+	}
+	if got := web.PhysicalLocation.ArtifactLocation; got.URI != "infra/web/main.tf" || got.URIBaseID != "%SRCROOT%" {
+		t.Errorf("aws_instance.web artifactLocation = %+v, want infra/web/main.tf relative to %%SRCROOT%%", got)
+	}
+	if web.PhysicalLocation.Region.StartLine != 3 {
+		t.Errorf("aws_instance.web startLine = %d, want 3", web.PhysicalLocation.Region.StartLine)
+	}
+	if results[1].Locations[0].PhysicalLocation.Region.StartLine != 7 {
+		t.Errorf("aws_s3_bucket.old startLine = %d, want 7", results[1].Locations[0].PhysicalLocation.Region.StartLine)
+	}
+
+	// A resource with no declaring block (e.g. removed from config) keeps only its logical location.
+	gone := results[2].Locations[0]
+	if gone.PhysicalLocation != nil {
+		t.Errorf("aws_iam_role.gone physical location = %+v, want none", gone.PhysicalLocation)
+	}
+	if gone.LogicalLocations[0].FullyQualifiedName != "aws_iam_role.gone" {
+		t.Errorf("logical location = %q, want aws_iam_role.gone", gone.LogicalLocations[0].FullyQualifiedName)
+	}
+}
+
+func TestWriteSARIF_SkipsFilesOutsideWorkingDirectory(t *testing.T) {
+	ws := sarifWorkspace(t)
+	// A workspace outside the repository cannot be mapped to its files
+	t.Chdir(t.TempDir())
+	doc := writeSARIFDoc(t, sarifResults(ws), source.NewLocator())
+
+	web := doc.Runs[0].Results[0].Locations[0]
+	if web.PhysicalLocation != nil {
+		t.Errorf("physical location = %+v, want none outside the working directory", web.PhysicalLocation)
+	}
+	if len(web.LogicalLocations) != 1 || web.LogicalLocations[0].FullyQualifiedName != "aws_instance.web" {
+		t.Errorf("logical locations = %+v, want aws_instance.web", web.LogicalLocations)
+	}
+}
+
+func TestWriteSARIF_RelativeToGitRoot(t *testing.T) {
+	ws := sarifWorkspace(t)
+	root := filepath.Dir(filepath.Dir(ws))
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Run from a subdirectory, as when the config lives below the repository root
+	t.Chdir(filepath.Join(root, "infra"))
+	doc := writeSARIFDoc(t, sarifResults(ws), source.NewLocator())
+
+	web := doc.Runs[0].Results[0].Locations[0]
+	if web.PhysicalLocation == nil || web.PhysicalLocation.ArtifactLocation.URI != "infra/web/main.tf" {
+		t.Errorf("physical location = %+v, want infra/web/main.tf relative to the repository", web.PhysicalLocation)
+	}
+	base := doc.Runs[0].OriginalURIBaseIDs["%SRCROOT%"].URI
+	if want := "file://" + filepath.ToSlash(root) + "/"; base != want {
+		t.Errorf("%%SRCROOT%% = %q, want %q", base, want)
+	}
+}
+
+func TestWriteSARIF_SkipsDownloadedModules(t *testing.T) {
+	ws := sarifWorkspace(t)
+	modDir := filepath.Join(ws, ".terraform", "modules", "vpc")
+	if err := os.MkdirAll(modDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := `{"Modules":[{"Key":"vpc","Source":"registry.terraform.io/acme/vpc/aws","Dir":".terraform/modules/vpc"}]}`
+	if err := os.WriteFile(filepath.Join(ws, ".terraform", "modules", "modules.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(modDir, "main.tf"), []byte("resource \"aws_vpc\" \"main\" {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	results := []report.ScanResult{{
+		WorkspacePath: ws,
+		ResourceChanges: []report.ResourceChange{{
+			Address: "module.vpc.aws_vpc.main", Action: "update", ModuleAddress: "module.vpc",
+			Mode: "managed", Type: "aws_vpc", Name: "main",
+		}},
+	}}
+	doc := writeSARIFDoc(t, results, source.NewLocator())
+
+	if loc := doc.Runs[0].Results[0].Locations[0]; loc.PhysicalLocation != nil {
+		t.Errorf("physical location = %+v, want none for a downloaded module", loc.PhysicalLocation.ArtifactLocation)
+	}
+}
+
+func TestWriteSARIF_ScanErrorsAreNotifications(t *testing.T) {
+	doc := writeSARIFDoc(t, sarifResults(sarifWorkspace(t)), nil)
+	inv := doc.Runs[0].Invocations[0]
+	if inv.ExecutionSuccessful {
+		t.Error("executionSuccessful = true, want false when a workspace errored")
+	}
+	if len(inv.ToolExecutionNotifications) != 1 || !strings.Contains(inv.ToolExecutionNotifications[0].Message.Text, "credentials not configured") {
+		t.Errorf("notifications = %+v, want the scan error", inv.ToolExecutionNotifications)
+	}
+}
+
+func TestWriteSARIF_NoDriftHasEmptyResults(t *testing.T) {
+	var buf bytes.Buffer
+	if err := report.WriteSARIF(&buf, noDriftResults(), testMeta, nil); err != nil {
+		t.Fatalf("WriteSARIF() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `"results": []`) {
+		t.Errorf("WriteSARIF() output should contain an empty results array:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,41 @@
+(function () {
+  var text = document.getElementById("filter-text");
+  var statusBoxes = document.querySelectorAll("#filter-status input");
+  var actionBoxes = document.querySelectorAll("#filter-action input");
+
+  function checked(boxes) {
+    var values = {};
+    boxes.forEach(function (b) { if (b.checked) { values[b.value] = true; } });
+    return values;
+  }
+
+  function apply() {
+    var query = text.value.trim().toLowerCase();
+    var statuses = checked(statusBoxes);
+    var actions = checked(actionBoxes);
+    var visible = 0;
+
+    document.querySelectorAll(".workspace").forEach(function (ws) {
+      var wsMatches = ws.dataset.name.toLowerCase().indexOf(query) !== -1;
+      var shownResources = 0;
+      var resources = ws.querySelectorAll(".resource");
+      resources.forEach(function (rc) {
+        var show = actions[rc.dataset.action] &&
+          (wsMatches || rc.dataset.name.toLowerCase().indexOf(query) !== -1);
+        rc.hidden = !show;
+        if (show) { shownResources++; }
+      });
+
+      var show = statuses[ws.dataset.status] &&
+        (resources.length === 0 ? wsMatches : shownResources > 0);
+      ws.hidden = !show;
+      if (show) { visible++; }
+    });
+
+    document.getElementById("no-matches").hidden = visible !== 0;
+  }
+
+  text.addEventListener("input", apply);
+  statusBoxes.forEach(function (b) { b.addEventListener("change", apply); });
+  actionBoxes.forEach(function (b) { b.addEventListener("change", apply); });
+})();
@@ -0,0 +1,288 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ANSI escape sequences used by the text report when color is enabled.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+// textActions maps resource actions to the symbol, color and description
+// Terraform uses for them in plan output.
+var textActions = map[string]struct {
+	Symbol      string
+	Color       string
+	Description string
+}{
+	"create":  {"+", ansiGreen, "will be created"},
+	"delete":  {"-", ansiRed, "will be destroyed"},
+	"update":  {"~", ansiYellow, "will be updated in-place"},
+	"replace": {"-/+", ansiRed, "must be replaced"},
+	"read":    {"<=", ansiGreen, "will be read during apply"},
+}
+
+// TextFormatter writes the human-readable report produced by Print.
+// Color enables ANSI colors; callers should set it only when writing to a
+// terminal that accepts them.
+type TextFormatter struct {
+	Color bool
+}
+
+// Format writes the text report for results to w.
+func (f TextFormatter) Format(w io.Writer, results []ScanResult, _ Metadata) error {
+	p := textPrinter{w: w, color: f.Color}
+	p.print(results)
+	return nil
+}
+
+// Print writes a human-readable drift report to w, without color.
+// Resources are rendered like terraform plan output: an action symbol per
+// resource and attribute, attributes sorted by name and multiline strings
+// shown as line diffs.
+func Print(w io.Writer, results []ScanResult) {
+	p := textPrinter{w: w}
+	p.print(results)
+}
+
+// textPrinter renders the text report, optionally with ANSI colors.
+type textPrinter struct {
+	w     io.Writer
+	color bool
+}
+
+// paint wraps s in the ANSI sequence code when color is enabled.
+func (p textPrinter) paint(code, s string) string {
+	if !p.color || code == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+func (p textPrinter) print(results []ScanResult) {
+	w := p.w
+	summary := Summarize(results)
+
+	// Print summary header
+	fmt.Fprintf(w, "%s\n", p.paint(ansiBold, "Drift Scan Summary"))
+	fmt.Fprintf(w, "===================\n")
+	fmt.Fprintf(w, "Workspaces scanned: %d\n", summary.WorkspacesScanned)
+	fmt.Fprintf(w, "Workspaces with drift: %d\n", summary.WorkspacesWithDrift)
+	fmt.Fprintf(w, "Total drifted resources: %d\n", summary.TotalDriftedResources)
+	fmt.Fprintf(w, "Scan errors: %d\n", summary.ScanErrors)
+	if summary.InformationalFindings > 0 {
+		fmt.Fprintf(w, "Informational findings: %d\n", summary.InformationalFindings)
+	}
+	if summary.FailedChecks > 0 {
+		fmt.Fprintf(w, "Health check failures: %d\n", summary.FailedChecks)
+	}
+	if summary.TotalDuration > 0 {
+		fmt.Fprintf(w, "Total scan duration: %s\n", formatDuration(summary.TotalDuration))
+	}
+	p.printBreakdown(summary)
+	fmt.Fprintln(w)
+
+	// Print detailed results per workspace, under a header for each group
+	group := ""
+	for _, v := range buildView(results) {
+		if v.Group != group {
+			group = v.Group
+			if group != "" {
+				fmt.Fprintf(w, "%s\n\n", p.paint(ansiBold, "== "+group+" =="))
+			}
+		}
+		if v.Err != nil {
+			fmt.Fprintf(w, "%s %s\n", p.paint(ansiRed+ansiBold, "ERROR:"), v.Path)
+			fmt.Fprintf(w, "  %v\n", v.Err)
+			continue
+		}
+
+		fmt.Fprintf(w, "%s %s\n", p.paint(ansiBold, "Workspace:"), v.Path)
+
+		if len(v.Resources) == 0 {
+			fmt.Fprintf(w, "  No drift detected\n")
+		} else {
+			for _, rc := range v.Resources {
+				p.printResource(rc)
+			}
+		}
+		if len(v.CheckFailures) > 0 {
+			fmt.Fprintf(w, "  Health check failures:\n")
+			for _, c := range v.CheckFailures {
+				fmt.Fprintf(w, "    %s (status: %s)\n", c.Address, p.paint(ansiRed, c.Status))
+				for _, prob := range c.Problems {
+					fmt.Fprintf(w, "      %s\n", prob)
+				}
+			}
+		}
+		if len(v.Informational) > 0 {
+			fmt.Fprintf(w, "  Informational (not drift):\n")
+			for _, f := range v.Informational {
+				fmt.Fprintf(w, "    %s: %s", f.Kind, f.Address)
+				if f.Reason != "" {
+					fmt.Fprintf(w, " (%s)", f.Reason)
+				}
+				fmt.Fprintln(w)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// printBreakdown writes the drifted resource counts and workspace rankings of
+// summary, skipping any that are empty.
+func (p textPrinter) printBreakdown(summary Summary) {
+	for _, b := range []struct {
+		Label  string
+		Counts map[string]int
+	}{
+		{"By action", summary.ByAction},
+		{"By provider", summary.ByProvider},
+		{"By resource type", summary.ByResourceType},
+	} {
+		if len(b.Counts) > 0 {
+			fmt.Fprintf(p.w, "%s: %s\n", b.Label, FormatCounts(SortedCounts(b.Counts)))
+		}
+	}
+	if len(summary.MostDrifted) > 0 {
+		fmt.Fprintf(p.w, "Most drifted workspaces:\n")
+		for _, s := range summary.MostDrifted {
+			fmt.Fprintf(p.w, "  %s: %d resource(s)\n", s.WorkspacePath, s.DriftedResources)
+		}
+	}
+	if len(summary.Slowest) > 0 {
+		fmt.Fprintf(p.w, "Slowest workspaces:\n")
+		for _, s := range summary.Slowest {
+			fmt.Fprintf(p.w, "  %s: %s\n", s.WorkspacePath, formatDuration(s.Duration))
+		}
+	}
+}
+
+// printResource renders one resource as a terraform plan style block:
+//
+//	# aws_instance.web will be updated in-place
+//	~ resource "aws_instance" "web" {
+//	    ~ ami = ami-old -> ami-new
+//	  }
+func (p textPrinter) printResource(rc resourceView) {
+	act, ok := textActions[rc.Action]
+	if !ok {
+		act.Symbol, act.Color, act.Description = "~", ansiYellow, "has changed ("+rc.Action+")"
+	}
+
+	fmt.Fprintf(p.w, "  %s\n", p.paint(ansiBold, "# "+rc.Address+" "+act.Description))
+	fmt.Fprintf(p.w, "  %s %s {\n", p.paint(act.Color, act.Symbol), resourceHeader(rc))
+
+	width := 0
+	for _, attr := range rc.Attributes {
+		if len(attr.Name) > width {
+			width = len(attr.Name)
+		}
+	}
+	for _, attr := range rc.Attributes {
+		p.printAttribute(attr, width)
+	}
+	fmt.Fprintf(p.w, "%s}\n", strings.Repeat(" ", len(act.Symbol)+3))
+}
+
+// resourceHeader returns the block header for rc, e.g. resource "aws_instance" "web".
+// Results not built from a plan may lack a type and name; the address is used instead.
+func resourceHeader(rc resourceView) string {
+	if rc.Type == "" || rc.Name == "" {
+		return rc.Address
+	}
+	keyword := "resource"
+	if rc.Mode == "data" {
+		keyword = "data"
+	}
+	return fmt.Sprintf("%s %q %q", keyword, rc.Type, rc.Name)
+}
+
+// printAttribute renders one attribute change with its name padded to width.
+func (p textPrinter) printAttribute(attr attributeView, width int) {
+	name := attr.Name + strings.Repeat(" ", width-len(attr.Name))
+	switch {
+	case attr.Sensitive:
+		fmt.Fprintf(p.w, "      %s %s = %s\n", p.paint(ansiYellow, "~"), name, attr.After)
+	case attr.Added:
+		fmt.Fprintf(p.w, "      %s %s = %s\n", p.paint(ansiGreen, "+"), name, attr.After)
+	case attr.Removed:
+		fmt.Fprintf(p.w, "      %s %s = %s -> null\n", p.paint(ansiRed, "-"), name, attr.Before)
+	case !attr.Unknown && (strings.Contains(attr.Before, "\n") || strings.Contains(attr.After, "\n")):
+		fmt.Fprintf(p.w, "      %s %s = <<-EOT\n", p.paint(ansiYellow, "~"), name)
+		p.printLineDiff(attr.Before, attr.After)
+		fmt.Fprintf(p.w, "        EOT\n")
+	default:
+		fmt.Fprintf(p.w, "      %s %s = %s -> %s\n", p.paint(ansiYellow, "~"), name, attr.Before, attr.After)
+	}
+}
+
+// printLineDiff renders a line-by-line diff of two multiline strings.
+func (p textPrinter) printLineDiff(before, after string) {
+	for _, l := range diffLines(strings.Split(before, "\n"), strings.Split(after, "\n")) {
+		switch l.Op {
+		case '-':
+			fmt.Fprintf(p.w, "          %s %s\n", p.paint(ansiRed, "-"), l.Text)
+		case '+':
+			fmt.Fprintf(p.w, "          %s %s\n", p.paint(ansiGreen, "+"), l.Text)
+		default:
+			fmt.Fprintf(p.w, "            %s\n", l.Text)
+		}
+	}
+}
+
+// diffLine is one line of a line diff: Op is '-', '+' or ' ' (unchanged).
+type diffLine struct {
+	Op   byte
+	Text string
+}
+
+// diffLines returns a minimal line diff of a and b based on their longest
+// common subsequence. Removed lines are emitted before added ones.
+func diffLines(a, b []string) []diffLine {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []diffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, diffLine{'-', a[i]})
+			i++
+		default:
+			out = append(out, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		out = append(out, diffLine{'+', b[j]})
+	}
+	return out
+}
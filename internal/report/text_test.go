@@ -0,0 +1,179 @@
+package report_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+func actionResults() []report.ScanResult {
+	return []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			ResourceChanges: []report.ResourceChange{
+				{Address: "aws_s3_bucket.logs", Action: "create", Type: "aws_s3_bucket", Name: "logs"},
+				{Address: "aws_iam_role.old", Action: "delete", Type: "aws_iam_role", Name: "old"},
+				{
+					Address: "aws_instance.web",
+					Action:  "update",
+					Type:    "aws_instance",
+					Name:    "web",
+					Attributes: map[string]report.AttributeChange{
+						"tags":          {Before: "a", After: "b"},
+						"ami":           {Before: "ami-old", After: "ami-new"},
+						"instance_type": {Before: "t3.micro", After: nil},
+					},
+				},
+				{Address: "aws_db_instance.main", Action: "replace"},
+			},
+		},
+	}
+}
+
+// unknownResults has a resource whose attributes are only known after apply,
+// one of them sensitive.
+func unknownResults() []report.ScanResult {
+	return []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			ResourceChanges: []report.ResourceChange{
+				{
+					Address: "aws_instance.web",
+					Action:  "replace",
+					Attributes: map[string]report.AttributeChange{
+						"id":         {Before: "i-123", AfterUnknown: true},
+						"private_ip": {AfterUnknown: true},
+						"password":   {Before: "hunter2", Sensitive: true, AfterUnknown: true},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestPrint_KnownAfterApply(t *testing.T) {
+	var buf bytes.Buffer
+	report.Print(&buf, unknownResults())
+	out := buf.String()
+	for _, want := range []string{
+		"~ id         = i-123 -> (known after apply)",
+		"+ private_ip = (known after apply)",
+		"~ password   = (sensitive value)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Print() missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "hunter2") || strings.Contains(out, "null") {
+		t.Errorf("Print() leaked a sensitive value or showed an unknown value as null:\n%s", out)
+	}
+}
+
+func TestPrint_TerraformStyleActions(t *testing.T) {
+	var buf bytes.Buffer
+	report.Print(&buf, actionResults())
+	output := buf.String()
+
+	for _, want := range []string{
+		"# aws_s3_bucket.logs will be created\n  + resource \"aws_s3_bucket\" \"logs\" {",
+		"# aws_iam_role.old will be destroyed\n  - resource \"aws_iam_role\" \"old\" {",
+		"# aws_instance.web will be updated in-place\n  ~ resource \"aws_instance\" \"web\" {",
+		"# aws_db_instance.main must be replaced\n  -/+ aws_db_instance.main {",
+		"~ ami           = ami-old -> ami-new",
+		"- instance_type = t3.micro -> null",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Print() output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestPrint_SortsAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	report.Print(&buf, actionResults())
+	output := buf.String()
+
+	ami := strings.Index(output, "ami ")
+	instanceType := strings.Index(output, "instance_type ")
+	tags := strings.Index(output, "tags ")
+	if ami < 0 || instanceType < 0 || tags < 0 || !(ami < instanceType && instanceType < tags) {
+		t.Errorf("Print() attributes not sorted by name:\n%s", output)
+	}
+}
+
+func TestPrint_MultilineStringDiff(t *testing.T) {
+	results := []report.ScanResult{
+		{
+			WorkspacePath: "./infra/staging",
+			ResourceChanges: []report.ResourceChange{
+				{
+					Address: "aws_instance.web",
+					Action:  "update",
+					Attributes: map[string]report.AttributeChange{
+						"user_data": {
+							Before: "#!/bin/bash\necho old\nexit 0",
+							After:  "#!/bin/bash\necho new\nexit 0",
+						},
+					},
+				},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	report.Print(&buf, results)
+	output := buf.String()
+
+	want := "" +
+		"      ~ user_data = <<-EOT\n" +
+		"            #!/bin/bash\n" +
+		"          - echo old\n" +
+		"          + echo new\n" +
+		"            exit 0\n" +
+		"        EOT\n"
+	if !strings.Contains(output, want) {
+		t.Errorf("Print() output missing line diff:\n%s\nwant:\n%s", output, want)
+	}
+}
+
+func TestPrint_NoColor(t *testing.T) {
+	var buf bytes.Buffer
+	report.Print(&buf, actionResults())
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("Print() output contains ANSI escapes:\n%q", buf.String())
+	}
+}
+
+func TestTextFormatter_Color(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (report.TextFormatter{Color: true}).Format(&buf, actionResults(), report.Metadata{}); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, "\x1b[32m+\x1b[0m resource \"aws_s3_bucket\"") {
+		t.Errorf("Format() output does not color create in green:\n%q", output)
+	}
+	if !strings.Contains(output, "\x1b[31m-\x1b[0m instance_type") {
+		t.Errorf("Format() output does not color removed attribute in red:\n%q", output)
+	}
+}
+
+func TestPrint_GroupHeaders(t *testing.T) {
+	results, err := report.GroupResults(actionResults(), "action")
+	if err != nil {
+		t.Fatalf("GroupResults() error = %v", err)
+	}
+	var buf bytes.Buffer
+	report.Print(&buf, results)
+	output := buf.String()
+
+	create := strings.Index(output, "== action: create ==")
+	update := strings.Index(output, "== action: update ==")
+	if create < 0 || update < 0 || create > update {
+		t.Errorf("Print() output missing ordered group headers:\n%s", output)
+	}
+	if !strings.Contains(output, "Workspaces scanned: 1\n") {
+		t.Errorf("Print() summary counts split workspace more than once:\n%s", output)
+	}
+}
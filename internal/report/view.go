@@ -0,0 +1,108 @@
+package report
+
+import "sort"
+
+// redactedValue replaces the before/after values of sensitive attributes.
+const redactedValue = "(sensitive value)"
+
+// knownAfterApply replaces the after value of attributes Terraform only
+// knows once the plan is applied.
+const knownAfterApply = "(known after apply)"
+
+// workspaceView is the display model of one ScanResult, shared by the
+// human-oriented renderers (text, markdown) so they group, order and redact
+// results the same way: resources keep scan order, attributes are sorted by
+// name and sensitive values are replaced with redactedValue.
+type workspaceView struct {
+	Path          string
+	Group         string
+	Status        string
+	Err           error
+	Stderr        string
+	Resources     []resourceView
+	CheckFailures []CheckFailure
+	Informational []Finding
+}
+
+// resourceView is the display model of one drifted resource.
+type resourceView struct {
+	Address    string
+	Action     string
+	Mode       string
+	Type       string
+	Name       string
+	Attributes []attributeView
+}
+
+// attributeView is a changed attribute with its values rendered for display.
+type attributeView struct {
+	Name      string
+	Before    string
+	After     string
+	Sensitive bool
+	// Unknown marks an after value only known after apply.
+	Unknown bool
+	// Added and Removed mark attributes with no value before or after.
+	Added   bool
+	Removed bool
+}
+
+// buildView converts results into per-workspace display models.
+func buildView(results []ScanResult) []workspaceView {
+	views := make([]workspaceView, 0, len(results))
+	for _, r := range results {
+		v := workspaceView{
+			Path:          r.WorkspacePath,
+			Group:         r.Group,
+			Status:        r.Status(),
+			Err:           r.Err,
+			Stderr:        r.Stderr,
+			CheckFailures: r.CheckFailures,
+			Informational: r.Informational,
+		}
+		for _, rc := range r.ResourceChanges {
+			v.Resources = append(v.Resources, resourceView{
+				Address:    rc.Address,
+				Action:     rc.Action,
+				Mode:       rc.Mode,
+				Type:       rc.Type,
+				Name:       rc.Name,
+				Attributes: attributeViews(rc.Attributes),
+			})
+		}
+		views = append(views, v)
+	}
+	return views
+}
+
+// attributeViews renders attrs sorted by name, redacting sensitive values and
+// showing values only known after apply as knownAfterApply.
+func attributeViews(attrs map[string]AttributeChange) []attributeView {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	views := make([]attributeView, 0, len(names))
+	for _, name := range names {
+		c := attrs[name]
+		v := attributeView{
+			Name:      name,
+			Sensitive: c.Sensitive,
+			Unknown:   c.AfterUnknown,
+			Added:     c.Before == nil && (c.After != nil || c.AfterUnknown),
+			Removed:   c.Before != nil && c.After == nil && !c.AfterUnknown,
+		}
+		switch {
+		case c.Sensitive:
+			v.Before, v.After = redactedValue, redactedValue
+		case c.AfterUnknown:
+			v.Before, v.After = formatValue(c.Before), knownAfterApply
+		default:
+			v.Before, v.After = formatValue(c.Before), formatValue(c.After)
+		}
+		views = append(views, v)
+	}
+	return views
+}
@@ -4,7 +4,11 @@ package runner
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os/exec"
+	"time"
+
+	"github.com/daemonship/driftwatch/internal/parser"
 )
 
 // Result holds the outcome of running terraform plan in a single workspace.
@@ -12,13 +16,20 @@ type Result struct {
 	// WorkspacePath is the directory of the workspace that was scanned.
 	WorkspacePath string
 	// PlanOutput is the raw JSON output from terraform plan -json.
+	// Empty when Options.StreamPlan is set; see Plan and PlanErr instead.
 	PlanOutput []byte
+	// Plan is the plan decoded while streaming stdout (Options.StreamPlan).
+	Plan *parser.Plan
+	// PlanErr holds the error from decoding the streamed plan, if any.
+	PlanErr error
 	// Stderr is the captured stderr from the terraform plan invocation.
 	Stderr []byte
 	// ExitCode is the process exit code (0=no changes, 1=error, 2=changes present).
 	ExitCode int
 	// Err holds any execution error (e.g., binary not found, permission denied).
 	Err error
+	// Duration is the wall-clock time taken by terraform plan.
+	Duration time.Duration
 }
 
 // Options configures the workspace runner.
@@ -26,6 +37,9 @@ type Options struct {
 	// Binary is the terraform (or tofu) binary to invoke.
 	// Defaults to "terraform" if empty.
 	Binary string
+	// StreamPlan decodes stdout incrementally with parser.ParseReader instead
+	// of buffering it, so very large plans do not have to fit in memory.
+	StreamPlan bool
 }
 
 // RunWorkspace executes terraform plan -json -detailed-exitcode in the given
@@ -42,12 +56,19 @@ func RunWorkspace(workspacePath string, opts Options) Result {
 	cmd.Dir = workspacePath
 
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
-	result.PlanOutput = stdout.Bytes()
+	start := time.Now()
+	var err error
+	if opts.StreamPlan {
+		err = runStreaming(cmd, &result)
+	} else {
+		cmd.Stdout = &stdout
+		err = cmd.Run()
+		result.PlanOutput = stdout.Bytes()
+	}
 	result.Stderr = stderr.Bytes()
+	result.Duration = time.Since(start)
 
 	if exitErr, ok := err.(*exec.ExitError); ok {
 		// Command ran but exited with non-zero code
@@ -64,6 +85,24 @@ func RunWorkspace(workspacePath string, opts Options) Result {
 	return result
 }
 
+// runStreaming starts cmd and decodes its stdout into result.Plan as it is
+// produced. Any output left after a decode error is drained so the process
+// can exit. Returns the error from starting or waiting on cmd.
+func runStreaming(cmd *exec.Cmd, result *Result) error {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	result.Plan, result.PlanErr = parser.ParseReader(stdout)
+	_, _ = io.Copy(io.Discard, stdout)
+
+	return cmd.Wait()
+}
+
 // RunAll iterates workspacePaths sequentially and returns a result per workspace.
 func RunAll(workspacePaths []string, opts Options) []Result {
 	results := make([]Result, 0, len(workspacePaths))
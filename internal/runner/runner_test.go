@@ -102,6 +102,60 @@ func main() {
 	}
 }
 
+func TestRunWorkspace_StreamPlanDecodesStdout(t *testing.T) {
+	fakeTerraform := buildFakeTerraform(t, `
+package main
+import (
+	"fmt"
+	"os"
+)
+func main() {
+	fmt.Print(`+"`"+`{"format_version":"1.2","planned_values":{"root_module":{}},"resource_changes":[{"address":"aws_instance.web","change":{"actions":["update"],"before":{"ami":"ami-old"},"after":{"ami":"ami-new"}}}]}`+"`"+`)
+	os.Exit(2)
+}
+`)
+	dir := t.TempDir()
+	result := runner.RunWorkspace(dir, runner.Options{Binary: fakeTerraform, StreamPlan: true})
+	if result.ExitCode != 2 {
+		t.Errorf("RunWorkspace() ExitCode = %d, want 2 for drift", result.ExitCode)
+	}
+	if result.PlanErr != nil {
+		t.Fatalf("RunWorkspace() PlanErr = %v, want nil", result.PlanErr)
+	}
+	if result.Plan == nil || len(result.Plan.ResourceChanges) != 1 {
+		t.Fatalf("RunWorkspace() Plan = %+v, want one resource change", result.Plan)
+	}
+	if len(result.PlanOutput) != 0 {
+		t.Errorf("RunWorkspace() PlanOutput has %d bytes, want none when streaming", len(result.PlanOutput))
+	}
+}
+
+func TestRunWorkspace_StreamPlanRecordsDecodeError(t *testing.T) {
+	fakeTerraform := buildFakeTerraform(t, `
+package main
+import (
+	"fmt"
+	"os"
+)
+func main() {
+	fmt.Print("not json")
+	fmt.Fprintln(os.Stderr, "Error: provider crashed")
+	os.Exit(1)
+}
+`)
+	dir := t.TempDir()
+	result := runner.RunWorkspace(dir, runner.Options{Binary: fakeTerraform, StreamPlan: true})
+	if result.PlanErr == nil {
+		t.Error("RunWorkspace() PlanErr = nil, want decode error for non-JSON output")
+	}
+	if result.ExitCode != 1 {
+		t.Errorf("RunWorkspace() ExitCode = %d, want 1", result.ExitCode)
+	}
+	if len(result.Stderr) == 0 {
+		t.Error("RunWorkspace() Stderr is empty, want stderr captured while streaming")
+	}
+}
+
 func TestRunAll_ReturnsOneResultPerWorkspace(t *testing.T) {
 	paths := []string{"/path/one", "/path/two", "/path/three"}
 	results := runner.RunAll(paths, runner.Options{Binary: "nonexistent-binary-xyz"})
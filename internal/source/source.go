@@ -0,0 +1,195 @@
+// Package source locates resource declarations in Terraform configuration files.
+package source
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Location is the position of a block in a .tf file.
+type Location struct {
+	// File is the path of the .tf file, joined onto the workspace path.
+	File string
+	// Line is the 1-based line number of the block header.
+	Line int
+}
+
+// Resource identifies a resource or data source block to locate.
+type Resource struct {
+	// ModuleAddress is the containing module address (e.g. "module.network"), or "" for the root module.
+	ModuleAddress string
+	// Mode is "managed" or "data"; empty is treated as "managed".
+	Mode string
+	// Type is the resource type (e.g. "aws_instance").
+	Type string
+	// Name is the resource name (e.g. "web").
+	Name string
+}
+
+// blockPattern matches a resource or data block header such as
+// `resource "aws_instance" "web" {`, capturing the keyword, type and name.
+var blockPattern = regexp.MustCompile(`^\s*(resource|data)\s+"?([\w-]+)"?\s+"?([\w-]+)"?\s*\{`)
+
+// Locator finds resource blocks by scanning the .tf files of a workspace and,
+// for resources in child modules, the module directories recorded by
+// terraform init in .terraform/modules/modules.json. Results are cached per
+// directory, so a Locator should be reused across lookups in one scan.
+// Locator is not safe for concurrent use.
+type Locator struct {
+	blocks  map[string]map[string]Location // dir -> "mode.type.name" -> location
+	modules map[string]map[string]string   // workspace -> module key -> dir
+}
+
+// NewLocator returns an empty Locator.
+func NewLocator() *Locator {
+	return &Locator{
+		blocks:  make(map[string]map[string]Location),
+		modules: make(map[string]map[string]string),
+	}
+}
+
+// Find returns the location of the block declaring r in the given workspace.
+// Returns false if the module directory or block cannot be found.
+func (l *Locator) Find(workspace string, r Resource) (Location, bool) {
+	dir := workspace
+	if r.ModuleAddress != "" {
+		moduleDir, ok := l.moduleDir(workspace, moduleKey(r.ModuleAddress))
+		if !ok {
+			return Location{}, false
+		}
+		dir = moduleDir
+	}
+
+	mode := "resource"
+	if r.Mode == "data" {
+		mode = "data"
+	}
+	loc, ok := l.dirBlocks(dir)[mode+"."+r.Type+"."+r.Name]
+	return loc, ok
+}
+
+// moduleKey converts a module address like `module.a.module.b["x"]` to the
+// key used in modules.json ("a.b"). Instance keys are dropped since every
+// instance of a module shares one source directory.
+func moduleKey(address string) string {
+	var names []string
+	for _, part := range splitModuleAddress(address) {
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			part = part[:i]
+		}
+		names = append(names, part)
+	}
+	return strings.Join(names, ".")
+}
+
+// splitModuleAddress returns the module names in address, ignoring dots
+// inside instance keys.
+func splitModuleAddress(address string) []string {
+	var names []string
+	rest := address
+	for strings.HasPrefix(rest, "module.") {
+		rest = strings.TrimPrefix(rest, "module.")
+		end := len(rest)
+		depth := 0
+		for i, c := range rest {
+			if c == '[' {
+				depth++
+			} else if c == ']' {
+				depth--
+			} else if c == '.' && depth == 0 {
+				end = i
+				break
+			}
+		}
+		names = append(names, rest[:end])
+		rest = strings.TrimPrefix(rest[end:], ".")
+	}
+	return names
+}
+
+// moduleDir resolves a module key to its directory using the workspace's
+// .terraform/modules/modules.json manifest.
+func (l *Locator) moduleDir(workspace, key string) (string, bool) {
+	manifest, ok := l.modules[workspace]
+	if !ok {
+		manifest = readModuleManifest(workspace)
+		l.modules[workspace] = manifest
+	}
+	dir, ok := manifest[key]
+	return dir, ok
+}
+
+// readModuleManifest reads .terraform/modules/modules.json, returning module
+// key to directory (joined onto workspace). Returns an empty map on any error.
+func readModuleManifest(workspace string) map[string]string {
+	dirs := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(workspace, ".terraform", "modules", "modules.json"))
+	if err != nil {
+		return dirs
+	}
+	var manifest struct {
+		Modules []struct {
+			Key string `json:"Key"`
+			Dir string `json:"Dir"`
+		} `json:"Modules"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return dirs
+	}
+	for _, m := range manifest.Modules {
+		if m.Key == "" {
+			continue
+		}
+		dirs[m.Key] = filepath.Join(workspace, filepath.FromSlash(m.Dir))
+	}
+	return dirs
+}
+
+// dirBlocks returns the resource and data blocks declared in dir's .tf files.
+func (l *Locator) dirBlocks(dir string) map[string]Location {
+	if blocks, ok := l.blocks[dir]; ok {
+		return blocks
+	}
+	blocks := make(map[string]Location)
+	l.blocks[dir] = blocks
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return blocks
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		scanBlocks(file, blocks)
+	}
+	return blocks
+}
+
+// scanBlocks records the block headers found in file. The first declaration
+// of a block wins, since Terraform rejects duplicates anyway.
+func scanBlocks(file string, blocks map[string]Location) {
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		m := blockPattern.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		key := m[1] + "." + m[2] + "." + m[3]
+		if _, seen := blocks[key]; !seen {
+			blocks[key] = Location{File: file, Line: line}
+		}
+	}
+}
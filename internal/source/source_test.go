@@ -0,0 +1,119 @@
+package source_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/daemonship/driftwatch/internal/source"
+)
+
+const mainTF = `terraform {
+  required_version = ">= 1.0.0"
+}
+
+resource "aws_instance" "web" {
+  ami           = "ami-0abc123"
+  instance_type = "t3.micro"
+}
+
+data "aws_ami" "latest" {
+  most_recent = true
+}
+`
+
+const networkTF = `variable "cidr" {}
+
+resource "aws_security_group" "allow_ssh" {
+  name = "allow-ssh"
+}
+`
+
+const modulesJSON = `{"Modules":[
+  {"Key":"","Source":"","Dir":"."},
+  {"Key":"network","Source":"./modules/network","Dir":"modules/network"},
+  {"Key":"network.inner","Source":"./inner","Dir":"modules/network/inner"}
+]}`
+
+// newWorkspace builds a workspace with a root module and a "network" child module.
+func newWorkspace(t *testing.T) string {
+	t.Helper()
+	ws := t.TempDir()
+	writeFile(t, filepath.Join(ws, "main.tf"), mainTF)
+	writeFile(t, filepath.Join(ws, "modules", "network", "main.tf"), networkTF)
+	writeFile(t, filepath.Join(ws, "modules", "network", "inner", "sg.tf"), networkTF)
+	writeFile(t, filepath.Join(ws, ".terraform", "modules", "modules.json"), modulesJSON)
+	return ws
+}
+
+func TestFind_RootResource(t *testing.T) {
+	ws := newWorkspace(t)
+	loc, ok := source.NewLocator().Find(ws, source.Resource{Mode: "managed", Type: "aws_instance", Name: "web"})
+	if !ok {
+		t.Fatal("Find() ok = false, want true")
+	}
+	if loc.File != filepath.Join(ws, "main.tf") || loc.Line != 5 {
+		t.Errorf("Find() = %+v, want main.tf line 5", loc)
+	}
+}
+
+func TestFind_DataSource(t *testing.T) {
+	ws := newWorkspace(t)
+	loc, ok := source.NewLocator().Find(ws, source.Resource{Mode: "data", Type: "aws_ami", Name: "latest"})
+	if !ok || loc.Line != 10 {
+		t.Errorf("Find() = %+v, %v; want main.tf line 10", loc, ok)
+	}
+}
+
+func TestFind_ManagedDoesNotMatchDataBlock(t *testing.T) {
+	ws := newWorkspace(t)
+	if loc, ok := source.NewLocator().Find(ws, source.Resource{Type: "aws_ami", Name: "latest"}); ok {
+		t.Errorf("Find() = %+v, want no match for managed resource with only a data block", loc)
+	}
+}
+
+func TestFind_ModuleResource(t *testing.T) {
+	ws := newWorkspace(t)
+	l := source.NewLocator()
+	tests := []struct {
+		module string
+		file   string
+	}{
+		{"module.network", filepath.Join(ws, "modules", "network", "main.tf")},
+		{`module.network["eu"]`, filepath.Join(ws, "modules", "network", "main.tf")},
+		{"module.network.module.inner[0]", filepath.Join(ws, "modules", "network", "inner", "sg.tf")},
+	}
+	for _, tt := range tests {
+		loc, ok := l.Find(ws, source.Resource{ModuleAddress: tt.module, Type: "aws_security_group", Name: "allow_ssh"})
+		if !ok {
+			t.Errorf("Find(%s) ok = false, want true", tt.module)
+			continue
+		}
+		if loc.File != tt.file || loc.Line != 3 {
+			t.Errorf("Find(%s) = %+v, want %s line 3", tt.module, loc, tt.file)
+		}
+	}
+}
+
+func TestFind_UnknownModule(t *testing.T) {
+	ws := newWorkspace(t)
+	if _, ok := source.NewLocator().Find(ws, source.Resource{ModuleAddress: "module.missing", Type: "aws_security_group", Name: "allow_ssh"}); ok {
+		t.Error("Find() ok = true for module not in modules.json, want false")
+	}
+}
+
+func TestFind_MissingWorkspace(t *testing.T) {
+	if _, ok := source.NewLocator().Find("/nonexistent/workspace", source.Resource{Type: "aws_instance", Name: "web"}); ok {
+		t.Error("Find() ok = true for missing workspace, want false")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("writeFile mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("writeFile: %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+// Package state remembers the outcome of the previous scan between runs, so
+// a scan can be compared with the one before it — for example to resolve an
+// incident once a workspace's drift is gone.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/daemonship/driftwatch/internal/report"
+)
+
+// Version is the version of the state file format.
+const Version = 1
+
+// DefaultPath is where the state is kept unless the config sets state_file.
+const DefaultPath = ".driftwatch/state.json"
+
+// Snapshot is the last known state of every scanned workspace.
+type Snapshot struct {
+	Version    int                  `json:"version"`
+	Workspaces map[string]Workspace `json:"workspaces"`
+	// Notifiers holds data notification targets keep between runs, such as
+	// the messages they posted or the incidents they opened, by a key made
+	// of the target's name and one its notifier chooses.
+	Notifiers map[string]json.RawMessage `json:"notifiers,omitempty"`
+
+	mu sync.Mutex // guards Notifiers; notifiers run concurrently
+}
+
+// Workspace is the last known state of one workspace.
+type Workspace struct {
+	// Status is report.StatusClean, StatusDrifted or StatusError.
+	Status string `json:"status"`
+	// Drifted maps the address of each drifted resource to its action. It is
+	// kept from the last successful scan when the latest scan errored.
+	Drifted map[string]string `json:"drifted,omitempty"`
+	// ScannedAt is when the workspace was last scanned.
+	ScannedAt time.Time `json:"scanned_at"`
+}
+
+// New returns an empty snapshot.
+func New() *Snapshot {
+	return &Snapshot{Version: Version, Workspaces: make(map[string]Workspace)}
+}
+
+// Load reads the snapshot at path. A missing file is not an error: it
+// yields an empty snapshot, as on the first run.
+func Load(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading state file: %w", err)
+	}
+	s := New()
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("parsing state file %s: %w", path, err)
+	}
+	if s.Version != Version {
+		return nil, fmt.Errorf("state file %s has version %d, want %d", path, s.Version, Version)
+	}
+	if s.Workspaces == nil {
+		s.Workspaces = make(map[string]Workspace)
+	}
+	return s, nil
+}
+
+// Save writes the snapshot to path, creating its directory if needed. The
+// file is replaced atomically so an interrupted run cannot corrupt it.
+func (s *Snapshot) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".state-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Workspace returns the last known state of the workspace at path. It is
+// safe to call on a nil snapshot.
+func (s *Snapshot) Workspace(path string) (Workspace, bool) {
+	if s == nil {
+		return Workspace{}, false
+	}
+	ws, ok := s.Workspaces[path]
+	return ws, ok
+}
+
+// NotifierData decodes the data stored under key into v and reports
+// whether there was any. It is safe to call on a nil snapshot and from
+// several goroutines.
+func (s *Snapshot) NotifierData(key string, v interface{}) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	s.mu.Lock()
+	data, ok := s.Notifiers[key]
+	s.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("state for %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// SetNotifierData stores v under key for the next run; a nil v removes the
+// key. It does nothing on a nil snapshot and is safe for concurrent use.
+func (s *Snapshot) SetNotifierData(key string, v interface{}) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v == nil {
+		delete(s.Notifiers, key)
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("state for %s: %w", key, err)
+	}
+	if s.Notifiers == nil {
+		s.Notifiers = make(map[string]json.RawMessage)
+	}
+	s.Notifiers[key] = data
+	return nil
+}
+
+// Record updates the snapshot with the results of a scan at now. Workspaces
+// not in results keep their previous state. A workspace whose scan errored
+// keeps its previously drifted resources, since its real state is unknown.
+func (s *Snapshot) Record(results []report.ScanResult, now time.Time) {
+	for _, r := range results {
+		ws := Workspace{Status: r.Status(), ScannedAt: now}
+		if ws.Status == report.StatusError {
+			ws.Drifted = s.Workspaces[r.WorkspacePath].Drifted
+		}
+		for _, rc := range r.ResourceChanges {
+			if ws.Drifted == nil {
+				ws.Drifted = make(map[string]string)
+			}
+			ws.Drifted[rc.Address] = rc.Action
+		}
+		s.Workspaces[r.WorkspacePath] = ws
+	}
+}
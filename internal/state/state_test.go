@@ -0,0 +1,127 @@
+package state_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/daemonship/driftwatch/internal/report"
+	"github.com/daemonship/driftwatch/internal/state"
+)
+
+func TestLoad_MissingFileIsEmpty(t *testing.T) {
+	s, err := state.Load(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("Load() error = %v, want nil", err)
+	}
+	if len(s.Workspaces) != 0 {
+		t.Errorf("Workspaces = %v, want empty", s.Workspaces)
+	}
+}
+
+func TestSaveLoad_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.json")
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	s := state.New()
+	s.Record([]report.ScanResult{
+		{WorkspacePath: "./infra/staging", ResourceChanges: []report.ResourceChange{{Address: "aws_instance.web", Action: "update"}}},
+		{WorkspacePath: "./infra/clean"},
+	}, now)
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := state.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	staging, ok := loaded.Workspace("./infra/staging")
+	if !ok || staging.Status != report.StatusDrifted || staging.Drifted["aws_instance.web"] != "update" {
+		t.Errorf("staging = %+v, want drifted aws_instance.web", staging)
+	}
+	if !staging.ScannedAt.Equal(now) {
+		t.Errorf("ScannedAt = %v, want %v", staging.ScannedAt, now)
+	}
+	if clean, _ := loaded.Workspace("./infra/clean"); clean.Status != report.StatusClean || len(clean.Drifted) != 0 {
+		t.Errorf("clean = %+v, want clean with no drift", clean)
+	}
+}
+
+func TestRecord_ErrorKeepsPreviousDrift(t *testing.T) {
+	s := state.New()
+	s.Record([]report.ScanResult{
+		{WorkspacePath: "./infra/staging", ResourceChanges: []report.ResourceChange{{Address: "aws_instance.web", Action: "update"}}},
+		{WorkspacePath: "./infra/other"},
+	}, time.Now())
+	s.Record([]report.ScanResult{{WorkspacePath: "./infra/staging", Err: errors.New("plan failed")}}, time.Now())
+
+	staging, _ := s.Workspace("./infra/staging")
+	if staging.Status != report.StatusError || staging.Drifted["aws_instance.web"] != "update" {
+		t.Errorf("staging = %+v, want error status keeping previous drift", staging)
+	}
+	if _, ok := s.Workspace("./infra/other"); !ok {
+		t.Error("workspace missing from a later scan was forgotten, want kept")
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"bad json":    "{",
+		"bad version": `{"version": 99, "workspaces": {}}`,
+	} {
+		path := filepath.Join(dir, name+".json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := state.Load(path); err == nil {
+			t.Errorf("%s: Load() error = nil, want error", name)
+		}
+	}
+}
+
+func TestWorkspace_NilSnapshot(t *testing.T) {
+	var s *state.Snapshot
+	if _, ok := s.Workspace("./infra"); ok {
+		t.Error("Workspace() on nil snapshot ok = true, want false")
+	}
+}
+
+func TestNotifierData_RoundTrip(t *testing.T) {
+	type thread struct {
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	}
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	s := state.New()
+	if err := s.SetNotifierData("slack:#drift", thread{Channel: "C1", TS: "123.456"}); err != nil {
+		t.Fatalf("SetNotifierData() error = %v", err)
+	}
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := state.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	var got thread
+	if ok, err := loaded.NotifierData("slack:#drift", &got); err != nil || !ok || got.TS != "123.456" {
+		t.Errorf("NotifierData() = %+v, %v, %v; want the stored thread", got, ok, err)
+	}
+	if err := loaded.SetNotifierData("slack:#drift", nil); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := loaded.NotifierData("slack:#drift", &got); ok {
+		t.Error("NotifierData() found data after it was removed")
+	}
+
+	var nilSnapshot *state.Snapshot
+	if ok, err := nilSnapshot.NotifierData("x", &got); ok || err != nil {
+		t.Errorf("NotifierData() on nil snapshot = %v, %v; want nothing", ok, err)
+	}
+}
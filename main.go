@@ -2,6 +2,14 @@ package main
 
 import "github.com/daemonship/driftwatch/cmd"
 
+// Set at build time by goreleaser via -ldflags.
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
 func main() {
+	cmd.SetVersion(version, commit, date)
 	cmd.Execute()
 }